@@ -43,6 +43,11 @@ It has these top-level messages:
 	SetSuggestionParametersReply
 	StopSuggestionRequest
 	StopSuggestionReply
+	ResourceConf
+	Toleration
+	ParameterInjection
+	MetricsCollectorConf
+	MetricsCollectorSidecar
 */
 package api
 
@@ -153,6 +158,106 @@ func (x TrialState) String() string {
 }
 func (TrialState) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
+// What vizier-core does with a suggested trial whose parameters are the same as a completed or running trial of the study.
+// This value is stored as TINYINT in MySQL.
+type DuplicatePolicy int32
+
+const (
+	// The trial is run again.
+	DuplicatePolicy_ALLOW_DUPLICATE DuplicatePolicy = 0
+	// The trial takes the result of the completed trial without running. A duplicate of a running trial is dropped.
+	DuplicatePolicy_REUSE_RESULT DuplicatePolicy = 1
+	// The trial is dropped and the suggestion service is asked for other trials.
+	DuplicatePolicy_RESUGGEST DuplicatePolicy = 2
+)
+
+var DuplicatePolicy_name = map[int32]string{
+	0: "ALLOW_DUPLICATE",
+	1: "REUSE_RESULT",
+	2: "RESUGGEST",
+}
+var DuplicatePolicy_value = map[string]int32{
+	"ALLOW_DUPLICATE": 0,
+	"REUSE_RESULT":    1,
+	"RESUGGEST":       2,
+}
+
+func (x DuplicatePolicy) String() string {
+	return proto.EnumName(DuplicatePolicy_name, int32(x))
+}
+func (DuplicatePolicy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type ParameterInjection_Mode int32
+
+const (
+	// Arguments appended to the command, formatted with arg_format.
+	ParameterInjection_ARGS ParameterInjection_Mode = 0
+	// Environment variables named env_prefix and the parameter name in upper case.
+	ParameterInjection_ENV ParameterInjection_Mode = 1
+	// A file at file_path, in YAML when it ends with .yaml or .yml and in JSON otherwise.
+	ParameterInjection_FILE ParameterInjection_Mode = 2
+	// Only Go templates in the command, e.g. {{.Params.lr}}.
+	ParameterInjection_TEMPLATE ParameterInjection_Mode = 3
+)
+
+var ParameterInjection_Mode_name = map[int32]string{
+	0: "ARGS",
+	1: "ENV",
+	2: "FILE",
+	3: "TEMPLATE",
+}
+var ParameterInjection_Mode_value = map[string]int32{
+	"ARGS":     0,
+	"ENV":      1,
+	"FILE":     2,
+	"TEMPLATE": 3,
+}
+
+func (x ParameterInjection_Mode) String() string {
+	return proto.EnumName(ParameterInjection_Mode_name, int32(x))
+}
+func (ParameterInjection_Mode) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{37, 0} }
+
+type MetricsCollectorConf_Kind int32
+
+const (
+	// name=value tokens separated by spaces.
+	MetricsCollectorConf_KEY_VALUE MetricsCollectorConf_Kind = 0
+	// Matches of regex with the named groups name and value, and optionally step.
+	MetricsCollectorConf_REGEX MetricsCollectorConf_Kind = 1
+	// JSON objects, one per line, with the metrics as fields.
+	MetricsCollectorConf_JSON_LINES MetricsCollectorConf_Kind = 2
+	// Lines of the file at file_path in file_format instead of the logs.
+	MetricsCollectorConf_FILE MetricsCollectorConf_Kind = 3
+	// Scalar summaries of the TensorBoard event files in the directory file_path,
+	// {mount path}/logs/{Study ID}_{Trial ID} by default.
+	MetricsCollectorConf_TF_EVENT MetricsCollectorConf_Kind = 4
+	// Samples of the Prometheus metrics endpoint of the trial, scraped every scrape_interval seconds.
+	MetricsCollectorConf_PROMETHEUS MetricsCollectorConf_Kind = 5
+)
+
+var MetricsCollectorConf_Kind_name = map[int32]string{
+	0: "KEY_VALUE",
+	1: "REGEX",
+	2: "JSON_LINES",
+	3: "FILE",
+	4: "TF_EVENT",
+	5: "PROMETHEUS",
+}
+var MetricsCollectorConf_Kind_value = map[string]int32{
+	"KEY_VALUE":  0,
+	"REGEX":      1,
+	"JSON_LINES": 2,
+	"FILE":       3,
+	"TF_EVENT":   4,
+	"PROMETHEUS": 5,
+}
+
+func (x MetricsCollectorConf_Kind) String() string {
+	return proto.EnumName(MetricsCollectorConf_Kind_name, int32(x))
+}
+func (MetricsCollectorConf_Kind) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{38, 0} }
+
 type FeasibleSpace struct {
 	Max  string   `protobuf:"bytes,1,opt,name=max" json:"max,omitempty"`
 	Min  string   `protobuf:"bytes,2,opt,name=min" json:"min,omitempty"`
@@ -277,6 +382,8 @@ func (m *Metrics) GetValue() string {
 type EvaluationLog struct {
 	Time    string     `protobuf:"bytes,1,opt,name=time" json:"time,omitempty"`
 	Metrics []*Metrics `protobuf:"bytes,2,rep,name=metrics" json:"metrics,omitempty"`
+	// Training step of the metrics, 0 when the trial does not report it.
+	Step int64 `protobuf:"varint,3,opt,name=step" json:"step,omitempty"`
 }
 
 func (m *EvaluationLog) Reset()                    { *m = EvaluationLog{} }
@@ -298,6 +405,13 @@ func (m *EvaluationLog) GetMetrics() []*Metrics {
 	return nil
 }
 
+func (m *EvaluationLog) GetStep() int64 {
+	if m != nil {
+		return m.Step
+	}
+	return 0
+}
+
 type SuggestionParameter struct {
 	Name  string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
@@ -454,6 +568,14 @@ type StudyConfig struct {
 	Scheduler            string                        `protobuf:"bytes,17,opt,name=scheduler" json:"scheduler,omitempty"`
 	Mount                *MountConf                    `protobuf:"bytes,18,opt,name=mount" json:"mount,omitempty"`
 	PullSecret           string                        `protobuf:"bytes,19,opt,name=pull_secret,json=pullSecret" json:"pull_secret,omitempty"`
+	DuplicatePolicy      DuplicatePolicy               `protobuf:"varint,20,opt,name=duplicate_policy,json=duplicatePolicy,enum=api.DuplicatePolicy" json:"duplicate_policy,omitempty"`
+	// Job manifest in YAML or JSON that the kubernetes worker builds the job of each trial on.
+	JobTemplate string `protobuf:"bytes,21,opt,name=job_template,json=jobTemplate" json:"job_template,omitempty"`
+	// Kubernetes namespace of the jobs of the trials and of TensorBoard.
+	Namespace          string                `protobuf:"bytes,22,opt,name=namespace" json:"namespace,omitempty"`
+	Resources          *ResourceConf         `protobuf:"bytes,23,opt,name=resources" json:"resources,omitempty"`
+	ParameterInjection *ParameterInjection   `protobuf:"bytes,24,opt,name=parameter_injection,json=parameterInjection" json:"parameter_injection,omitempty"`
+	MetricsCollector   *MetricsCollectorConf `protobuf:"bytes,25,opt,name=metrics_collector,json=metricsCollector" json:"metrics_collector,omitempty"`
 }
 
 func (m *StudyConfig) Reset()                    { *m = StudyConfig{} }
@@ -594,6 +716,48 @@ func (m *StudyConfig) GetPullSecret() string {
 	return ""
 }
 
+func (m *StudyConfig) GetDuplicatePolicy() DuplicatePolicy {
+	if m != nil {
+		return m.DuplicatePolicy
+	}
+	return DuplicatePolicy_ALLOW_DUPLICATE
+}
+
+func (m *StudyConfig) GetJobTemplate() string {
+	if m != nil {
+		return m.JobTemplate
+	}
+	return ""
+}
+
+func (m *StudyConfig) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *StudyConfig) GetResources() *ResourceConf {
+	if m != nil {
+		return m.Resources
+	}
+	return nil
+}
+
+func (m *StudyConfig) GetParameterInjection() *ParameterInjection {
+	if m != nil {
+		return m.ParameterInjection
+	}
+	return nil
+}
+
+func (m *StudyConfig) GetMetricsCollector() *MetricsCollectorConf {
+	if m != nil {
+		return m.MetricsCollector
+	}
+	return nil
+}
+
 type StudyConfig_ParameterConfigs struct {
 	Configs []*ParameterConfig `protobuf:"bytes,1,rep,name=configs" json:"configs,omitempty"`
 }
@@ -773,6 +937,8 @@ func (m *SuggestTrialsRequest) GetConfigs() *StudyConfig {
 type SuggestTrialsReply struct {
 	Trials    []*Trial `protobuf:"bytes,1,rep,name=trials" json:"trials,omitempty"`
 	Completed bool     `protobuf:"varint,2,opt,name=completed" json:"completed,omitempty"`
+	// Opaque state of the study that the manager stores and gives back in GenerateTrialsRequest.
+	State []byte `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
 }
 
 func (m *SuggestTrialsReply) Reset()                    { *m = SuggestTrialsReply{} }
@@ -797,6 +963,8 @@ func (m *SuggestTrialsReply) GetCompleted() bool {
 type CompleteTrialRequest struct {
 	WorkerId   string `protobuf:"bytes,1,opt,name=worker_id,json=workerId" json:"worker_id,omitempty"`
 	IsComplete bool   `protobuf:"varint,2,opt,name=is_complete,json=isComplete" json:"is_complete,omitempty"`
+	StudyId    string `protobuf:"bytes,3,opt,name=study_id,json=studyId" json:"study_id,omitempty"`
+	TrialId    string `protobuf:"bytes,4,opt,name=trial_id,json=trialId" json:"trial_id,omitempty"`
 }
 
 func (m *CompleteTrialRequest) Reset()                    { *m = CompleteTrialRequest{} }
@@ -818,6 +986,20 @@ func (m *CompleteTrialRequest) GetIsComplete() bool {
 	return false
 }
 
+func (m *CompleteTrialRequest) GetStudyId() string {
+	if m != nil {
+		return m.StudyId
+	}
+	return ""
+}
+
+func (m *CompleteTrialRequest) GetTrialId() string {
+	if m != nil {
+		return m.TrialId
+	}
+	return ""
+}
+
 type CompleteTrialReply struct {
 }
 
@@ -909,7 +1091,9 @@ func (m *GetObjectValueReply) GetTrials() []*Trial {
 type AddMeasurementToTrialsRequest struct {
 	StudyId string `protobuf:"bytes,1,opt,name=study_id,json=studyId" json:"study_id,omitempty"`
 	// metrics can be a json string
-	Metrics string `protobuf:"bytes,2,opt,name=metrics" json:"metrics,omitempty"`
+	Metrics  string           `protobuf:"bytes,2,opt,name=metrics" json:"metrics,omitempty"`
+	TrialId  string           `protobuf:"bytes,3,opt,name=trial_id,json=trialId" json:"trial_id,omitempty"`
+	EvalLogs []*EvaluationLog `protobuf:"bytes,4,rep,name=eval_logs,json=evalLogs" json:"eval_logs,omitempty"`
 }
 
 func (m *AddMeasurementToTrialsRequest) Reset()                    { *m = AddMeasurementToTrialsRequest{} }
@@ -931,6 +1115,20 @@ func (m *AddMeasurementToTrialsRequest) GetMetrics() string {
 	return ""
 }
 
+func (m *AddMeasurementToTrialsRequest) GetTrialId() string {
+	if m != nil {
+		return m.TrialId
+	}
+	return ""
+}
+
+func (m *AddMeasurementToTrialsRequest) GetEvalLogs() []*EvaluationLog {
+	if m != nil {
+		return m.EvalLogs
+	}
+	return nil
+}
+
 type AddMeasurementToTrialsReply struct {
 }
 
@@ -994,6 +1192,8 @@ type GenerateTrialsRequest struct {
 	Configs         *StudyConfig `protobuf:"bytes,2,opt,name=configs" json:"configs,omitempty"`
 	CompletedTrials []*Trial     `protobuf:"bytes,3,rep,name=completed_trials,json=completedTrials" json:"completed_trials,omitempty"`
 	RunningTrials   []*Trial     `protobuf:"bytes,4,rep,name=running_trials,json=runningTrials" json:"running_trials,omitempty"`
+	// State saved by the suggestion service at the last GenerateTrials. It is used when the service lost the study.
+	State []byte `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
 }
 
 func (m *GenerateTrialsRequest) Reset()                    { *m = GenerateTrialsRequest{} }
@@ -1029,9 +1229,18 @@ func (m *GenerateTrialsRequest) GetRunningTrials() []*Trial {
 	return nil
 }
 
+func (m *GenerateTrialsRequest) GetState() []byte {
+	if m != nil {
+		return m.State
+	}
+	return nil
+}
+
 type GenerateTrialsReply struct {
 	Trials    []*Trial `protobuf:"bytes,1,rep,name=trials" json:"trials,omitempty"`
 	Completed bool     `protobuf:"varint,2,opt,name=completed" json:"completed,omitempty"`
+	// Opaque state of the study that the manager stores and gives back in GenerateTrialsRequest.
+	State []byte `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
 }
 
 func (m *GenerateTrialsReply) Reset()                    { *m = GenerateTrialsReply{} }
@@ -1053,6 +1262,13 @@ func (m *GenerateTrialsReply) GetCompleted() bool {
 	return false
 }
 
+func (m *GenerateTrialsReply) GetState() []byte {
+	if m != nil {
+		return m.State
+	}
+	return nil
+}
+
 type SetSuggestionParametersRequest struct {
 	StudyId              string                 `protobuf:"bytes,1,opt,name=study_id,json=studyId" json:"study_id,omitempty"`
 	SuggestionParameters []*SuggestionParameter `protobuf:"bytes,2,rep,name=suggestion_parameters,json=suggestionParameters" json:"suggestion_parameters,omitempty"`
@@ -1117,6 +1333,266 @@ func (m *StopSuggestionReply) String() string            { return proto.CompactT
 func (*StopSuggestionReply) ProtoMessage()               {}
 func (*StopSuggestionReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
 
+// Resources and scheduling of the container of each trial.
+type ResourceConf struct {
+	// Quantities by resource name, e.g. cpu: "2", memory: "4Gi", ephemeral-storage: "10Gi" or extended resources.
+	Requests     map[string]string `protobuf:"bytes,1,rep,name=requests" json:"requests,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Limits       map[string]string `protobuf:"bytes,2,rep,name=limits" json:"limits,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	NodeSelector map[string]string `protobuf:"bytes,3,rep,name=node_selector,json=nodeSelector" json:"node_selector,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Tolerations  []*Toleration     `protobuf:"bytes,4,rep,name=tolerations" json:"tolerations,omitempty"`
+	// Kubernetes core/v1 Affinity of the pods of the trials in YAML or JSON.
+	Affinity string `protobuf:"bytes,5,opt,name=affinity" json:"affinity,omitempty"`
+}
+
+func (m *ResourceConf) Reset()                    { *m = ResourceConf{} }
+func (m *ResourceConf) String() string            { return proto.CompactTextString(m) }
+func (*ResourceConf) ProtoMessage()               {}
+func (*ResourceConf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *ResourceConf) GetRequests() map[string]string {
+	if m != nil {
+		return m.Requests
+	}
+	return nil
+}
+
+func (m *ResourceConf) GetLimits() map[string]string {
+	if m != nil {
+		return m.Limits
+	}
+	return nil
+}
+
+func (m *ResourceConf) GetNodeSelector() map[string]string {
+	if m != nil {
+		return m.NodeSelector
+	}
+	return nil
+}
+
+func (m *ResourceConf) GetTolerations() []*Toleration {
+	if m != nil {
+		return m.Tolerations
+	}
+	return nil
+}
+
+func (m *ResourceConf) GetAffinity() string {
+	if m != nil {
+		return m.Affinity
+	}
+	return ""
+}
+
+type Toleration struct {
+	Key string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	// Exists or Equal (default)
+	Operator string `protobuf:"bytes,2,opt,name=operator" json:"operator,omitempty"`
+	Value    string `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	// NoSchedule, PreferNoSchedule or NoExecute. Empty matches all effects.
+	Effect string `protobuf:"bytes,4,opt,name=effect" json:"effect,omitempty"`
+}
+
+func (m *Toleration) Reset()                    { *m = Toleration{} }
+func (m *Toleration) String() string            { return proto.CompactTextString(m) }
+func (*Toleration) ProtoMessage()               {}
+func (*Toleration) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+func (m *Toleration) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Toleration) GetOperator() string {
+	if m != nil {
+		return m.Operator
+	}
+	return ""
+}
+
+func (m *Toleration) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Toleration) GetEffect() string {
+	if m != nil {
+		return m.Effect
+	}
+	return ""
+}
+
+// How the parameters of a trial are given to it.
+type ParameterInjection struct {
+	Mode ParameterInjection_Mode `protobuf:"varint,1,opt,name=mode,enum=api.ParameterInjection_Mode" json:"mode,omitempty"`
+	// Go template of the arguments of a parameter, separated by spaces. {{.Name}}={{.Value}} by default.
+	ArgFormat string `protobuf:"bytes,2,opt,name=arg_format,json=argFormat" json:"arg_format,omitempty"`
+	EnvPrefix string `protobuf:"bytes,3,opt,name=env_prefix,json=envPrefix" json:"env_prefix,omitempty"`
+	// /etc/katib/parameters.json by default.
+	FilePath string `protobuf:"bytes,4,opt,name=file_path,json=filePath" json:"file_path,omitempty"`
+}
+
+func (m *ParameterInjection) Reset()                    { *m = ParameterInjection{} }
+func (m *ParameterInjection) String() string            { return proto.CompactTextString(m) }
+func (*ParameterInjection) ProtoMessage()               {}
+func (*ParameterInjection) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+func (m *ParameterInjection) GetMode() ParameterInjection_Mode {
+	if m != nil {
+		return m.Mode
+	}
+	return ParameterInjection_ARGS
+}
+
+func (m *ParameterInjection) GetArgFormat() string {
+	if m != nil {
+		return m.ArgFormat
+	}
+	return ""
+}
+
+func (m *ParameterInjection) GetEnvPrefix() string {
+	if m != nil {
+		return m.EnvPrefix
+	}
+	return ""
+}
+
+func (m *ParameterInjection) GetFilePath() string {
+	if m != nil {
+		return m.FilePath
+	}
+	return ""
+}
+
+// How the metrics of a trial are read from its output.
+type MetricsCollectorConf struct {
+	Kind  MetricsCollectorConf_Kind `protobuf:"varint,1,opt,name=kind,enum=api.MetricsCollectorConf_Kind" json:"kind,omitempty"`
+	Regex string                    `protobuf:"bytes,2,opt,name=regex" json:"regex,omitempty"`
+	// Path of the metrics file or directory, with the placeholders of the command.
+	FilePath string `protobuf:"bytes,3,opt,name=file_path,json=filePath" json:"file_path,omitempty"`
+	// KEY_VALUE, REGEX or JSON_LINES.
+	FileFormat MetricsCollectorConf_Kind `protobuf:"varint,4,opt,name=file_format,json=fileFormat,enum=api.MetricsCollectorConf_Kind" json:"file_format,omitempty"`
+	// Name of the step in KEY_VALUE, JSON_LINES and PROMETHEUS. step by default.
+	StepName string `protobuf:"bytes,5,opt,name=step_name,json=stepName" json:"step_name,omitempty"`
+	// Port and path of the metrics endpoint of PROMETHEUS. /metrics by default.
+	Port int32  `protobuf:"varint,6,opt,name=port" json:"port,omitempty"`
+	Path string `protobuf:"bytes,7,opt,name=path" json:"path,omitempty"`
+	// Prometheus series by metric name, e.g. accuracy: model_accuracy{split="eval"}.
+	// Metrics which are not in it are read from the series of the same name.
+	PrometheusMetrics map[string]string `protobuf:"bytes,8,rep,name=prometheus_metrics,json=prometheusMetrics" json:"prometheus_metrics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// 10 by default.
+	ScrapeInterval int32 `protobuf:"varint,9,opt,name=scrape_interval,json=scrapeInterval" json:"scrape_interval,omitempty"`
+	// Collect the metrics in the pods of the kubernetes worker instead of vizier-core.
+	Sidecar *MetricsCollectorSidecar `protobuf:"bytes,10,opt,name=sidecar" json:"sidecar,omitempty"`
+}
+
+func (m *MetricsCollectorConf) Reset()                    { *m = MetricsCollectorConf{} }
+func (m *MetricsCollectorConf) String() string            { return proto.CompactTextString(m) }
+func (*MetricsCollectorConf) ProtoMessage()               {}
+func (*MetricsCollectorConf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+
+func (m *MetricsCollectorConf) GetKind() MetricsCollectorConf_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return MetricsCollectorConf_KEY_VALUE
+}
+
+func (m *MetricsCollectorConf) GetRegex() string {
+	if m != nil {
+		return m.Regex
+	}
+	return ""
+}
+
+func (m *MetricsCollectorConf) GetFilePath() string {
+	if m != nil {
+		return m.FilePath
+	}
+	return ""
+}
+
+func (m *MetricsCollectorConf) GetFileFormat() MetricsCollectorConf_Kind {
+	if m != nil {
+		return m.FileFormat
+	}
+	return MetricsCollectorConf_KEY_VALUE
+}
+
+func (m *MetricsCollectorConf) GetStepName() string {
+	if m != nil {
+		return m.StepName
+	}
+	return ""
+}
+
+func (m *MetricsCollectorConf) GetPort() int32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *MetricsCollectorConf) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *MetricsCollectorConf) GetPrometheusMetrics() map[string]string {
+	if m != nil {
+		return m.PrometheusMetrics
+	}
+	return nil
+}
+
+func (m *MetricsCollectorConf) GetScrapeInterval() int32 {
+	if m != nil {
+		return m.ScrapeInterval
+	}
+	return 0
+}
+
+func (m *MetricsCollectorConf) GetSidecar() *MetricsCollectorSidecar {
+	if m != nil {
+		return m.Sidecar
+	}
+	return nil
+}
+
+// Container in the pod of each trial which collects its metrics and sends them to vizier-core.
+type MetricsCollectorSidecar struct {
+	// katib/metrics-collector by default.
+	Image string `protobuf:"bytes,1,opt,name=image" json:"image,omitempty"`
+	// Address of vizier-core from the trials. vizier-core.katib:6789 by default.
+	ManagerAddress string `protobuf:"bytes,2,opt,name=manager_address,json=managerAddress" json:"manager_address,omitempty"`
+}
+
+func (m *MetricsCollectorSidecar) Reset()                    { *m = MetricsCollectorSidecar{} }
+func (m *MetricsCollectorSidecar) String() string            { return proto.CompactTextString(m) }
+func (*MetricsCollectorSidecar) ProtoMessage()               {}
+func (*MetricsCollectorSidecar) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+func (m *MetricsCollectorSidecar) GetImage() string {
+	if m != nil {
+		return m.Image
+	}
+	return ""
+}
+
+func (m *MetricsCollectorSidecar) GetManagerAddress() string {
+	if m != nil {
+		return m.ManagerAddress
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*FeasibleSpace)(nil), "api.FeasibleSpace")
 	proto.RegisterType((*ParameterConfig)(nil), "api.ParameterConfig")
@@ -1154,9 +1630,17 @@ func init() {
 	proto.RegisterType((*SetSuggestionParametersReply)(nil), "api.SetSuggestionParametersReply")
 	proto.RegisterType((*StopSuggestionRequest)(nil), "api.StopSuggestionRequest")
 	proto.RegisterType((*StopSuggestionReply)(nil), "api.StopSuggestionReply")
+	proto.RegisterType((*ResourceConf)(nil), "api.ResourceConf")
+	proto.RegisterType((*Toleration)(nil), "api.Toleration")
+	proto.RegisterType((*ParameterInjection)(nil), "api.ParameterInjection")
+	proto.RegisterType((*MetricsCollectorConf)(nil), "api.MetricsCollectorConf")
+	proto.RegisterType((*MetricsCollectorSidecar)(nil), "api.MetricsCollectorSidecar")
 	proto.RegisterEnum("api.ParameterType", ParameterType_name, ParameterType_value)
 	proto.RegisterEnum("api.OptimizationType", OptimizationType_name, OptimizationType_value)
 	proto.RegisterEnum("api.TrialState", TrialState_name, TrialState_value)
+	proto.RegisterEnum("api.DuplicatePolicy", DuplicatePolicy_name, DuplicatePolicy_value)
+	proto.RegisterEnum("api.ParameterInjection_Mode", ParameterInjection_Mode_name, ParameterInjection_Mode_value)
+	proto.RegisterEnum("api.MetricsCollectorConf_Kind", MetricsCollectorConf_Kind_name, MetricsCollectorConf_Kind_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1658,111 +2142,161 @@ var _AutoStopping_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("api.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1690 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xdd, 0x72, 0xdb, 0xc6,
-	0x15, 0x36, 0x48, 0x51, 0x24, 0x0f, 0x45, 0x12, 0x5a, 0x51, 0x31, 0x4c, 0xdb, 0xb1, 0x82, 0x66,
-	0x12, 0x8d, 0x3a, 0x91, 0x1b, 0xba, 0x9d, 0x4e, 0x2e, 0x3a, 0x1d, 0x9a, 0x62, 0x54, 0x8c, 0xf9,
-	0xa3, 0x01, 0xe9, 0xa4, 0xe9, 0x45, 0x39, 0x6b, 0x72, 0x4d, 0xa3, 0x06, 0x01, 0x14, 0xbb, 0x50,
-	0xa2, 0x3c, 0x42, 0xaf, 0x3a, 0xd3, 0xeb, 0xbe, 0x41, 0xaf, 0xfb, 0x0c, 0xbd, 0xeb, 0x4b, 0xf4,
-	0x3d, 0xda, 0xd9, 0xc5, 0x02, 0x04, 0x40, 0x90, 0x62, 0x5b, 0xdf, 0xed, 0x9e, 0x3f, 0x9c, 0xdf,
-	0xef, 0x2c, 0x09, 0x55, 0xec, 0x59, 0x97, 0x9e, 0xef, 0x32, 0x17, 0x15, 0xb1, 0x67, 0xe9, 0xd7,
-	0x50, 0xff, 0x9a, 0x60, 0x6a, 0xbd, 0xb1, 0xc9, 0xc4, 0xc3, 0x73, 0x82, 0x54, 0x28, 0xae, 0xf0,
-	0x0f, 0x9a, 0x72, 0xa6, 0x9c, 0x57, 0x4d, 0x7e, 0x14, 0x14, 0xcb, 0xd1, 0x0a, 0x92, 0x62, 0x39,
-	0x08, 0xc1, 0x81, 0x6d, 0x51, 0xa6, 0x15, 0xcf, 0x8a, 0xe7, 0x55, 0x53, 0x9c, 0xf5, 0x3f, 0x2b,
-	0xd0, 0xbc, 0xc1, 0x3e, 0x5e, 0x11, 0x46, 0xfc, 0x9e, 0xeb, 0xbc, 0xb5, 0x96, 0x5c, 0xce, 0xc1,
-	0x2b, 0x22, 0x8d, 0x89, 0x33, 0xfa, 0x0a, 0x1a, 0x5e, 0x24, 0x36, 0x63, 0x77, 0x1e, 0x11, 0x86,
-	0x1b, 0x1d, 0x74, 0xc9, 0x3d, 0x8b, 0x2d, 0x4c, 0xef, 0x3c, 0x62, 0xd6, 0xbd, 0xe4, 0x15, 0x5d,
-	0x42, 0xe5, 0xad, 0xf4, 0x55, 0x2b, 0x9e, 0x29, 0xe7, 0x35, 0xa9, 0x94, 0x0a, 0xc0, 0x8c, 0x65,
-	0x74, 0x0f, 0xaa, 0xb1, 0xbd, 0x0f, 0xed, 0x4b, 0x0b, 0x4a, 0xb7, 0xd8, 0x0e, 0x42, 0x47, 0xaa,
-	0x66, 0x78, 0xd1, 0x5f, 0x40, 0x79, 0x48, 0x98, 0x6f, 0xcd, 0x69, 0xee, 0xf7, 0x62, 0xa5, 0x42,
-	0x52, 0xe9, 0x15, 0xd4, 0xfb, 0xfc, 0x84, 0x99, 0xe5, 0x3a, 0x03, 0x57, 0xa4, 0x8d, 0x59, 0x6b,
-	0x55, 0x7e, 0x46, 0x9f, 0x41, 0x79, 0x15, 0x5a, 0xd6, 0x0a, 0x67, 0xc5, 0xf3, 0x5a, 0xe7, 0x48,
-	0xf8, 0x28, 0xbf, 0x66, 0x46, 0x4c, 0xfd, 0xd7, 0x70, 0x32, 0x09, 0x96, 0x4b, 0x42, 0xb9, 0xb1,
-	0xdd, 0xd1, 0xe7, 0x7b, 0xf3, 0x1c, 0x8a, 0x53, 0xbc, 0xfc, 0x2f, 0x14, 0xbe, 0x84, 0xea, 0xd0,
-	0x0d, 0x1c, 0xc6, 0x6b, 0xce, 0x7b, 0xc5, 0xbb, 0x9d, 0x47, 0xdd, 0xe3, 0xdd, 0xce, 0xb9, 0x21,
-	0x0f, 0xb3, 0x77, 0x52, 0x47, 0x9c, 0xf5, 0xbf, 0x14, 0xa0, 0x34, 0xf5, 0x2d, 0x6c, 0xa3, 0x47,
-	0x50, 0x61, 0xfc, 0x30, 0xb3, 0x16, 0x52, 0xa9, 0x2c, 0xee, 0xc6, 0x82, 0xb3, 0x28, 0x0b, 0x16,
-	0x77, 0x9c, 0x15, 0x2a, 0x97, 0xc5, 0xdd, 0x58, 0xa0, 0x17, 0xb0, 0xae, 0xc6, 0x8c, 0x92, 0xb0,
-	0x11, 0x6b, 0x9d, 0x46, 0xba, 0x6c, 0xe6, 0x51, 0x2c, 0x34, 0x21, 0x0c, 0x7d, 0x0e, 0x87, 0x94,
-	0x61, 0x16, 0x50, 0xed, 0x40, 0x14, 0xb9, 0x29, 0xa4, 0x85, 0x1b, 0x13, 0x86, 0x19, 0x31, 0x25,
-	0x1b, 0x3d, 0x87, 0x2a, 0xb9, 0xc5, 0xf6, 0xcc, 0x76, 0x97, 0x54, 0x2b, 0x09, 0xcb, 0x61, 0x43,
-	0xa4, 0xaa, 0x64, 0x56, 0xb8, 0xd0, 0xc0, 0x5d, 0x52, 0xf4, 0x39, 0x34, 0xdd, 0x37, 0x7f, 0x20,
-	0x73, 0x66, 0xdd, 0x92, 0x59, 0x98, 0xa1, 0x43, 0xe1, 0x70, 0x23, 0x26, 0x7f, 0xc3, 0xa9, 0xe8,
-	0x09, 0x1c, 0x30, 0xbc, 0xa4, 0x5a, 0x59, 0x18, 0xad, 0x84, 0x0e, 0xe0, 0xa5, 0x29, 0xa8, 0xfa,
-	0x3f, 0x0f, 0xa1, 0x36, 0xe1, 0x11, 0xee, 0x98, 0x9e, 0x16, 0x94, 0xdc, 0xef, 0x1d, 0xe2, 0x47,
-	0x25, 0x10, 0x17, 0xf4, 0x12, 0x8e, 0x5d, 0x8f, 0x59, 0x2b, 0xeb, 0x47, 0xe1, 0x5d, 0xd8, 0xca,
-	0x45, 0x11, 0xe5, 0xa9, 0xf8, 0xc8, 0x38, 0xc1, 0x15, 0xdd, 0xac, 0xba, 0x19, 0x0a, 0xfa, 0x69,
-	0xc6, 0xc6, 0xd2, 0xc5, 0xb6, 0xc8, 0x94, 0x92, 0x16, 0xbe, 0x76, 0xb1, 0x8d, 0x46, 0x70, 0xbc,
-	0x2e, 0xc0, 0x5c, 0xb8, 0xcb, 0x53, 0xc5, 0x47, 0xf2, 0x13, 0xf1, 0xc1, 0x44, 0x1c, 0x97, 0x19,
-	0x54, 0xa0, 0xa6, 0xea, 0x65, 0x28, 0xe8, 0x0b, 0x40, 0x78, 0x3e, 0x27, 0x94, 0xce, 0x3c, 0xe2,
-	0xaf, 0x2c, 0x4a, 0x2d, 0xd7, 0xa1, 0xda, 0xa1, 0x80, 0x97, 0xe3, 0x90, 0x73, 0xb3, 0x66, 0x70,
-	0x5f, 0x69, 0xd8, 0xe4, 0x33, 0x6c, 0x2f, 0x5d, 0xdf, 0x62, 0xef, 0x56, 0x5a, 0x59, 0x64, 0x44,
-	0x95, 0x8c, 0x6e, 0x44, 0x17, 0xb6, 0x03, 0xe6, 0x52, 0xe6, 0x7a, 0x09, 0xe9, 0x8a, 0x90, 0x3e,
-	0x8e, 0x38, 0x6b, 0xf1, 0xcf, 0xa0, 0x19, 0xb6, 0x1d, 0xc3, 0xf4, 0xfd, 0x4c, 0x14, 0xa0, 0x2a,
-	0x64, 0xeb, 0x82, 0x3c, 0xc5, 0xf4, 0xfd, 0x88, 0x57, 0x62, 0x08, 0xa7, 0x34, 0x1e, 0xb4, 0x59,
-	0x1c, 0x11, 0xd5, 0x40, 0x14, 0x57, 0x0b, 0xd3, 0xb0, 0x39, 0x8a, 0x66, 0x8b, 0x6e, 0x12, 0x69,
-	0xdc, 0x1a, 0xb5, 0xbc, 0xd6, 0x40, 0x3f, 0x83, 0x56, 0xa6, 0xc3, 0x42, 0xcf, 0x8e, 0x84, 0x67,
-	0x28, 0xdd, 0x66, 0xc2, 0x3d, 0x6d, 0x8d, 0x17, 0x75, 0x91, 0xc6, 0xe8, 0xca, 0x5b, 0xc8, 0x5a,
-	0xe1, 0x25, 0xd1, 0x1a, 0x61, 0x0b, 0x89, 0x0b, 0x97, 0x9f, 0xbb, 0xab, 0x15, 0x76, 0x16, 0x5a,
-	0x33, 0x94, 0x97, 0x57, 0x3e, 0xd2, 0x4b, 0x2f, 0xd0, 0xd4, 0x33, 0xe5, 0xbc, 0x64, 0xf2, 0x23,
-	0x7a, 0x02, 0x55, 0x3a, 0x7f, 0x47, 0x16, 0x81, 0x4d, 0x7c, 0xed, 0x58, 0x58, 0x59, 0x13, 0xd0,
-	0xa7, 0x50, 0x5a, 0x71, 0x3c, 0xd0, 0x90, 0xe8, 0x87, 0x70, 0x28, 0x63, 0x84, 0x30, 0x43, 0x26,
-	0x7a, 0x06, 0x35, 0x2f, 0xb0, 0xed, 0x19, 0x25, 0x73, 0x9f, 0x30, 0xed, 0x44, 0x58, 0x01, 0x4e,
-	0x9a, 0x08, 0x4a, 0xfb, 0x25, 0xa8, 0xd9, 0xc6, 0x41, 0x97, 0xdc, 0xc9, 0xb0, 0xd9, 0x14, 0x91,
-	0xa7, 0x56, 0x7a, 0xe2, 0x43, 0x39, 0x33, 0x12, 0xd2, 0x0d, 0x40, 0x3d, 0x9f, 0x60, 0x46, 0x44,
-	0x3b, 0x9a, 0xe4, 0x8f, 0x01, 0xa1, 0x0c, 0xbd, 0x80, 0xa3, 0xb0, 0xc2, 0xa1, 0x98, 0x98, 0xaf,
-	0x5a, 0x47, 0xcd, 0xf6, 0xad, 0x59, 0xa3, 0xeb, 0x8b, 0xfe, 0x05, 0xa8, 0x29, 0x53, 0x9e, 0x7d,
-	0x97, 0x42, 0x28, 0x25, 0x85, 0x50, 0x5c, 0x7c, 0xc2, 0x5c, 0x2f, 0xf5, 0xdd, 0x1d, 0xe2, 0x2a,
-	0x34, 0x12, 0xe2, 0x9e, 0x7d, 0xa7, 0x23, 0x50, 0xaf, 0x09, 0x13, 0x04, 0x2a, 0x0d, 0xe8, 0x7f,
-	0x53, 0xa0, 0x2a, 0x28, 0x86, 0xf3, 0xd6, 0xdd, 0x61, 0x2e, 0x46, 0x8e, 0x42, 0x1e, 0x72, 0x14,
-	0x93, 0xc8, 0x71, 0x01, 0xc7, 0x7e, 0xe0, 0x38, 0x96, 0xb3, 0x9c, 0x85, 0x38, 0xec, 0x04, 0x2b,
-	0x31, 0xf5, 0x25, 0xb3, 0x29, 0x19, 0x02, 0x21, 0x47, 0xc1, 0x0a, 0x5d, 0xc2, 0xc9, 0xdc, 0x5d,
-	0x79, 0x36, 0x61, 0x64, 0x91, 0x90, 0x2e, 0x09, 0xe9, 0xe3, 0x98, 0x15, 0xc9, 0xeb, 0x5d, 0x68,
-	0x24, 0x42, 0xe0, 0x09, 0x7b, 0x0e, 0x35, 0xe9, 0xb2, 0xf3, 0xd6, 0x8d, 0x6a, 0xd8, 0x58, 0x27,
-	0x9e, 0xc7, 0x65, 0x02, 0x8d, 0x8e, 0x54, 0xff, 0x93, 0x02, 0x2d, 0x39, 0x43, 0xc2, 0x2c, 0xbd,
-	0x3f, 0x97, 0xf9, 0xe0, 0x50, 0xd8, 0x02, 0x0e, 0x17, 0xeb, 0x8e, 0x2a, 0x6e, 0x69, 0x83, 0xb8,
-	0x9b, 0xbe, 0x01, 0x94, 0xf1, 0x85, 0xc7, 0xa4, 0xc3, 0xa1, 0xc8, 0x45, 0x14, 0x0e, 0xac, 0xd7,
-	0x8a, 0x29, 0x39, 0x7c, 0x60, 0xe2, 0xf4, 0x08, 0x57, 0x2a, 0xe6, 0x9a, 0xa0, 0x4f, 0xa1, 0xd5,
-	0x93, 0x97, 0x50, 0x4d, 0xc6, 0xf8, 0x18, 0xaa, 0xdf, 0xbb, 0xfe, 0x7b, 0xe2, 0xaf, 0x83, 0xac,
-	0x84, 0x04, 0x63, 0xc1, 0xe7, 0xc7, 0xa2, 0xb3, 0xc8, 0x88, 0x34, 0x0a, 0x16, 0x8d, 0x2c, 0xe9,
-	0x2d, 0x40, 0x19, 0xab, 0xbc, 0xad, 0xde, 0xc0, 0x47, 0x93, 0x77, 0x6e, 0x60, 0x2f, 0xe4, 0xde,
-	0x73, 0xbd, 0x3d, 0x32, 0x9a, 0x8f, 0xa0, 0x85, 0x2d, 0x08, 0xaa, 0x7f, 0x07, 0xad, 0x8d, 0x6f,
-	0xec, 0x9b, 0xa9, 0xa7, 0x00, 0x71, 0xcc, 0xe1, 0x4b, 0xa7, 0x6a, 0x56, 0xa3, 0xa0, 0xa9, 0xfe,
-	0x73, 0x38, 0xbd, 0x26, 0x6c, 0x2c, 0xe0, 0x4e, 0x60, 0xdd, 0x3e, 0xb9, 0xd2, 0xbf, 0x82, 0x93,
-	0xac, 0xd6, 0x9e, 0xfe, 0xe8, 0x53, 0x78, 0xda, 0x5d, 0x2c, 0x86, 0x04, 0xd3, 0xc0, 0x27, 0x2b,
-	0xe2, 0xb0, 0xa9, 0xbb, 0x77, 0x23, 0x6a, 0xc9, 0x27, 0x9b, 0x92, 0x80, 0x60, 0xfd, 0x29, 0x3c,
-	0xde, 0x66, 0x95, 0x17, 0xe9, 0x5f, 0x0a, 0x3c, 0x33, 0x1c, 0x8b, 0x59, 0xd8, 0xb6, 0x7e, 0x24,
-	0xb2, 0xe7, 0x26, 0xc4, 0xbf, 0xb5, 0xe6, 0xe4, 0x43, 0x0f, 0xc0, 0xd6, 0x35, 0x56, 0xfc, 0x9f,
-	0xd6, 0x58, 0x62, 0x9e, 0x0e, 0xee, 0x9b, 0xa7, 0x67, 0xf0, 0x74, 0x7b, 0x94, 0x3c, 0x0f, 0xff,
-	0x50, 0x78, 0xb9, 0x1d, 0xe2, 0x63, 0xd9, 0xc3, 0xfb, 0x64, 0x3d, 0xe1, 0x41, 0xe1, 0x1e, 0x0f,
-	0xd0, 0x2f, 0x40, 0xcd, 0x20, 0x5a, 0x14, 0x77, 0xb2, 0x17, 0x9a, 0x69, 0x68, 0xa3, 0xe8, 0x4b,
-	0x68, 0xa4, 0x40, 0x93, 0xc7, 0x9a, 0x55, 0xaa, 0x27, 0xd1, 0x93, 0xea, 0xdf, 0xf2, 0x16, 0x4c,
-	0x47, 0xf2, 0x61, 0xc0, 0xe3, 0xef, 0x0a, 0x7c, 0x3c, 0x21, 0x2c, 0xa7, 0x42, 0xfb, 0x24, 0x6b,
-	0x6b, 0xf5, 0x0b, 0xff, 0x6f, 0xf5, 0xef, 0x45, 0xd3, 0x8f, 0xe1, 0xc9, 0x56, 0xbf, 0x79, 0xf1,
-	0x3b, 0x70, 0x2a, 0x56, 0x62, 0x2c, 0xb0, 0xc7, 0x1a, 0x3d, 0x85, 0x93, 0xac, 0x8e, 0x67, 0xdf,
-	0x5d, 0xbc, 0x86, 0x7a, 0xea, 0xb7, 0x1c, 0x52, 0xe1, 0xe8, 0xf5, 0xe8, 0xd5, 0x68, 0xfc, 0xed,
-	0x68, 0x36, 0xfd, 0xee, 0xa6, 0xaf, 0x3e, 0x40, 0x00, 0x87, 0x57, 0xe3, 0xd7, 0x2f, 0x07, 0x7d,
-	0x55, 0x41, 0x65, 0x28, 0x1a, 0xa3, 0xa9, 0x5a, 0x40, 0x47, 0x50, 0xb9, 0x32, 0x26, 0x3d, 0xb3,
-	0x3f, 0xed, 0xab, 0x45, 0xd4, 0x84, 0x5a, 0xaf, 0x3b, 0xed, 0x5f, 0x8f, 0x4d, 0xa3, 0xd7, 0x1d,
-	0xa8, 0x07, 0x17, 0xbf, 0x01, 0x35, 0xfb, 0xae, 0x46, 0x1a, 0xb4, 0x22, 0xcb, 0xe3, 0x9b, 0xa9,
-	0x31, 0x34, 0x7e, 0xd7, 0x9d, 0x1a, 0xe3, 0x91, 0xfa, 0x80, 0x1b, 0x1b, 0x1a, 0x23, 0x4e, 0xe1,
-	0xdf, 0xe0, 0xb7, 0xee, 0x6f, 0xc3, 0x5b, 0xe1, 0x62, 0x00, 0xb0, 0xfe, 0x1d, 0x82, 0x6a, 0x50,
-	0xbe, 0xe9, 0x8f, 0xae, 0x8c, 0xd1, 0xb5, 0xfa, 0x80, 0x5f, 0xcc, 0xd7, 0xa3, 0x11, 0xbf, 0x28,
-	0xa8, 0x0e, 0xd5, 0xde, 0x78, 0x78, 0x33, 0xe8, 0x4f, 0xfb, 0x57, 0x6a, 0x81, 0x3b, 0xfd, 0xca,
-	0x18, 0x0c, 0xfa, 0x57, 0x6a, 0x11, 0x55, 0xa1, 0xd4, 0x37, 0xcd, 0xb1, 0xa9, 0xfe, 0xd0, 0xf9,
-	0x6b, 0x09, 0xca, 0x43, 0xec, 0xe0, 0x25, 0xf1, 0xd1, 0xaf, 0xa0, 0x96, 0x78, 0xb6, 0xa0, 0x87,
-	0xa2, 0x1e, 0x9b, 0x6f, 0xa2, 0xf6, 0xe9, 0x26, 0x83, 0xf7, 0xe7, 0x2f, 0xf9, 0x83, 0x43, 0xbe,
-	0x4b, 0xd0, 0xa9, 0x2c, 0x66, 0xfa, 0x59, 0xd3, 0x3e, 0xc9, 0x92, 0xa5, 0x62, 0xbc, 0xfb, 0xa5,
-	0x62, 0xf6, 0x39, 0x23, 0x15, 0x33, 0x4f, 0x84, 0x1e, 0xd4, 0x53, 0x4b, 0x16, 0x3d, 0x4a, 0xf6,
-	0x60, 0x0a, 0x05, 0xda, 0x0f, 0xf3, 0x58, 0xd2, 0x48, 0x6a, 0xf7, 0x49, 0x23, 0x79, 0x5b, 0x56,
-	0x1a, 0xd9, 0x5c, 0x95, 0xc8, 0x80, 0x66, 0x66, 0x8d, 0xa1, 0xc7, 0xe1, 0x07, 0x73, 0x17, 0x68,
-	0xfb, 0x51, 0x3e, 0x93, 0x9b, 0xfa, 0x5a, 0xbc, 0x84, 0x12, 0x0b, 0x08, 0xb5, 0xa3, 0xd8, 0x37,
-	0x77, 0x59, 0x5b, 0xcb, 0xe5, 0x71, 0x3b, 0xbf, 0x87, 0x8f, 0xf2, 0xf7, 0x06, 0xd2, 0x85, 0xce,
-	0xce, 0x55, 0xd5, 0x3e, 0xdb, 0x29, 0xc3, 0xed, 0x2f, 0x40, 0xdb, 0x86, 0xc8, 0xe8, 0x53, 0xa1,
-	0x7d, 0xcf, 0x5a, 0x6a, 0xeb, 0xf7, 0x48, 0x79, 0xf6, 0x5d, 0xe7, 0xdf, 0x0a, 0xc0, 0x7a, 0x44,
-	0xc3, 0xe4, 0x24, 0xa1, 0x31, 0x4e, 0x4e, 0x0e, 0xf2, 0xc7, 0xc9, 0xd9, 0xc4, 0x52, 0x0c, 0x0f,
-	0xb7, 0x00, 0x0a, 0xfa, 0x49, 0x58, 0x9a, 0x9d, 0x30, 0xd9, 0xfe, 0x64, 0xb7, 0x90, 0xac, 0x63,
-	0x1a, 0x5f, 0xa4, 0xab, 0xb9, 0x40, 0x25, 0x5d, 0xcd, 0x01, 0xa4, 0x4e, 0x03, 0x8e, 0xba, 0x01,
-	0x73, 0x39, 0xcb, 0xb3, 0x9c, 0xe5, 0x9b, 0x43, 0xf1, 0x87, 0xdc, 0x8b, 0xff, 0x04, 0x00, 0x00,
-	0xff, 0xff, 0x86, 0x76, 0x0e, 0x54, 0x9d, 0x13, 0x00, 0x00,
+	// 2485 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x39, 0x5b, 0x6f, 0xdb, 0xc8,
+	0xd5, 0xa1, 0x2e, 0xb6, 0x74, 0x64, 0x4b, 0xf4, 0x58, 0x8e, 0x19, 0xe5, 0xe6, 0xf0, 0x5b, 0x6c,
+	0x02, 0x7f, 0x58, 0x27, 0x71, 0xba, 0x6d, 0xd3, 0x45, 0x11, 0x28, 0x36, 0xed, 0xa8, 0x91, 0x25,
+	0x81, 0x92, 0xb3, 0x49, 0x1f, 0x4a, 0xd0, 0xd2, 0x58, 0x66, 0xcc, 0x5b, 0x39, 0x23, 0x6f, 0xbc,
+	0x3f, 0xa1, 0x4f, 0x05, 0xda, 0xd7, 0x02, 0xfd, 0x01, 0x7d, 0xee, 0x2f, 0xd9, 0xe7, 0x3e, 0xb5,
+	0xbf, 0xa3, 0xc5, 0xcc, 0x90, 0x14, 0x29, 0x51, 0xb6, 0xd3, 0xe6, 0x8d, 0x73, 0x6e, 0x73, 0x6e,
+	0x73, 0xe6, 0x9c, 0x21, 0x94, 0x4d, 0xdf, 0xda, 0xf1, 0x03, 0x8f, 0x7a, 0x28, 0x6f, 0xfa, 0x96,
+	0x7a, 0x08, 0xab, 0x07, 0xd8, 0x24, 0xd6, 0x89, 0x8d, 0xfb, 0xbe, 0x39, 0xc4, 0x48, 0x86, 0xbc,
+	0x63, 0x7e, 0x52, 0xa4, 0x2d, 0xe9, 0x49, 0x59, 0x67, 0x9f, 0x1c, 0x62, 0xb9, 0x4a, 0x2e, 0x84,
+	0x58, 0x2e, 0x42, 0x50, 0xb0, 0x2d, 0x42, 0x95, 0xfc, 0x56, 0xfe, 0x49, 0x59, 0xe7, 0xdf, 0xea,
+	0x1f, 0x25, 0xa8, 0xf5, 0xcc, 0xc0, 0x74, 0x30, 0xc5, 0xc1, 0x9e, 0xe7, 0x9e, 0x5a, 0x63, 0x46,
+	0xe7, 0x9a, 0x0e, 0x0e, 0x85, 0xf1, 0x6f, 0xf4, 0x12, 0xaa, 0x7e, 0x44, 0x66, 0xd0, 0x4b, 0x1f,
+	0x73, 0xc1, 0xd5, 0x5d, 0xb4, 0xc3, 0x34, 0x8b, 0x25, 0x0c, 0x2e, 0x7d, 0xac, 0xaf, 0xfa, 0xc9,
+	0x25, 0xda, 0x81, 0xd2, 0x69, 0xa8, 0xab, 0x92, 0xdf, 0x92, 0x9e, 0x54, 0x42, 0xa6, 0x94, 0x01,
+	0x7a, 0x4c, 0xa3, 0xfa, 0x50, 0x8e, 0xe5, 0x7d, 0x69, 0x5d, 0xea, 0x50, 0xbc, 0x30, 0xed, 0x89,
+	0x50, 0xa4, 0xac, 0x8b, 0x85, 0xfa, 0x02, 0x96, 0x8f, 0x30, 0x0d, 0xac, 0x21, 0xc9, 0xdc, 0x2f,
+	0x66, 0xca, 0x25, 0x99, 0x0c, 0x58, 0xd5, 0xd8, 0x97, 0x49, 0x2d, 0xcf, 0x6d, 0x7b, 0xdc, 0x6d,
+	0xd4, 0x9a, 0xb2, 0xb2, 0x6f, 0xf4, 0x35, 0x2c, 0x3b, 0x42, 0xb2, 0x92, 0xdb, 0xca, 0x3f, 0xa9,
+	0xec, 0xae, 0x70, 0x1d, 0xc3, 0xdd, 0xf4, 0x08, 0xc9, 0x78, 0x09, 0xc5, 0x3e, 0x57, 0x2b, 0xaf,
+	0xf3, 0x6f, 0xf5, 0x15, 0xac, 0xf7, 0x27, 0xe3, 0x31, 0x26, 0x6c, 0x83, 0xab, 0x3d, 0x92, 0xad,
+	0xe1, 0x53, 0xc8, 0x0f, 0xcc, 0xf1, 0x67, 0x30, 0x3c, 0x87, 0xf2, 0x91, 0x37, 0x71, 0x29, 0xcb,
+	0x03, 0x96, 0x3f, 0xfe, 0xc5, 0x30, 0xca, 0x28, 0xff, 0x62, 0xc8, 0x04, 0xf9, 0x26, 0x3d, 0x0b,
+	0x79, 0xf8, 0xb7, 0xfa, 0xa7, 0x1c, 0x14, 0x07, 0x81, 0x65, 0xda, 0xe8, 0x0e, 0x94, 0x28, 0xfb,
+	0x30, 0xac, 0x51, 0xc8, 0xb4, 0xcc, 0xd7, 0xad, 0x11, 0x43, 0x11, 0x3a, 0x19, 0x5d, 0x32, 0x94,
+	0x60, 0x5e, 0xe6, 0xeb, 0xd6, 0x08, 0xbd, 0x80, 0x69, 0x84, 0x0c, 0x82, 0x45, 0x72, 0x56, 0x76,
+	0xab, 0xe9, 0x50, 0xea, 0x2b, 0x31, 0x51, 0x1f, 0x53, 0xf4, 0x18, 0x96, 0x08, 0x35, 0xe9, 0x84,
+	0x28, 0x05, 0x1e, 0xf8, 0x1a, 0xa7, 0xe6, 0x6a, 0xf4, 0xa9, 0x49, 0xb1, 0x1e, 0xa2, 0xd1, 0x53,
+	0x28, 0xe3, 0x0b, 0xd3, 0x36, 0x6c, 0x6f, 0x4c, 0x94, 0x22, 0x97, 0x2c, 0x92, 0x24, 0x15, 0x39,
+	0xbd, 0xc4, 0x88, 0xda, 0xde, 0x98, 0xa0, 0xc7, 0x50, 0xf3, 0x4e, 0x3e, 0xe2, 0x21, 0xb5, 0x2e,
+	0xb0, 0x21, 0x3c, 0xb4, 0xc4, 0x15, 0xae, 0xc6, 0xe0, 0x77, 0x0c, 0x8a, 0xee, 0x41, 0x81, 0x9a,
+	0x63, 0xa2, 0x2c, 0x73, 0xa1, 0x25, 0xa1, 0x80, 0x39, 0xd6, 0x39, 0x54, 0xfd, 0x73, 0x19, 0x2a,
+	0x7d, 0x66, 0xe1, 0x15, 0x27, 0xaa, 0x0e, 0x45, 0xef, 0x07, 0x17, 0x07, 0x51, 0x08, 0xf8, 0x02,
+	0xbd, 0x86, 0x35, 0xcf, 0xa7, 0x96, 0x63, 0xfd, 0xc8, 0xb5, 0x13, 0xe9, 0x9d, 0xe7, 0x56, 0x6e,
+	0xf0, 0x4d, 0xba, 0x09, 0x2c, 0xcf, 0x70, 0xd9, 0x9b, 0x81, 0xa0, 0xff, 0x9f, 0x91, 0x31, 0xf6,
+	0x4c, 0x9b, 0x7b, 0x4a, 0x4a, 0x13, 0x1f, 0x7a, 0xa6, 0x8d, 0x3a, 0xb0, 0x36, 0x0d, 0xc0, 0x90,
+	0xab, 0xcb, 0x5c, 0xc5, 0x8e, 0xe9, 0x23, 0xbe, 0x61, 0xc2, 0x8e, 0x9d, 0x99, 0x4a, 0x41, 0x74,
+	0xd9, 0x9f, 0x81, 0xa0, 0x6f, 0x00, 0x99, 0xc3, 0x21, 0x26, 0xc4, 0xf0, 0x71, 0xe0, 0x58, 0x84,
+	0x58, 0x9e, 0x4b, 0x94, 0x25, 0x5e, 0x72, 0xd6, 0x04, 0xa6, 0x37, 0x45, 0x30, 0x5d, 0x89, 0x48,
+	0x72, 0xc3, 0xb4, 0xc7, 0x5e, 0x60, 0xd1, 0x33, 0x47, 0x59, 0xe6, 0x1e, 0x91, 0x43, 0x44, 0x33,
+	0x82, 0x73, 0xd9, 0x13, 0xea, 0x11, 0xea, 0xf9, 0x09, 0xea, 0x12, 0xa7, 0x5e, 0x8b, 0x30, 0x53,
+	0xf2, 0xaf, 0xa1, 0x26, 0xd2, 0x8e, 0x9a, 0xe4, 0xdc, 0xe0, 0x01, 0x28, 0x73, 0xda, 0x55, 0x0e,
+	0x1e, 0x98, 0xe4, 0xbc, 0xc3, 0x22, 0x71, 0x04, 0x1b, 0x24, 0x3e, 0x68, 0x46, 0x6c, 0x11, 0x51,
+	0x80, 0x07, 0x57, 0x11, 0x6e, 0x98, 0x3f, 0x8a, 0x7a, 0x9d, 0xcc, 0x03, 0x49, 0x9c, 0x1a, 0x95,
+	0xac, 0xd4, 0x40, 0xcf, 0xa0, 0x3e, 0x93, 0x61, 0x42, 0xb3, 0x15, 0xae, 0x19, 0x4a, 0xa7, 0x19,
+	0x57, 0x4f, 0x99, 0xd6, 0x90, 0x55, 0xee, 0xc6, 0x68, 0xc9, 0x52, 0xc8, 0x72, 0xcc, 0x31, 0x56,
+	0xaa, 0x22, 0x85, 0xf8, 0x82, 0xd1, 0x0f, 0x3d, 0xc7, 0x31, 0xdd, 0x91, 0x52, 0x13, 0xf4, 0xe1,
+	0x92, 0x1d, 0xe9, 0xb1, 0x3f, 0x51, 0xe4, 0x2d, 0xe9, 0x49, 0x51, 0x67, 0x9f, 0xe8, 0x1e, 0x94,
+	0xc9, 0xf0, 0x0c, 0x8f, 0x26, 0x36, 0x0e, 0x94, 0x35, 0x2e, 0x65, 0x0a, 0x40, 0x5f, 0x41, 0xd1,
+	0x61, 0xf5, 0x40, 0x41, 0x5b, 0x52, 0x7c, 0x28, 0xe3, 0x0a, 0xa1, 0x0b, 0x24, 0x7a, 0x08, 0x15,
+	0x7f, 0x62, 0xdb, 0x06, 0xc1, 0xc3, 0x00, 0x53, 0x65, 0x9d, 0x4b, 0x01, 0x06, 0xea, 0x73, 0x08,
+	0x7a, 0x05, 0xf2, 0x68, 0xe2, 0xdb, 0xd6, 0xd0, 0xa4, 0xd8, 0xf0, 0x3d, 0xdb, 0x1a, 0x5e, 0x2a,
+	0x75, 0x9e, 0xd2, 0x75, 0x2e, 0x71, 0x3f, 0x42, 0xf6, 0x38, 0x4e, 0xaf, 0x8d, 0xd2, 0x00, 0xf4,
+	0x08, 0x56, 0x3e, 0x7a, 0x27, 0x06, 0xc5, 0x8e, 0x6f, 0x9b, 0x14, 0x2b, 0x1b, 0x7c, 0x8b, 0xca,
+	0x47, 0xef, 0x64, 0x10, 0x82, 0x98, 0x21, 0xcc, 0x8d, 0x84, 0xdd, 0x25, 0xca, 0x6d, 0x61, 0x48,
+	0x0c, 0x60, 0x75, 0x20, 0xc0, 0xc4, 0x9b, 0x04, 0x43, 0x4c, 0x94, 0x4d, 0x6e, 0xcc, 0x1a, 0xdf,
+	0x5a, 0x0f, 0xa1, 0xdc, 0x9e, 0x29, 0x0d, 0x7a, 0x03, 0xeb, 0xd3, 0x53, 0x61, 0xb9, 0x3c, 0x26,
+	0x9e, 0xab, 0x28, 0x9c, 0x75, 0x33, 0x5d, 0x9c, 0x5a, 0x11, 0x5a, 0x47, 0xfe, 0x1c, 0x0c, 0x1d,
+	0xc0, 0x5a, 0x18, 0x2e, 0x63, 0xe8, 0xd9, 0x36, 0x1e, 0x52, 0x2f, 0x50, 0xee, 0x70, 0x39, 0x77,
+	0x92, 0x77, 0xc1, 0x5e, 0x84, 0xe4, 0xaa, 0xc8, 0xce, 0x0c, 0xb4, 0xf1, 0x1a, 0xe4, 0xd9, 0xd3,
+	0x87, 0x76, 0x58, 0xa4, 0xf9, 0xa7, 0x22, 0xf1, 0x64, 0xab, 0xa7, 0x35, 0x13, 0x74, 0x7a, 0x44,
+	0xa4, 0xb6, 0x00, 0xed, 0x05, 0xd8, 0xa4, 0x98, 0x9f, 0x69, 0x1d, 0xff, 0x7e, 0x82, 0x09, 0x45,
+	0x2f, 0x60, 0x45, 0x1c, 0x13, 0x41, 0xc6, 0x8b, 0x54, 0x65, 0x57, 0x9e, 0x3d, 0xfc, 0x7a, 0x85,
+	0x4c, 0x17, 0xea, 0x37, 0x20, 0xa7, 0x44, 0xf9, 0xf6, 0x65, 0xaa, 0xcc, 0x4b, 0xa9, 0x32, 0xcf,
+	0xc8, 0xfb, 0xd4, 0xf3, 0x53, 0xfb, 0x5e, 0x41, 0x2e, 0x43, 0x35, 0x41, 0xee, 0xdb, 0x97, 0x2a,
+	0x02, 0xf9, 0x10, 0x53, 0x0e, 0x20, 0xa1, 0x00, 0xf5, 0x6f, 0x12, 0x94, 0x39, 0xa4, 0xe5, 0x9e,
+	0x7a, 0x57, 0x88, 0x8b, 0xcb, 0x6f, 0x2e, 0xab, 0xfc, 0xe6, 0x93, 0xe5, 0x77, 0x1b, 0xd6, 0x82,
+	0x89, 0xeb, 0x5a, 0xee, 0xd8, 0x10, 0x97, 0x99, 0x3b, 0x71, 0x78, 0xe9, 0x2c, 0xea, 0xb5, 0x10,
+	0xc1, 0xaf, 0x99, 0xce, 0xc4, 0x41, 0x3b, 0xb0, 0x3e, 0xf4, 0x1c, 0xdf, 0xc6, 0x14, 0x8f, 0x12,
+	0xd4, 0x45, 0x4e, 0xbd, 0x16, 0xa3, 0x22, 0x7a, 0xb5, 0x09, 0xd5, 0x84, 0x09, 0xcc, 0x61, 0x4f,
+	0xa1, 0x12, 0xaa, 0xec, 0x9e, 0x7a, 0x51, 0x0c, 0xab, 0x53, 0xc7, 0x33, 0xbb, 0x74, 0x20, 0xd1,
+	0x27, 0x51, 0xff, 0x20, 0x41, 0x3d, 0x2c, 0x44, 0x5c, 0x2c, 0xb9, 0xde, 0x97, 0xd9, 0x15, 0x36,
+	0xb7, 0xa0, 0xc2, 0x6e, 0x4f, 0x33, 0x2a, 0xbf, 0x20, 0x0d, 0xe2, 0x6c, 0x7a, 0x07, 0x68, 0x46,
+	0x17, 0x66, 0x93, 0x0a, 0x4b, 0xdc, 0x17, 0x91, 0x39, 0x30, 0xbd, 0x9b, 0xf5, 0x10, 0xc3, 0x0e,
+	0x6b, 0xec, 0x1e, 0xae, 0x4a, 0x49, 0x9f, 0x02, 0xb8, 0x91, 0x7b, 0xe1, 0x4a, 0xf0, 0x85, 0x46,
+	0xde, 0x85, 0xf2, 0x0f, 0x5e, 0x70, 0x8e, 0x83, 0xa9, 0x95, 0x25, 0x01, 0x68, 0x8d, 0x58, 0x15,
+	0xb2, 0xd8, 0x11, 0x13, 0x7c, 0xa1, 0x54, 0xb0, 0x48, 0x24, 0x29, 0xe5, 0xa2, 0x7c, 0xda, 0x45,
+	0xc9, 0xd6, 0xa5, 0x90, 0x6a, 0x5d, 0xd4, 0x3a, 0xa0, 0x19, 0x5d, 0x58, 0x36, 0x9e, 0xc0, 0xed,
+	0xfe, 0x99, 0x37, 0xb1, 0x47, 0x61, 0xcf, 0xe1, 0xf9, 0x37, 0x08, 0x44, 0xf6, 0xed, 0x95, 0x5b,
+	0x70, 0x7b, 0xa9, 0x1f, 0xa0, 0x3e, 0xb7, 0xc7, 0x4d, 0x1d, 0x7c, 0x1f, 0x20, 0xf6, 0x94, 0xe8,
+	0x3c, 0xcb, 0x7a, 0x39, 0x72, 0x15, 0x51, 0x7f, 0x06, 0x1b, 0x87, 0x98, 0x76, 0xf9, 0x55, 0xc3,
+	0xef, 0x99, 0x9b, 0x78, 0x58, 0x7d, 0x09, 0xeb, 0xb3, 0x5c, 0x37, 0xd4, 0x47, 0xfd, 0xab, 0x04,
+	0xf7, 0x9b, 0xa3, 0xd1, 0x11, 0x36, 0xc9, 0x24, 0xc0, 0x0e, 0x76, 0xe9, 0xc0, 0xbb, 0x71, 0x02,
+	0x2b, 0xc9, 0x1e, 0x5a, 0x4a, 0xde, 0x7f, 0xc9, 0xb8, 0xe5, 0xd3, 0x2d, 0x67, 0xaa, 0xf3, 0x2b,
+	0x5c, 0xdf, 0xf9, 0xa9, 0xf7, 0xe1, 0xee, 0x22, 0x0d, 0x59, 0xc4, 0xff, 0x29, 0xc1, 0xc3, 0x96,
+	0x6b, 0x51, 0xcb, 0xb4, 0xad, 0x1f, 0x71, 0x98, 0xf7, 0x7d, 0x1c, 0x5c, 0x58, 0x43, 0xfc, 0xa5,
+	0x0f, 0xe1, 0xc2, 0x7e, 0x24, 0xff, 0x5f, 0xf5, 0x23, 0x89, 0x33, 0x5d, 0xb8, 0xee, 0x4c, 0x3f,
+	0x84, 0xfb, 0x8b, 0xad, 0x64, 0x7e, 0xf8, 0x87, 0xc4, 0x72, 0xc7, 0xc5, 0x81, 0x49, 0xf1, 0x8d,
+	0x23, 0x98, 0xd0, 0x20, 0x77, 0x8d, 0x06, 0xe8, 0x5b, 0x90, 0x67, 0xaa, 0x6a, 0x64, 0x77, 0x32,
+	0xb1, 0x6a, 0xe9, 0xf2, 0x4a, 0xd0, 0x73, 0xa8, 0xa6, 0x0a, 0x77, 0x14, 0xf4, 0x24, 0xd3, 0x6a,
+	0xb2, 0x82, 0xf3, 0xee, 0x89, 0x50, 0xd6, 0x4e, 0xb0, 0x8a, 0xbd, 0xa2, 0x8b, 0x85, 0xea, 0xc0,
+	0xfa, 0xac, 0x7d, 0x5f, 0xa4, 0xac, 0x4d, 0xb7, 0xcb, 0x27, 0xb7, 0xfb, 0xbb, 0x04, 0x0f, 0xfa,
+	0x98, 0x66, 0x44, 0xf3, 0x26, 0x8e, 0x5d, 0x98, 0x29, 0xb9, 0xff, 0x35, 0x53, 0xae, 0xad, 0xfe,
+	0x0f, 0xe0, 0xde, 0x42, 0xbd, 0x59, 0xa2, 0xec, 0xc2, 0x06, 0xbf, 0xc2, 0x63, 0x82, 0x1b, 0x5c,
+	0xfb, 0x1b, 0xb0, 0x3e, 0xcb, 0xc3, 0x44, 0xfd, 0x94, 0x87, 0x95, 0x64, 0xa3, 0x86, 0xbe, 0x83,
+	0x52, 0x20, 0xa4, 0x45, 0xe1, 0x78, 0x38, 0xd7, 0xcd, 0xed, 0x84, 0xfb, 0x11, 0xcd, 0xa5, 0xc1,
+	0xa5, 0x1e, 0x33, 0xa0, 0x6f, 0x61, 0xc9, 0xb6, 0x1c, 0x8b, 0x46, 0x4e, 0xba, 0x3f, 0xcf, 0xda,
+	0xe6, 0x78, 0xc1, 0x18, 0x12, 0xa3, 0x37, 0xb0, 0xea, 0x7a, 0x23, 0x6c, 0x10, 0x1c, 0xf6, 0x70,
+	0x22, 0x29, 0xff, 0x6f, 0x9e, 0xbb, 0xe3, 0x8d, 0x70, 0x3f, 0xa4, 0x12, 0x32, 0x56, 0xdc, 0x04,
+	0x08, 0x3d, 0x87, 0x0a, 0xf5, 0x6c, 0x96, 0x61, 0x7c, 0x34, 0x12, 0x79, 0x1a, 0x8e, 0xb0, 0x31,
+	0x5c, 0x4f, 0xd2, 0xa0, 0x06, 0x94, 0xcc, 0xd3, 0x53, 0xcb, 0xb5, 0xe8, 0x25, 0xcf, 0xd6, 0xb2,
+	0x1e, 0xaf, 0x1b, 0xdf, 0xc1, 0x6a, 0xca, 0x54, 0xd6, 0xe5, 0x9f, 0xe3, 0xcb, 0x68, 0x70, 0x3f,
+	0xc7, 0x97, 0xd9, 0xd3, 0xfe, 0xaf, 0x72, 0xbf, 0x94, 0x1a, 0x2f, 0xa1, 0x92, 0x30, 0xf6, 0xb3,
+	0x58, 0x5f, 0xc1, 0xda, 0x9c, 0xa5, 0x9f, 0x23, 0x40, 0x3d, 0x03, 0x98, 0xda, 0x9b, 0xc1, 0xd9,
+	0x80, 0x92, 0xe7, 0x33, 0xb4, 0x17, 0xcd, 0xc8, 0xf1, 0x3a, 0xfb, 0x1d, 0x07, 0xdd, 0x86, 0x25,
+	0x7c, 0x7a, 0x8a, 0x87, 0x34, 0xbc, 0xc5, 0xc3, 0x95, 0xfa, 0x93, 0x04, 0x68, 0xbe, 0x5d, 0x47,
+	0xcf, 0xa0, 0xe0, 0x78, 0x23, 0x31, 0x95, 0x57, 0x77, 0xef, 0x2d, 0xe8, 0xea, 0x77, 0x8e, 0xbc,
+	0x11, 0xd6, 0x39, 0x25, 0xbb, 0x57, 0xcd, 0x60, 0x6c, 0x9c, 0x7a, 0x81, 0x63, 0xd2, 0x50, 0xa9,
+	0xb2, 0x19, 0x8c, 0x0f, 0x38, 0x80, 0xa1, 0xb1, 0x7b, 0x61, 0xf8, 0x01, 0x3e, 0xb5, 0x3e, 0x85,
+	0xaa, 0x95, 0xb1, 0x7b, 0xd1, 0xe3, 0x00, 0x76, 0xbb, 0x9e, 0x5a, 0x36, 0x36, 0xf8, 0x23, 0x8a,
+	0xd0, 0xb0, 0xc4, 0x00, 0x3d, 0xf6, 0x90, 0xf2, 0x1c, 0x0a, 0x6c, 0x23, 0x54, 0x82, 0x42, 0x53,
+	0x3f, 0xec, 0xcb, 0xb7, 0xd0, 0x32, 0xe4, 0xb5, 0xce, 0x3b, 0x59, 0x62, 0xa0, 0x83, 0x56, 0x5b,
+	0x93, 0x73, 0x68, 0x05, 0x4a, 0x03, 0xed, 0xa8, 0xd7, 0x6e, 0x0e, 0x34, 0x39, 0xaf, 0xfe, 0xab,
+	0x00, 0xf5, 0xac, 0xe9, 0x01, 0xed, 0x42, 0xe1, 0xdc, 0x72, 0x47, 0xa1, 0x61, 0x0f, 0x16, 0x8e,
+	0x19, 0x3b, 0x6f, 0x2d, 0x77, 0xa4, 0x73, 0x5a, 0xe6, 0xd1, 0x00, 0x8f, 0xf1, 0xa7, 0x28, 0x4e,
+	0x7c, 0x91, 0x56, 0x39, 0x9f, 0x56, 0x19, 0xbd, 0x82, 0x0a, 0x47, 0x86, 0xee, 0x28, 0xdc, 0x68,
+	0x37, 0x60, 0x2c, 0xa1, 0xbf, 0xee, 0x42, 0x99, 0x50, 0xec, 0x8b, 0x01, 0x38, 0xcc, 0x6b, 0x06,
+	0xe0, 0x63, 0x2f, 0x7b, 0x6d, 0xf2, 0x02, 0xca, 0xdf, 0x5f, 0x8a, 0x3a, 0xff, 0x8e, 0x5f, 0xa0,
+	0x96, 0xa7, 0x2f, 0x50, 0xc8, 0x00, 0xe4, 0x07, 0x9e, 0x83, 0xe9, 0x19, 0x9e, 0x10, 0x23, 0xea,
+	0x14, 0x4a, 0xfc, 0x54, 0x3d, 0x5b, 0xac, 0x4c, 0x2f, 0xe6, 0x09, 0xd1, 0xe2, 0xa8, 0xae, 0xf9,
+	0xb3, 0x70, 0xf6, 0x26, 0x44, 0x86, 0x81, 0xe9, 0x63, 0xc3, 0x72, 0x29, 0x0e, 0x2e, 0x4c, 0x9b,
+	0x3f, 0x23, 0x14, 0xf5, 0xaa, 0x00, 0xb7, 0x42, 0x28, 0xfa, 0x39, 0x2c, 0x13, 0x6b, 0x84, 0x87,
+	0x66, 0xa0, 0x00, 0x2f, 0x9f, 0xf7, 0x32, 0xb7, 0xef, 0x0b, 0x1a, 0x3d, 0x22, 0x6e, 0xec, 0xc3,
+	0xed, 0x6c, 0x6d, 0x3e, 0xeb, 0x38, 0xbd, 0x87, 0x02, 0x73, 0x30, 0x5a, 0x85, 0xf2, 0x5b, 0xed,
+	0x83, 0xf1, 0xae, 0xd9, 0x3e, 0xd6, 0xe4, 0x5b, 0xa8, 0x0c, 0x45, 0x5d, 0x3b, 0xd4, 0xde, 0xcb,
+	0x12, 0xaa, 0x02, 0xfc, 0xa6, 0xdf, 0xed, 0x18, 0xed, 0x56, 0x47, 0xeb, 0xcb, 0xb9, 0x38, 0xaf,
+	0xf2, 0x3c, 0xaf, 0x0e, 0x0c, 0xed, 0x9d, 0xd6, 0x19, 0xc8, 0x05, 0x46, 0xd7, 0xd3, 0xbb, 0x47,
+	0xda, 0xe0, 0x8d, 0x76, 0xdc, 0x97, 0x8b, 0xea, 0x7b, 0xd8, 0x5c, 0x60, 0xc3, 0xf4, 0x05, 0x42,
+	0x4a, 0xbe, 0x40, 0x3c, 0x86, 0x9a, 0x63, 0xba, 0xe6, 0x18, 0x07, 0x86, 0x39, 0x1a, 0x05, 0x98,
+	0x44, 0x9d, 0x5b, 0x35, 0x04, 0x37, 0x05, 0x74, 0xfb, 0x18, 0x56, 0x53, 0xcf, 0xb5, 0x48, 0x86,
+	0x95, 0xe3, 0xce, 0xdb, 0x4e, 0xf7, 0xfb, 0x8e, 0x31, 0xf8, 0xd0, 0x63, 0xfa, 0x03, 0x2c, 0xed,
+	0x77, 0x8f, 0x5f, 0xb7, 0x35, 0x59, 0x62, 0x27, 0xa2, 0xd5, 0x19, 0x88, 0x73, 0xb0, 0xdf, 0xea,
+	0xef, 0xe9, 0x1a, 0x3b, 0x07, 0xa8, 0x06, 0x95, 0xbd, 0xe6, 0x40, 0x3b, 0xec, 0xea, 0xad, 0xbd,
+	0x66, 0x5b, 0x2e, 0x6c, 0xbf, 0x01, 0x79, 0xf6, 0x99, 0x0c, 0x29, 0x50, 0x8f, 0x24, 0x77, 0x7b,
+	0x83, 0xd6, 0x51, 0xeb, 0xb7, 0xcd, 0x41, 0xab, 0xdb, 0x91, 0x6f, 0x31, 0x61, 0x47, 0xad, 0x0e,
+	0x83, 0xb0, 0x3d, 0xd8, 0xaa, 0xf9, 0x5e, 0xac, 0x72, 0xdb, 0x6d, 0x80, 0xe9, 0xb3, 0x22, 0xaa,
+	0xc0, 0x72, 0x4f, 0xeb, 0xec, 0xb7, 0x3a, 0x87, 0xf2, 0x2d, 0xb6, 0xd0, 0x8f, 0x3b, 0x1d, 0xb6,
+	0x90, 0x98, 0xd3, 0xf7, 0xba, 0x47, 0xbd, 0xb6, 0x36, 0xd0, 0xf6, 0xe5, 0x1c, 0x53, 0xfa, 0x6d,
+	0xab, 0xdd, 0xd6, 0xf6, 0xe5, 0x3c, 0x0b, 0x80, 0xa6, 0xeb, 0x5d, 0x5d, 0xfe, 0xb4, 0x7d, 0x08,
+	0xb5, 0x99, 0xb7, 0x0e, 0xb4, 0x0e, 0xb5, 0x66, 0xbb, 0xdd, 0xfd, 0xde, 0xd8, 0x3f, 0xee, 0xb5,
+	0x5b, 0xcc, 0x0c, 0xf9, 0x16, 0xf3, 0x82, 0xae, 0x1d, 0xf7, 0x35, 0x43, 0xd7, 0xfa, 0xc7, 0xed,
+	0x81, 0x90, 0xcf, 0xbe, 0x0f, 0x0f, 0xb5, 0xfe, 0x40, 0xce, 0xed, 0xfe, 0xa5, 0x08, 0xcb, 0x47,
+	0xc2, 0x95, 0xe8, 0xd7, 0x50, 0x49, 0x4c, 0xe2, 0x48, 0x3c, 0x4e, 0xcc, 0x8f, 0xf9, 0x8d, 0x8d,
+	0x79, 0x04, 0x6b, 0x6c, 0x7e, 0x01, 0xe5, 0x78, 0xd4, 0x46, 0x1b, 0xe1, 0x7d, 0x9f, 0x9e, 0xd4,
+	0x1b, 0xeb, 0xb3, 0xe0, 0x90, 0x31, 0x1e, 0x67, 0x43, 0xc6, 0xd9, 0x09, 0xbd, 0xb1, 0x3e, 0x0b,
+	0x66, 0x8c, 0x7b, 0xb0, 0x9a, 0x9a, 0x1b, 0xd1, 0x9d, 0x64, 0x9b, 0x92, 0x6a, 0x2a, 0x1b, 0x9b,
+	0x59, 0xa8, 0x50, 0x48, 0x6a, 0x2e, 0x0b, 0x85, 0x64, 0xcd, 0x8d, 0x8d, 0xcd, 0x2c, 0x14, 0x13,
+	0xd2, 0x82, 0xda, 0xcc, 0x88, 0x85, 0xee, 0x8a, 0x0d, 0x33, 0x87, 0xbb, 0xc6, 0x9d, 0x6c, 0x24,
+	0x13, 0x75, 0x00, 0xd5, 0xf4, 0x70, 0x84, 0x1a, 0x91, 0xed, 0xf3, 0x73, 0x56, 0x43, 0xc9, 0xc4,
+	0x31, 0x39, 0xbf, 0x83, 0xdb, 0xd9, 0x63, 0x08, 0x52, 0x39, 0xcf, 0x95, 0x53, 0x54, 0x63, 0xeb,
+	0x4a, 0x1a, 0x26, 0x7f, 0x04, 0xca, 0xa2, 0x06, 0x1f, 0x7d, 0xc5, 0xb9, 0xaf, 0x99, 0x72, 0x1a,
+	0xea, 0x35, 0x54, 0xbe, 0x7d, 0xb9, 0xfb, 0x6f, 0x09, 0x60, 0xda, 0xc5, 0x09, 0xe7, 0x24, 0x7b,
+	0xea, 0xd8, 0x39, 0x19, 0x83, 0x44, 0x43, 0xc9, 0xc4, 0x31, 0xe5, 0x4d, 0xd8, 0x5c, 0xd0, 0x73,
+	0x22, 0xd1, 0x87, 0x5d, 0xdd, 0x49, 0x37, 0x1e, 0x5d, 0x4d, 0x14, 0xc6, 0x31, 0xdd, 0x82, 0x86,
+	0xaa, 0x66, 0xf6, 0xb2, 0x0d, 0x25, 0x13, 0xc7, 0x3c, 0x50, 0x85, 0x95, 0xe6, 0x84, 0x7a, 0x0c,
+	0xe5, 0x5b, 0xee, 0xf8, 0x64, 0x89, 0xff, 0xbc, 0x7b, 0xf1, 0x9f, 0x01, 0x00, 0x14, 0x4f, 0x38,
+	0x68, 0xc9, 0x1b, 0x00, 0x00,
 }
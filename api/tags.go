@@ -0,0 +1,5 @@
+package api
+
+// RestoreTrialTag names the trial whose checkpoint a new trial is restarted from.
+// The suggestion services set it and the workers pass the checkpoint directory of that trial to the new one.
+const RestoreTrialTag = "RestoreTrialID"
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/benchmark"
+	"github.com/mlkube/katib/suggestion"
+)
+
+var algorithms = flag.String("a", "random,quasirandom", "comma separated algorithms to benchmark")
+var servers = flag.String("s", "", "comma separated algorithm=address of suggestion servers, e.g. hyperband=127.0.0.1:6789")
+var problems = flag.String("p", "branin,hartmann6,rosenbrock", "comma separated problems, a function with optional -noisy and -mf suffixes")
+var runs = flag.Int("n", 5, "runs per algorithm and problem")
+var workers = flag.Int("w", 4, "parallel workers")
+var budget = flag.Int("b", 100, "completed trials per run")
+var maxResource = flag.Int("R", 27, "max resource of the multi-fidelity problems")
+var duration = flag.Float64("d", 60, "mean duration of a trial at full fidelity in seconds")
+var seed = flag.Int64("seed", 1, "seed of the first run")
+var params = flag.String("param", "", "comma separated Name=Value suggestion parameters overriding the defaults")
+var format = flag.String("format", "csv", "output format, csv or json")
+var out = flag.String("o", "", "output file, stdout if empty")
+
+// inProcess are the suggestion services the benchmark runs without a server.
+var inProcess = map[string]func() pb.SuggestionServer{
+	"random":      func() pb.SuggestionServer { return suggestion.NewRandomSuggestService() },
+	"quasirandom": func() pb.SuggestionServer { return suggestion.NewQuasiRandomSuggestService() },
+}
+
+// remoteSuggestion calls a suggestion server through its client.
+type remoteSuggestion struct {
+	c pb.SuggestionClient
+}
+
+func (r *remoteSuggestion) GenerateTrials(ctx context.Context, in *pb.GenerateTrialsRequest) (*pb.GenerateTrialsReply, error) {
+	return r.c.GenerateTrials(ctx, in)
+}
+
+func (r *remoteSuggestion) SetSuggestionParameters(ctx context.Context, in *pb.SetSuggestionParametersRequest) (*pb.SetSuggestionParametersReply, error) {
+	return r.c.SetSuggestionParameters(ctx, in)
+}
+
+func (r *remoteSuggestion) StopSuggestion(ctx context.Context, in *pb.StopSuggestionRequest) (*pb.StopSuggestionReply, error) {
+	return r.c.StopSuggestion(ctx, in)
+}
+
+func splitList(s string) []string {
+	var ret []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func splitPairs(s string) map[string]string {
+	ret := make(map[string]string)
+	for _, v := range splitList(s) {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			log.Fatalf("Invalid Name=Value %v", v)
+		}
+		ret[kv[0]] = kv[1]
+	}
+	return ret
+}
+
+func suggestionParameters(algorithm string, p *benchmark.Problem, o benchmark.Options, overrides map[string]string) []*pb.SuggestionParameter {
+	ps := benchmark.DefaultSuggestionParameters(algorithm, p, o)
+	seen := make(map[string]bool)
+	for _, sp := range ps {
+		if v, ok := overrides[sp.Name]; ok {
+			sp.Value = v
+		}
+		seen[sp.Name] = true
+	}
+	for k, v := range overrides {
+		if !seen[k] {
+			ps = append(ps, &pb.SuggestionParameter{Name: k, Value: v})
+		}
+	}
+	return ps
+}
+
+func main() {
+	flag.Parse()
+	services := make(map[string]pb.SuggestionServer)
+	algs := splitList(*algorithms)
+	for alg, addr := range splitPairs(*servers) {
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			log.Fatalf("Connect to %v failed: %v", addr, err)
+		}
+		defer conn.Close()
+		services[alg] = &remoteSuggestion{c: pb.NewSuggestionClient(conn)}
+		found := false
+		for _, a := range algs {
+			found = found || a == alg
+		}
+		if !found {
+			algs = append(algs, alg)
+		}
+	}
+	for _, alg := range algs {
+		if _, ok := services[alg]; ok {
+			continue
+		}
+		f, ok := inProcess[alg]
+		if !ok {
+			log.Fatalf("No suggestion server for %v. Give its address with -s", alg)
+		}
+		services[alg] = f()
+	}
+	overrides := splitPairs(*params)
+
+	var results []*benchmark.Result
+	for _, name := range splitList(*problems) {
+		p, err := benchmark.NewProblem(name, *maxResource)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for _, alg := range algs {
+			for i := 0; i < *runs; i++ {
+				o := benchmark.Options{Workers: *workers, Budget: *budget, Duration: *duration, Seed: *seed + int64(i)}
+				o.SuggestionParameters = suggestionParameters(alg, p, o, overrides)
+				studyId := fmt.Sprintf("benchmark-%s-%s-%d", alg, p.Name(), i)
+				points, err := benchmark.Run(context.Background(), services[alg], alg, studyId, p, o)
+				if err != nil {
+					log.Printf("%v on %v run %v failed: %v", alg, p.Name(), i, err)
+				}
+				if len(points) > 0 {
+					last := points[len(points)-1]
+					log.Printf("%v on %v run %v: %v trials, regret %v", alg, p.Name(), i, last.Trials, last.Regret)
+				}
+				results = append(results, &benchmark.Result{Algorithm: alg, Problem: p.Name(), Run: i, Points: points})
+			}
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("Create %v failed: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	var err error
+	switch *format {
+	case "csv":
+		err = benchmark.WriteCSV(w, results)
+	case "json":
+		err = benchmark.WriteJSON(w, results)
+	default:
+		log.Fatalf("Unknown format %v", *format)
+	}
+	if err != nil {
+		log.Fatalf("Write results failed: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// ResourceName is the parameter that receives the resource of a trial in the multi-fidelity problems.
+const ResourceName = "resource"
+
+// Function is a synthetic objective to minimize.
+type Function struct {
+	Name    string
+	Bounds  [][2]float64
+	F       func(x []float64) float64
+	Optimum float64
+	// Scale is the typical magnitude of F around the optimum. Noise and fidelity bias are relative to it.
+	Scale float64
+}
+
+var Branin = &Function{
+	Name:   "branin",
+	Bounds: [][2]float64{{-5, 10}, {0, 15}},
+	F: func(x []float64) float64 {
+		b := 5.1 / (4 * math.Pi * math.Pi)
+		c := 5 / math.Pi
+		t := 1 / (8 * math.Pi)
+		return math.Pow(x[1]-b*x[0]*x[0]+c*x[0]-6, 2) + 10*(1-t)*math.Cos(x[0]) + 10
+	},
+	Optimum: 0.397887,
+	Scale:   10,
+}
+
+var hartmannA = [4][6]float64{
+	{10, 3, 17, 3.5, 1.7, 8},
+	{0.05, 10, 17, 0.1, 8, 14},
+	{3, 3.5, 1.7, 10, 17, 8},
+	{17, 8, 0.05, 10, 0.1, 14},
+}
+
+var hartmannP = [4][6]float64{
+	{0.1312, 0.1696, 0.5569, 0.0124, 0.8283, 0.5886},
+	{0.2329, 0.4135, 0.8307, 0.3736, 0.1004, 0.9991},
+	{0.2348, 0.1451, 0.3522, 0.2883, 0.3047, 0.6650},
+	{0.4047, 0.8828, 0.8732, 0.5743, 0.1091, 0.0381},
+}
+
+var Hartmann6 = &Function{
+	Name:   "hartmann6",
+	Bounds: [][2]float64{{0, 1}, {0, 1}, {0, 1}, {0, 1}, {0, 1}, {0, 1}},
+	F: func(x []float64) float64 {
+		alpha := []float64{1, 1.2, 3, 3.2}
+		var f float64
+		for i := range alpha {
+			var s float64
+			for j := range x {
+				s += hartmannA[i][j] * math.Pow(x[j]-hartmannP[i][j], 2)
+			}
+			f -= alpha[i] * math.Exp(-s)
+		}
+		return f
+	},
+	Optimum: -3.32237,
+	Scale:   1,
+}
+
+var Rosenbrock = &Function{
+	Name:   "rosenbrock",
+	Bounds: [][2]float64{{-2, 2}, {-2, 2}, {-2, 2}, {-2, 2}},
+	F: func(x []float64) float64 {
+		var f float64
+		for i := 0; i+1 < len(x); i++ {
+			f += 100*math.Pow(x[i+1]-x[i]*x[i], 2) + math.Pow(1-x[i], 2)
+		}
+		return f
+	},
+	Optimum: 0,
+	Scale:   100,
+}
+
+var Functions = []*Function{Branin, Hartmann6, Rosenbrock}
+
+// Problem is a Function, optionally observed with gaussian noise or at a lower fidelity.
+type Problem struct {
+	*Function
+	// Noise is the standard deviation of the observation noise relative to Scale.
+	Noise float64
+	// R is the max resource of the multi-fidelity variant, 0 for the plain function.
+	// A trial with resource r observes F plus a bias that shrinks to 0 at r = R.
+	R int
+}
+
+func (p *Problem) Name() string {
+	n := p.Function.Name
+	if p.Noise > 0 {
+		n += "-noisy"
+	}
+	if p.R > 0 {
+		n += "-mf"
+	}
+	return n
+}
+
+// NewProblem returns the problem of the given name, e.g. branin, hartmann6-noisy or rosenbrock-mf.
+// r is the max resource of the multi-fidelity variants.
+func NewProblem(name string, r int) (*Problem, error) {
+	parts := strings.Split(name, "-")
+	p := &Problem{}
+	for _, f := range Functions {
+		if f.Name == parts[0] {
+			p.Function = f
+		}
+	}
+	if p.Function == nil {
+		return nil, fmt.Errorf("Unknown problem %v", name)
+	}
+	for _, v := range parts[1:] {
+		switch v {
+		case "noisy":
+			p.Noise = 0.1
+		case "mf":
+			p.R = r
+		default:
+			return nil, fmt.Errorf("Unknown problem variant %v", v)
+		}
+	}
+	return p, nil
+}
+
+func (p *Problem) ParameterConfigs() []*api.ParameterConfig {
+	var pcs []*api.ParameterConfig
+	for i, b := range p.Bounds {
+		pcs = append(pcs, &api.ParameterConfig{
+			Name:          fmt.Sprintf("x%d", i),
+			ParameterType: api.ParameterType_DOUBLE,
+			Feasible:      &api.FeasibleSpace{Min: strconv.FormatFloat(b[0], 'f', -1, 64), Max: strconv.FormatFloat(b[1], 'f', -1, 64)},
+		})
+	}
+	if p.R > 0 {
+		pcs = append(pcs, &api.ParameterConfig{
+			Name:          ResourceName,
+			ParameterType: api.ParameterType_INT,
+			Feasible:      &api.FeasibleSpace{Min: "1", Max: strconv.Itoa(p.R)},
+		})
+	}
+	return pcs
+}
+
+// Fidelity returns the fidelity in (0, 1] at which a parameter set is evaluated.
+func (p *Problem) Fidelity(ps []*api.Parameter) float64 {
+	if p.R == 0 {
+		return 1
+	}
+	for _, v := range ps {
+		if v.Name == ResourceName {
+			r, _ := strconv.ParseFloat(v.Value, 64)
+			return math.Min(1, math.Max(r, 1)/float64(p.R))
+		}
+	}
+	return 1
+}
+
+// Evaluate returns the observed value of a parameter set and its noise free value at full fidelity.
+func (p *Problem) Evaluate(ps []*api.Parameter, rng *rand.Rand) (float64, float64) {
+	x := make([]float64, len(p.Bounds))
+	for _, v := range ps {
+		var i int
+		if _, err := fmt.Sscanf(v.Name, "x%d", &i); err == nil && i >= 0 && i < len(x) {
+			x[i], _ = strconv.ParseFloat(v.Value, 64)
+		}
+	}
+	truth := p.F(x)
+	value := truth
+	if p.R > 0 {
+		// the bias depends on x, so that low fidelities do not rank the configurations exactly as F
+		var s float64
+		for _, xi := range x {
+			s += xi
+		}
+		value += p.Scale * (1 - p.Fidelity(ps)) * (0.5 + 0.5*math.Sin(3*s))
+	}
+	if p.Noise > 0 {
+		value += rng.NormFloat64() * p.Noise * p.Scale
+	}
+	return value, truth
+}
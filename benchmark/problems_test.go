@@ -0,0 +1,105 @@
+package benchmark
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func parameters(x []float64, r int) []*api.Parameter {
+	var ps []*api.Parameter
+	for i, v := range x {
+		ps = append(ps, &api.Parameter{Name: "x" + strconv.Itoa(i), Value: strconv.FormatFloat(v, 'f', -1, 64)})
+	}
+	if r > 0 {
+		ps = append(ps, &api.Parameter{Name: ResourceName, Value: strconv.Itoa(r)})
+	}
+	return ps
+}
+
+func TestOptimum(t *testing.T) {
+	for _, c := range []struct {
+		f          *Function
+		minimizers [][]float64
+	}{
+		{f: Branin, minimizers: [][]float64{{-math.Pi, 12.275}, {math.Pi, 2.275}, {9.42478, 2.475}}},
+		{f: Hartmann6, minimizers: [][]float64{{0.20169, 0.150011, 0.476874, 0.275332, 0.311652, 0.6573}}},
+		{f: Rosenbrock, minimizers: [][]float64{{1, 1, 1, 1}}},
+	} {
+		for _, x := range c.minimizers {
+			for i, b := range c.f.Bounds {
+				if x[i] < b[0] || x[i] > b[1] {
+					t.Errorf("%v: minimizer %v is out of the bounds %v", c.f.Name, x, c.f.Bounds)
+				}
+			}
+			if v := c.f.F(x); math.Abs(v-c.f.Optimum) > 1e-5 {
+				t.Errorf("%v: expected %v at %v, got %v", c.f.Name, c.f.Optimum, x, v)
+			}
+			// the optimum is a minimum
+			for i := range x {
+				for _, d := range []float64{-0.01, 0.01} {
+					y := append([]float64{}, x...)
+					y[i] += d
+					if v := c.f.F(y); v < c.f.Optimum {
+						t.Errorf("%v: %v at %v is below the optimum", c.f.Name, v, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestProblem(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		params int
+		err    bool
+	}{
+		{name: "branin", params: 2},
+		{name: "hartmann6-noisy", params: 6},
+		{name: "rosenbrock-mf", params: 5},
+		{name: "branin-noisy-mf", params: 3},
+		{name: "sphere", err: true},
+		{name: "branin-fast", err: true},
+	} {
+		p, err := NewProblem(c.name, 9)
+		if c.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", c.name, err)
+			continue
+		}
+		if p.Name() != c.name || len(p.ParameterConfigs()) != c.params {
+			t.Errorf("%v: expected %v parameters, got %v %v", c.name, c.params, p.Name(), p.ParameterConfigs())
+		}
+	}
+
+	// the low fidelities are biased, the full fidelity observes F
+	p, _ := NewProblem("branin-mf", 9)
+	x := []float64{math.Pi, 2.275}
+	for _, c := range []struct {
+		r        int
+		fidelity float64
+		biased   bool
+	}{
+		{r: 1, fidelity: 1.0 / 9, biased: true},
+		{r: 3, fidelity: 1.0 / 3, biased: true},
+		{r: 9, fidelity: 1},
+		{r: 0, fidelity: 1},
+	} {
+		ps := parameters(x, c.r)
+		if f := p.Fidelity(ps); math.Abs(f-c.fidelity) > 1e-9 {
+			t.Errorf("Resource %v: expected the fidelity %v, got %v", c.r, c.fidelity, f)
+		}
+		value, truth := p.Evaluate(ps, nil)
+		if math.Abs(truth-Branin.Optimum) > 1e-5 || (value != truth) == !c.biased {
+			t.Errorf("Resource %v: unexpected value %v and truth %v", c.r, value, truth)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Result is the outcome of one run of an algorithm on a problem.
+type Result struct {
+	Algorithm string
+	Problem   string
+	Run       int
+	Points    []Point
+}
+
+// WriteCSV writes one row per completed trial of every result.
+func WriteCSV(w io.Writer, results []*Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"algorithm", "problem", "run", "trials", "time", "best", "regret"})
+	for _, r := range results {
+		for _, p := range r.Points {
+			cw.Write([]string{
+				r.Algorithm,
+				r.Problem,
+				strconv.Itoa(r.Run),
+				strconv.Itoa(p.Trials),
+				strconv.FormatFloat(p.Time, 'f', -1, 64),
+				strconv.FormatFloat(p.Best, 'g', -1, 64),
+				strconv.FormatFloat(p.Regret, 'g', -1, 64),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Summary aggregates the runs of an algorithm on a problem.
+type Summary struct {
+	Algorithm string `json:"algorithm"`
+	Problem   string `json:"problem"`
+	Runs      int    `json:"runs"`
+	// Regret is the mean regret over the runs after each number of completed trials.
+	// A run that stopped earlier counts with its final regret.
+	Regret      []float64 `json:"regret"`
+	FinalRegret float64   `json:"final_regret"`
+	FinalStd    float64   `json:"final_regret_std"`
+	// Time is the mean simulated time the runs took.
+	Time float64 `json:"time"`
+}
+
+// Summarize returns a Summary per algorithm and problem, in the order they appear in results.
+// Runs without any completed trial are ignored.
+func Summarize(results []*Result) []*Summary {
+	var ret []*Summary
+	groups := make(map[[2]string][]*Result)
+	for _, r := range results {
+		if len(r.Points) == 0 {
+			continue
+		}
+		k := [2]string{r.Algorithm, r.Problem}
+		if _, ok := groups[k]; !ok {
+			ret = append(ret, &Summary{Algorithm: r.Algorithm, Problem: r.Problem})
+		}
+		groups[k] = append(groups[k], r)
+	}
+	for _, s := range ret {
+		rs := groups[[2]string{s.Algorithm, s.Problem}]
+		s.Runs = len(rs)
+		n := 0
+		for _, r := range rs {
+			if len(r.Points) > n {
+				n = len(r.Points)
+			}
+		}
+		s.Regret = make([]float64, n)
+		for _, r := range rs {
+			for i := range s.Regret {
+				p := r.Points[len(r.Points)-1]
+				if i < len(r.Points) {
+					p = r.Points[i]
+				}
+				s.Regret[i] += p.Regret / float64(len(rs))
+			}
+			last := r.Points[len(r.Points)-1]
+			s.FinalRegret += last.Regret / float64(len(rs))
+			s.Time += last.Time / float64(len(rs))
+		}
+		for _, r := range rs {
+			d := r.Points[len(r.Points)-1].Regret - s.FinalRegret
+			s.FinalStd += d * d / float64(len(rs))
+		}
+		s.FinalStd = math.Sqrt(s.FinalStd)
+	}
+	return ret
+}
+
+// WriteJSON writes the Summary of the results.
+func WriteJSON(w io.Writer, results []*Result) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(Summarize(results))
+}
@@ -0,0 +1,160 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Options configures a simulated study.
+type Options struct {
+	// Workers is the number of trials that run at the same time.
+	Workers int
+	// Budget is the number of completed trials after which the study stops.
+	Budget int
+	// Duration is the mean simulated time of a trial at full fidelity, in seconds.
+	// The time of a trial is proportional to its fidelity and jittered, so that trials complete out of order.
+	Duration float64
+	// Seed makes the noise, the trial durations and the Seed suggestion parameter reproducible.
+	Seed int64
+	// SuggestionParameters are given to the suggestion service as they are.
+	SuggestionParameters []*api.SuggestionParameter
+}
+
+// Point is the state of a study after a trial completed.
+type Point struct {
+	Trials int     `json:"trials"`
+	Time   float64 `json:"time"`
+	// Best is the best observed value.
+	Best float64 `json:"best"`
+	// Regret is the noise free full fidelity value of the trial with the best observed value, minus the optimum.
+	Regret float64 `json:"regret"`
+}
+
+type job struct {
+	trial *api.Trial
+	end   float64
+}
+
+// DefaultSuggestionParameters returns the suggestion parameters the benchmark gives to algorithm unless they are overridden.
+func DefaultSuggestionParameters(algorithm string, p *Problem, o Options) []*api.SuggestionParameter {
+	ps := map[string]string{"MaxParallel": strconv.Itoa(o.Workers)}
+	switch algorithm {
+	case "random", "quasirandom":
+		ps["SuggestionNum"] = strconv.Itoa(o.Budget)
+		ps["Seed"] = strconv.FormatInt(o.Seed, 10)
+	case "grid":
+		ps["DefaultGrid"] = strconv.Itoa(int(math.Max(2, math.Floor(math.Pow(float64(o.Budget), 1/float64(len(p.Bounds)))))))
+	case "hyperband", "bohb":
+		ps["Eta"] = "3"
+		ps["R"] = strconv.Itoa(maxResource(p))
+		ps["ResourceName"] = ResourceName
+		ps["Seed"] = strconv.FormatInt(o.Seed, 10)
+	case "asha":
+		ps["Eta"] = "3"
+		ps["R"] = strconv.Itoa(maxResource(p))
+		ps["r"] = "1"
+		ps["ResourceName"] = ResourceName
+		ps["SuggestionNum"] = strconv.Itoa(o.Budget)
+	default:
+		ps["SuggestionNum"] = strconv.Itoa(o.Budget)
+	}
+	var ret []*api.SuggestionParameter
+	for k, v := range ps {
+		ret = append(ret, &api.SuggestionParameter{Name: k, Value: v})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func maxResource(p *Problem) int {
+	if p.R > 0 {
+		return p.R
+	}
+	return 1
+}
+
+// Run simulates a study of p driven by s and returns a Point for every completed trial.
+// It asks s for trials the way the manager does, after every completion, and stops when
+// the budget is used, when s reports the study as completed, or when s suggests nothing while no trial runs.
+func Run(ctx context.Context, s api.SuggestionServer, algorithm string, studyId string, p *Problem, o Options) ([]Point, error) {
+	conf := &api.StudyConfig{
+		Name:                 studyId,
+		OptimizationType:     api.OptimizationType_MINIMIZE,
+		ObjectiveValueName:   "objective",
+		ParameterConfigs:     &api.StudyConfig_ParameterConfigs{Configs: p.ParameterConfigs()},
+		SuggestAlgorithm:     algorithm,
+		SuggestionParameters: o.SuggestionParameters,
+	}
+	_, err := s.SetSuggestionParameters(ctx, &api.SetSuggestionParametersRequest{StudyId: studyId, SuggestionParameters: o.SuggestionParameters, Configs: conf})
+	if err != nil {
+		return nil, err
+	}
+	defer s.StopSuggestion(ctx, &api.StopSuggestionRequest{StudyId: studyId})
+
+	rng := rand.New(rand.NewSource(o.Seed))
+	var state []byte
+	var completed []*api.Trial
+	var running []*job
+	var queued []*api.Trial
+	var points []Point
+	clock := 0.0
+	best := math.Inf(1)
+	regret := math.Inf(1)
+	n := 0
+	for len(completed) < o.Budget {
+		rts := append([]*api.Trial{}, queued...)
+		for _, j := range running {
+			rts = append(rts, j.trial)
+		}
+		r, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: studyId, Configs: conf, CompletedTrials: completed, RunningTrials: rts, State: state})
+		if err != nil {
+			return points, err
+		}
+		if len(r.State) > 0 {
+			state = r.State
+		}
+		if r.Completed {
+			break
+		}
+		for _, t := range r.Trials {
+			n++
+			if t.TrialId == "" {
+				t.TrialId = fmt.Sprintf("%s-%d", studyId, n)
+			}
+			t.StudyId = studyId
+			t.Status = api.TrialState_PENDING
+			queued = append(queued, t)
+		}
+		for len(running) < o.Workers && len(queued) > 0 {
+			t := queued[0]
+			queued = queued[1:]
+			t.Status = api.TrialState_RUNNING
+			d := o.Duration * p.Fidelity(t.ParameterSet) * math.Exp(0.3*rng.NormFloat64())
+			running = append(running, &job{trial: t, end: clock + d})
+		}
+		if len(running) == 0 {
+			break
+		}
+		sort.SliceStable(running, func(i, j int) bool { return running[i].end < running[j].end })
+		j := running[0]
+		running = running[1:]
+		clock = j.end
+		value, truth := p.Evaluate(j.trial.ParameterSet, rng)
+		j.trial.Status = api.TrialState_COMPLETED
+		j.trial.ObjectiveValue = strconv.FormatFloat(value, 'f', -1, 64)
+		j.trial.EvalLogs = []*api.EvaluationLog{{Time: time.Unix(0, 0).Add(time.Duration(clock * float64(time.Second))).UTC().Format(time.RFC3339), Metrics: []*api.Metrics{{Name: conf.ObjectiveValueName, Value: j.trial.ObjectiveValue}}}}
+		completed = append(completed, j.trial)
+		if value < best {
+			best = value
+			regret = truth - p.Optimum
+		}
+		points = append(points, Point{Trials: len(completed), Time: clock, Best: best, Regret: regret})
+	}
+	return points, nil
+}
@@ -0,0 +1,49 @@
+package benchmark
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mlkube/katib/suggestion"
+)
+
+func run(t *testing.T, p *Problem, seed int64) []Point {
+	o := Options{Workers: 3, Budget: 40, Duration: 1, Seed: seed}
+	o.SuggestionParameters = DefaultSuggestionParameters("random", p, o)
+	points, err := Run(context.Background(), suggestion.NewRandomSuggestService(), "random", "study", p, o)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return points
+}
+
+func TestRun(t *testing.T) {
+	p, _ := NewProblem("branin", 0)
+	points := run(t, p, 1)
+	if len(points) != 40 {
+		t.Fatalf("Expected a point per trial of the budget, got %v", len(points))
+	}
+	for i, pt := range points {
+		if pt.Trials != i+1 || pt.Regret < 0 {
+			t.Errorf("Unexpected point %v: %+v", i, pt)
+		}
+		if i == 0 {
+			continue
+		}
+		// without noise the best observed value is the true value, so the regret never increases
+		prev := points[i-1]
+		if pt.Time < prev.Time || pt.Best > prev.Best || pt.Regret > prev.Regret {
+			t.Errorf("Point %v %+v is worse or earlier than point %v %+v", i, pt, i-1, prev)
+		}
+	}
+	if points[39].Regret >= points[0].Regret {
+		t.Errorf("Expected the regret to decrease in 40 trials, got %v", points[39].Regret)
+	}
+	if !reflect.DeepEqual(points, run(t, p, 1)) {
+		t.Errorf("Expected the same points with the same seed")
+	}
+	if reflect.DeepEqual(points, run(t, p, 2)) {
+		t.Errorf("Expected other points with another seed")
+	}
+}
@@ -3,9 +3,15 @@ set -x
 set -e
 PREFIX="katib/"
 docker build -t ${PREFIX}vizier-core -f manager/Dockerfile .
+docker build -t ${PREFIX}metrics-collector -f manager/metricscollector/sidecar/Dockerfile .
 docker build -t ${PREFIX}suggestion-random -f suggestion/random/Dockerfile .
 docker build -t ${PREFIX}suggestion-grid -f suggestion/grid/Dockerfile .
 docker build -t ${PREFIX}suggestion-hyperband -f suggestion/hyperband/Dockerfile .
+docker build -t ${PREFIX}suggestion-cmaes -f suggestion/cmaes/Dockerfile .
+docker build -t ${PREFIX}suggestion-quasirandom -f suggestion/quasirandom/Dockerfile .
+docker build -t ${PREFIX}suggestion-asha -f suggestion/asha/Dockerfile .
+docker build -t ${PREFIX}suggestion-pbt -f suggestion/pbt/Dockerfile .
+docker build -t ${PREFIX}suggestion-genetic -f suggestion/genetic/Dockerfile .
 docker build -t ${PREFIX}dlk-manager -f vendor/github.com/osrg/dlk/build/Dockerfile vendor/github.com/osrg/dlk
 docker build -t ${PREFIX}katib-frontend -f manager/modeldb/Dockerfile .
 docker build -t ${PREFIX}katib-cli -f cli/Dockerfile .
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"log"
 )
 
@@ -25,10 +26,17 @@ func (d *db_conn) DB_Init() {
 		"gpu INT, " +
 		"scheduler VARCHAR(255), " +
 		"mount TEXT, " +
-		"pull_secret TEXT)")
+		"pull_secret TEXT, " +
+		"duplicate_policy TINYINT, " +
+		"job_template TEXT, " +
+		"namespace VARCHAR(255), " +
+		"resources TEXT, " +
+		"parameter_injection TEXT, " +
+		"metrics_collector TEXT)")
 	if err != nil {
 		log.Fatalf("Error creating studies table: %v", err)
 	}
+	d.addMissingColumns("studies", studiesAddedColumns)
 
 	_, err = db.Exec("CREATE TABLE IF NOT EXISTS study_permissions" +
 		"(study_id CHAR(16) NOT NULL, " +
@@ -68,4 +76,43 @@ func (d *db_conn) DB_Init() {
 	if err != nil {
 		log.Fatalf("Error creating workers table: %v", err)
 	}
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS suggestion_states" +
+		"(study_id CHAR(16) PRIMARY KEY, " +
+		"state MEDIUMBLOB, " +
+		"FOREIGN KEY(study_id) REFERENCES studies(id))")
+	if err != nil {
+		log.Fatalf("Error creating suggestion_states table: %v", err)
+	}
+}
+
+// studiesAddedColumns are the columns added to the studies table since its first version, in the order of the table.
+var studiesAddedColumns = [][2]string{
+	{"duplicate_policy", "TINYINT"},
+	{"job_template", "TEXT"},
+	{"namespace", "VARCHAR(255)"},
+	{"resources", "TEXT"},
+	{"parameter_injection", "TEXT"},
+	{"metrics_collector", "TEXT"},
+}
+
+// addMissingColumns adds the columns missing in a table created by an older version.
+// They are added in order after the existing ones, so that SELECT * keeps the order of the table.
+func (d *db_conn) addMissingColumns(table string, columns [][2]string) {
+	for _, c := range columns {
+		var n int
+		err := d.db.QueryRow("SELECT COUNT(*) FROM information_schema.columns "+
+			"WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", table, c[0]).Scan(&n)
+		if err != nil {
+			log.Fatalf("Error checking column %v of %v table: %v", c[0], table, err)
+		}
+		if n > 0 {
+			continue
+		}
+		_, err = d.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c[0], c[1]))
+		if err != nil {
+			log.Fatalf("Error adding column %v to %v table: %v", c[0], table, err)
+		}
+		log.Printf("Added column %v to %v table", c[0], table)
+	}
 }
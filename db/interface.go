@@ -43,10 +43,14 @@ type VizierDBInterface interface {
 	GetTrialList(string) ([]*api.Trial, error)
 	CreateTrial(*api.Trial) error
 	UpdateTrial(string, api.TrialState) error
+	UpdateTrialObjectiveValue(string, string) error
 	GetTrialLogs(string, *GetTrialLogOpts) ([]*TrialLog, error)
 	GetTrialTimestamp(string) (*time.Time, error)
 	StoreTrialLogs(string, []string) error
 	DeleteTrial(string) error
+
+	GetSuggestionState(string) ([]byte, error)
+	SetSuggestionState(string, []byte) error
 }
 
 type db_conn struct {
@@ -93,6 +97,9 @@ func (d *db_conn) GetStudyConfig(id string) (*api.StudyConfig, error) {
 
 	study := new(api.StudyConfig)
 	var dummy_id, configs, suggestion_parameters, tags, metrics, command, mconf string
+	// the columns added by addMissingColumns are NULL in the studies created before them
+	var dpolicy sql.NullInt64
+	var jtemplate, namespace, rconf, pinj, mcconf sql.NullString
 	err := row.Scan(&dummy_id,
 		&study.Name,
 		&study.Owner,
@@ -112,10 +119,19 @@ func (d *db_conn) GetStudyConfig(id string) (*api.StudyConfig, error) {
 		&study.Scheduler,
 		&mconf,
 		&study.PullSecret,
+		&dpolicy,
+		&jtemplate,
+		&namespace,
+		&rconf,
+		&pinj,
+		&mcconf,
 	)
 	if err != nil {
 		return nil, err
 	}
+	study.DuplicatePolicy = api.DuplicatePolicy(dpolicy.Int64)
+	study.JobTemplate = jtemplate.String
+	study.Namespace = namespace.String
 	study.ParameterConfigs = new(api.StudyConfig_ParameterConfigs)
 	err = jsonpb.UnmarshalString(configs, study.ParameterConfigs)
 	if err != nil {
@@ -160,6 +176,30 @@ func (d *db_conn) GetStudyConfig(id string) (*api.StudyConfig, error) {
 		}
 	}
 
+	if rconf.String != "" {
+		study.Resources = new(api.ResourceConf)
+		err = jsonpb.UnmarshalString(rconf.String, study.Resources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if pinj.String != "" {
+		study.ParameterInjection = new(api.ParameterInjection)
+		err = jsonpb.UnmarshalString(pinj.String, study.ParameterInjection)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if mcconf.String != "" {
+		study.MetricsCollector = new(api.MetricsCollectorConf)
+		err = jsonpb.UnmarshalString(mcconf.String, study.MetricsCollector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	study.Metrics = strings.Split(metrics, ",\n")
 	study.Command = strings.Split(command, ",\n")
 	return study, nil
@@ -206,6 +246,30 @@ func (d *db_conn) CreateStudy(in *api.StudyConfig) (string, error) {
 		}
 	}
 
+	var rconf string = ""
+	if in.Resources != nil {
+		rconf, err = (&jsonpb.Marshaler{}).MarshalToString(in.Resources)
+		if err != nil {
+			log.Fatalf("Error marshaling resource configs: %v", err)
+		}
+	}
+
+	var pinj string = ""
+	if in.ParameterInjection != nil {
+		pinj, err = (&jsonpb.Marshaler{}).MarshalToString(in.ParameterInjection)
+		if err != nil {
+			log.Fatalf("Error marshaling parameter injection: %v", err)
+		}
+	}
+
+	var mcconf string = ""
+	if in.MetricsCollector != nil {
+		mcconf, err = (&jsonpb.Marshaler{}).MarshalToString(in.MetricsCollector)
+		if err != nil {
+			log.Fatalf("Error marshaling metrics collector configs: %v", err)
+		}
+	}
+
 	tags := make([]string, len(in.Tags))
 	for i, elem := range in.Tags {
 		tags[i], err = (&jsonpb.Marshaler{}).MarshalToString(elem)
@@ -220,7 +284,7 @@ func (d *db_conn) CreateStudy(in *api.StudyConfig) (string, error) {
 	for true {
 		study_id = generate_randid()
 		_, err := d.db.Exec(
-			"INSERT INTO studies VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			"INSERT INTO studies VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 			study_id,
 			in.Name,
 			in.Owner,
@@ -240,6 +304,12 @@ func (d *db_conn) CreateStudy(in *api.StudyConfig) (string, error) {
 			in.Scheduler,
 			mconf,
 			in.PullSecret,
+			in.DuplicatePolicy,
+			in.JobTemplate,
+			in.Namespace,
+			rconf,
+			pinj,
+			mcconf,
 		)
 		if err == nil {
 			break
@@ -269,7 +339,11 @@ func (d *db_conn) CreateStudy(in *api.StudyConfig) (string, error) {
 }
 
 func (d *db_conn) DeleteStudy(id string) error {
-	_, err := d.db.Exec("DELETE FROM studies WHERE id = ?", id)
+	_, err := d.db.Exec("DELETE FROM suggestion_states WHERE study_id = ?", id)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec("DELETE FROM studies WHERE id = ?", id)
 	return err
 }
 
@@ -396,6 +470,11 @@ func (d *db_conn) UpdateTrial(id string, newstatus api.TrialState) error {
 	return err
 }
 
+func (d *db_conn) UpdateTrialObjectiveValue(id string, value string) error {
+	_, err := d.db.Exec("UPDATE trials SET objective_value = ? WHERE id = ?", value, id)
+	return err
+}
+
 func (d *db_conn) GetTrialLogs(id string, opts *GetTrialLogOpts) ([]*TrialLog, error) {
 	// TODO: opts not implemented
 	rows, err := d.db.Query("SELECT (time, value) FROM trial_logs WHERE trial_id = ? ORDER BY time", id)
@@ -472,3 +551,20 @@ func (d *db_conn) DeleteTrial(id string) error {
 	_, err := d.db.Exec("DELETE FROM trials WHERE id = ?", id)
 	return err
 }
+
+func (d *db_conn) GetSuggestionState(study_id string) ([]byte, error) {
+	var state []byte
+	row := d.db.QueryRow("SELECT state FROM suggestion_states WHERE study_id = ?", study_id)
+	err := row.Scan(&state)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return state, err
+}
+
+func (d *db_conn) SetSuggestionState(study_id string, state []byte) error {
+	_, err := d.db.Exec("INSERT INTO suggestion_states VALUES (?, ?) "+
+		"ON DUPLICATE KEY UPDATE state = VALUES(state)",
+		study_id, state)
+	return err
+}
@@ -52,6 +52,32 @@ func TestGetStudyConfig(t *testing.T) {
 	// TODO: check study data
 }
 
+func TestGetStudyConfigNullColumns(t *testing.T) {
+	var in api.StudyConfig
+	in.ParameterConfigs = new(api.StudyConfig_ParameterConfigs)
+	id, err := db_interface.CreateStudy(&in)
+	if err != nil {
+		t.Fatalf("CreateStudy error %v", err)
+	}
+	defer db_interface.DeleteStudy(id)
+
+	// a study created before the columns were added has NULL in them
+	db := db_interface.(*db_conn).db
+	_, err = db.Exec("UPDATE studies SET duplicate_policy = NULL, job_template = NULL, namespace = NULL, "+
+		"resources = NULL, parameter_injection = NULL, metrics_collector = NULL WHERE id = ?", id)
+	if err != nil {
+		t.Fatalf("UPDATE error %v", err)
+	}
+	study, err := db_interface.GetStudyConfig(id)
+	if err != nil {
+		t.Fatalf("GetStudyConfig failed: %v", err)
+	}
+	if study.DuplicatePolicy != api.DuplicatePolicy_ALLOW_DUPLICATE || study.JobTemplate != "" || study.Namespace != "" ||
+		study.Resources != nil || study.ParameterInjection != nil || study.MetricsCollector != nil {
+		t.Errorf("Expected the defaults for the NULL columns, got %v", study)
+	}
+}
+
 func TestCreateStudyIdGeneration(t *testing.T) {
 	var in api.StudyConfig
 	in.ParameterConfigs = new(api.StudyConfig_ParameterConfigs)
@@ -77,3 +103,28 @@ func TestCreateStudyIdGeneration(t *testing.T) {
 		}
 	}
 }
+
+func TestSuggestionState(t *testing.T) {
+	var in api.StudyConfig
+	in.ParameterConfigs = new(api.StudyConfig_ParameterConfigs)
+	id, err := db_interface.CreateStudy(&in)
+	if err != nil {
+		t.Fatalf("CreateStudy error %v", err)
+	}
+	defer db_interface.DeleteStudy(id)
+
+	state, err := db_interface.GetSuggestionState(id)
+	if err != nil || state != nil {
+		t.Errorf("Expected no state, got %v %v", state, err)
+	}
+	for _, s := range []string{"first", "second"} {
+		err = db_interface.SetSuggestionState(id, []byte(s))
+		if err != nil {
+			t.Fatalf("SetSuggestionState error %v", err)
+		}
+		state, err = db_interface.GetSuggestionState(id)
+		if err != nil || string(state) != s {
+			t.Errorf("GetSuggestionState returned %q %v, expected %q", state, err, s)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/mlkube/katib/api"
+)
+
+// memory_db keeps everything in memory. It is meant for tests, which can run vizier-core without MySQL.
+type memory_db struct {
+	mux     sync.Mutex
+	studies map[string]*api.StudyConfig
+	trials  map[string]*api.Trial
+	// trial ids in the order they were created
+	trialIds []string
+	logs     map[string][]*TrialLog
+	states   map[string][]byte
+}
+
+func NewMemoryDB() VizierDBInterface {
+	d := &memory_db{}
+	d.DB_Init()
+	return d
+}
+
+func (d *memory_db) DB_Init() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.studies = make(map[string]*api.StudyConfig)
+	d.trials = make(map[string]*api.Trial)
+	d.trialIds = nil
+	d.logs = make(map[string][]*TrialLog)
+	d.states = make(map[string][]byte)
+}
+
+func (d *memory_db) GetStudyConfig(id string) (*api.StudyConfig, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	study, ok := d.studies[id]
+	if !ok {
+		return nil, errors.New("study not found")
+	}
+	return proto.Clone(study).(*api.StudyConfig), nil
+}
+
+func (d *memory_db) GetStudyList() ([]string, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var result []string
+	for id := range d.studies {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (d *memory_db) CreateStudy(in *api.StudyConfig) (string, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	study_id := generate_randid()
+	for d.studies[study_id] != nil {
+		study_id = generate_randid()
+	}
+	d.studies[study_id] = proto.Clone(in).(*api.StudyConfig)
+	return study_id, nil
+}
+
+func (d *memory_db) DeleteStudy(id string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	delete(d.studies, id)
+	delete(d.states, id)
+	return nil
+}
+
+func (d *memory_db) GetTrial(id string) (*api.Trial, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	trial, ok := d.trials[id]
+	if !ok {
+		return nil, errors.New("trials not found")
+	}
+	return proto.Clone(trial).(*api.Trial), nil
+}
+
+func (d *memory_db) GetTrialStatus(id string) (api.TrialState, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	trial, ok := d.trials[id]
+	if !ok {
+		return api.TrialState_ERROR, errors.New("trials not found")
+	}
+	return trial.Status, nil
+}
+
+func (d *memory_db) GetTrialList(id string) ([]*api.Trial, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var result []*api.Trial
+	for _, tid := range d.trialIds {
+		if t, ok := d.trials[tid]; ok && t.StudyId == id {
+			result = append(result, proto.Clone(t).(*api.Trial))
+		}
+	}
+	return result, nil
+}
+
+func (d *memory_db) CreateTrial(trial *api.Trial) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	trial_id := generate_randid()
+	for d.trials[trial_id] != nil {
+		trial_id = generate_randid()
+	}
+	trial.TrialId = trial_id
+	d.trials[trial_id] = proto.Clone(trial).(*api.Trial)
+	d.trialIds = append(d.trialIds, trial_id)
+	return nil
+}
+
+func (d *memory_db) UpdateTrial(id string, newstatus api.TrialState) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if trial, ok := d.trials[id]; ok {
+		trial.Status = newstatus
+	}
+	return nil
+}
+
+func (d *memory_db) UpdateTrialObjectiveValue(id string, value string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if trial, ok := d.trials[id]; ok {
+		trial.ObjectiveValue = value
+	}
+	return nil
+}
+
+func (d *memory_db) GetTrialLogs(id string, opts *GetTrialLogOpts) ([]*TrialLog, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return append([]*TrialLog{}, d.logs[id]...), nil
+}
+
+func (d *memory_db) GetTrialTimestamp(id string) (*time.Time, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var last *time.Time
+	for _, l := range d.logs[id] {
+		mt, err := time.Parse(mysql_time_fmt, l.Time)
+		if err != nil {
+			return nil, err
+		}
+		if last == nil || mt.After(*last) {
+			last = &mt
+		}
+	}
+	return last, nil
+}
+
+func (d *memory_db) StoreTrialLogs(trial_id string, logs []string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var lasterr error
+	for _, logline := range logs {
+		if logline == "" {
+			continue
+		}
+		ls := strings.SplitN(logline, " ", 2)
+		if len(ls) != 2 {
+			log.Printf("Error parsing log: %s", logline)
+			lasterr = errors.New("Error parsing log")
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, ls[0])
+		if err != nil {
+			log.Printf("Error parsing time %s: %v", ls[0], err)
+			lasterr = err
+			continue
+		}
+		d.logs[trial_id] = append(d.logs[trial_id], &TrialLog{Time: t.UTC().Format(mysql_time_fmt), Value: ls[1]})
+	}
+	return lasterr
+}
+
+func (d *memory_db) DeleteTrial(id string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	delete(d.trials, id)
+	delete(d.logs, id)
+	return nil
+}
+
+func (d *memory_db) GetSuggestionState(study_id string) ([]byte, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.states[study_id], nil
+}
+
+func (d *memory_db) SetSuggestionState(study_id string, state []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.states[study_id] = append([]byte{}, state...)
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	pb "github.com/mlkube/katib/api"
+)
+
+const (
+	// duplicateOfTag names the completed trial whose result a reused trial took.
+	duplicateOfTag = "DuplicateOf"
+	// maxResuggest is how many times the suggestion service is asked again for the trials dropped as duplicates.
+	maxResuggest = 3
+)
+
+// parameterKey identifies the parameter set of a trial regardless of the order of the parameters.
+func parameterKey(t *pb.Trial) string {
+	ps := make([]string, len(t.ParameterSet))
+	for i, p := range t.ParameterSet {
+		ps[i] = p.Name + "=" + p.Value
+	}
+	sort.Strings(ps)
+	return strings.Join(ps, "\n")
+}
+
+// completedTrials returns the completed trials of the worker and the trials that reused a result.
+func (s *server) completedTrials(studyId string) []*pb.Trial {
+	s.reusedMux.Lock()
+	defer s.reusedMux.Unlock()
+	cts := s.wIF.GetCompletedTrials(studyId)
+	ret := make([]*pb.Trial, 0, len(cts)+len(s.reused[studyId]))
+	ret = append(ret, cts...)
+	return append(ret, s.reused[studyId]...)
+}
+
+// reuseResult stores t as a completed trial with the result of c, without running it.
+func (s *server) reuseResult(studyId string, t *pb.Trial, c *pb.Trial) error {
+	t.StudyId = studyId
+	t.Status = pb.TrialState_COMPLETED
+	t.ObjectiveValue = c.ObjectiveValue
+	t.EvalLogs = c.EvalLogs
+	t.Tags = append(t.Tags, &pb.Tag{Name: duplicateOfTag, Value: c.TrialId})
+	err := s.db.CreateTrial(t)
+	if err != nil {
+		return err
+	}
+	log.Printf("Study %v: Trial %v reuses the result of Trial %v", studyId, t.TrialId, c.TrialId)
+	s.reusedMux.Lock()
+	defer s.reusedMux.Unlock()
+	if s.reused == nil {
+		s.reused = make(map[string][]*pb.Trial)
+	}
+	s.reused[studyId] = append(s.reused[studyId], t)
+	return nil
+}
+
+func (s *server) cleanReused(studyId string) {
+	s.reusedMux.Lock()
+	defer s.reusedMux.Unlock()
+	delete(s.reused, studyId)
+}
+
+// filterDuplicates returns the trials whose parameter set is not the same as a successful or running trial,
+// or as an earlier trial of trials, and the number of trials it dropped.
+// With REUSE_RESULT, the duplicates of successful trials take their result instead of being dropped.
+// The parameter sets of failed trials are run again.
+func (s *server) filterDuplicates(studyId string, policy pb.DuplicatePolicy, trials []*pb.Trial, cts []*pb.Trial, rts []*pb.Trial) ([]*pb.Trial, int, error) {
+	completed := make(map[string]*pb.Trial)
+	for _, t := range cts {
+		if t.Status == pb.TrialState_COMPLETED && t.ObjectiveValue != "" {
+			completed[parameterKey(t)] = t
+		}
+	}
+	seen := make(map[string]bool)
+	for _, t := range rts {
+		seen[parameterKey(t)] = true
+	}
+	var ret []*pb.Trial
+	dropped := 0
+	for _, t := range trials {
+		k := parameterKey(t)
+		if c, ok := completed[k]; ok {
+			if policy == pb.DuplicatePolicy_REUSE_RESULT {
+				if err := s.reuseResult(studyId, t, c); err != nil {
+					return nil, 0, err
+				}
+			} else {
+				log.Printf("Study %v: drop a suggestion identical to completed Trial %v", studyId, c.TrialId)
+				dropped++
+			}
+			continue
+		}
+		if seen[k] {
+			log.Printf("Study %v: drop a suggestion identical to a running Trial", studyId)
+			dropped++
+			continue
+		}
+		seen[k] = true
+		ret = append(ret, t)
+	}
+	return ret, dropped, nil
+}
@@ -9,11 +9,17 @@ import (
 	"log"
 	"net"
 	"os"
+	"runtime"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/mlkube/katib/manager/metricscollector"
 	"github.com/mlkube/katib/manager/worker_interface"
 	dlkwif "github.com/mlkube/katib/manager/worker_interface/dlk"
+	kfwif "github.com/mlkube/katib/manager/worker_interface/kubeflow"
 	k8swif "github.com/mlkube/katib/manager/worker_interface/kubernetes"
+	localwif "github.com/mlkube/katib/manager/worker_interface/local"
 	nvdwif "github.com/mlkube/katib/manager/worker_interface/nvdocker"
 
 	tbif "github.com/mlkube/katib/manager/visualise/tensorboard"
@@ -24,6 +30,7 @@ import (
 	vdb "github.com/mlkube/katib/db"
 	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -36,9 +43,19 @@ const (
 	port          = "0.0.0.0:6789"
 )
 
+// seededAlgorithms take a Seed suggestion parameter. CreateStudy records one in the study when it is not set,
+// so that the trials of the study can be reproduced.
+var seededAlgorithms = map[string]bool{"random": true, "quasirandom": true, "hyperband": true, "bohb": true, "cmaes": true, "asha": true, "pbt": true, "genetic": true}
+
 var init_db = flag.Bool("init", false, "Initialize DB")
 var worker = flag.String("w", "kubernetes", "Worker Typw")
-var dbIf vdb.VizierDBInterface
+var localDir = flag.String("local-dir", "/tmp/katib", "Directory of the trial logs of the local worker")
+var localParallel = flag.Int("local-parallel", runtime.NumCPU(), "Max running trials of a study without MaxParallel in the local worker, 0 for no limit")
+
+// suggestionEndpoint returns the address of the suggestion service of an algorithm.
+func suggestionEndpoint(algorithm string) string {
+	return "vizier-suggestion-" + algorithm + ":6789"
+}
 
 type studyCh struct {
 	stopCh       chan bool
@@ -46,7 +63,17 @@ type studyCh struct {
 }
 type server struct {
 	wIF         worker_interface.WorkerInterface
+	db          vdb.VizierDBInterface
 	StudyChList map[string]studyCh
+	// suggestionEndpoint returns the address of the suggestion service of an algorithm. Tests replace it.
+	suggestionEndpoint func(algorithm string) string
+	// reused are the trials that took the result of a completed trial, see DuplicatePolicy.
+	reused    map[string][]*pb.Trial
+	reusedMux sync.Mutex
+}
+
+func newServer(wIF worker_interface.WorkerInterface, db vdb.VizierDBInterface) *server {
+	return &server{wIF: wIF, db: db, StudyChList: make(map[string]studyCh), suggestionEndpoint: suggestionEndpoint}
 }
 
 func (s *server) saveResult(study_id string) error {
@@ -92,6 +119,7 @@ func (s *server) saveResult(study_id string) error {
 func (s *server) trialIteration(conf *pb.StudyConfig, study_id string, sCh studyCh) error {
 	defer delete(s.StudyChList, study_id)
 	defer s.wIF.CleanWorkers(study_id)
+	defer s.cleanReused(study_id)
 	tm := time.NewTimer(1 * time.Second)
 	log.Printf("Study %v start.", study_id)
 	log.Printf("Study conf %v", conf)
@@ -115,7 +143,7 @@ func (s *server) trialIteration(conf *pb.StudyConfig, study_id string, sCh study
 				for _, trial := range r.Trials {
 					trial.Status = pb.TrialState_PENDING
 					trial.StudyId = study_id
-					err = dbIf.CreateTrial(trial)
+					err = s.db.CreateTrial(trial)
 					if err != nil {
 						log.Printf("CreateTrial failed %v", err)
 						return err
@@ -126,8 +154,14 @@ func (s *server) trialIteration(conf *pb.StudyConfig, study_id string, sCh study
 					log.Printf("SpawnWorkers failed %v", err)
 					return err
 				}
+				// TensorBoard is deployed on kubernetes, which the local worker does not use.
+				// It runs in the namespace of the study to mount the same PVC as the trials.
+				tbns := worker_interface.StudyNamespace(conf)
 				for _, t := range r.Trials {
-					err = tbif.SpawnTensorBoard(study_id, t.TrialId, k8s_namespace, conf.Mount)
+					if *worker == "local" {
+						break
+					}
+					err = tbif.SpawnTensorBoard(study_id, t.TrialId, tbns, conf.Mount)
 					if err != nil {
 						log.Printf("SpawnTB failed %v", err)
 						return err
@@ -152,8 +186,20 @@ func (s *server) CreateStudy(ctx context.Context, in *pb.CreateStudyRequest) (*p
 	if in.StudyConfig.ObjectiveValueName == "" {
 		return &pb.CreateStudyReply{}, errors.New("Objective_Value_Name is required.")
 	}
+	if in.StudyConfig.SuggestAlgorithm == "pbt" && (in.StudyConfig.Mount == nil || in.StudyConfig.Mount.Pvc == "") {
+		return &pb.CreateStudyReply{}, errors.New("pbt requires a Mount to store checkpoints.")
+	}
+	if _, err := metricscollector.New(in.StudyConfig, "", ""); err != nil {
+		return &pb.CreateStudyReply{}, err
+	}
+	if in.StudyConfig.MetricsCollector.GetSidecar() != nil && *worker != "kubernetes" {
+		return &pb.CreateStudyReply{}, errors.New(fmt.Sprintf("The metrics collector sidecar is not supported by the %v worker", *worker))
+	}
+	if seededAlgorithms[in.StudyConfig.SuggestAlgorithm] {
+		setSeed(in.StudyConfig)
+	}
 
-	study_id, err := dbIf.CreateStudy(in.StudyConfig)
+	study_id, err := s.db.CreateStudy(in.StudyConfig)
 
 	_, err = s.InitializeSuggestService(
 		ctx,
@@ -173,6 +219,15 @@ func (s *server) CreateStudy(ctx context.Context, in *pb.CreateStudyRequest) (*p
 	return &pb.CreateStudyReply{StudyId: study_id}, nil
 }
 
+func setSeed(conf *pb.StudyConfig) {
+	for _, sp := range conf.SuggestionParameters {
+		if sp.Name == "Seed" {
+			return
+		}
+	}
+	conf.SuggestionParameters = append(conf.SuggestionParameters, &pb.SuggestionParameter{Name: "Seed", Value: strconv.FormatInt(time.Now().UnixNano(), 10)})
+}
+
 func (s *server) StopStudy(ctx context.Context, in *pb.StopStudyRequest) (*pb.StopStudyReply, error) {
 	sc, ok := s.StudyChList[in.StudyId]
 	if !ok {
@@ -210,13 +265,13 @@ func (s *server) GetStudys(ctx context.Context, in *pb.GetStudysRequest) (*pb.Ge
 	ss := make([]*pb.StudyInfo, len(s.StudyChList))
 	i := 0
 	for sid := range s.StudyChList {
-		sc, _ := dbIf.GetStudyConfig(sid)
+		sc, _ := s.db.GetStudyConfig(sid)
 		ss[i] = &pb.StudyInfo{
 			StudyId:           sid,
 			Name:              sc.Name,
 			Owner:             sc.Owner,
 			RunningTrialNum:   int32(len(s.wIF.GetRunningTrials(sid))),
-			CompletedTrialNum: int32(len(s.wIF.GetCompletedTrials(sid))),
+			CompletedTrialNum: int32(len(s.completedTrials(sid))),
 		}
 		i++
 	}
@@ -224,7 +279,7 @@ func (s *server) GetStudys(ctx context.Context, in *pb.GetStudysRequest) (*pb.Ge
 }
 
 func (s *server) InitializeSuggestService(ctx context.Context, in *pb.InitializeSuggestServiceRequest) (*pb.InitializeSuggestServiceReply, error) {
-	conn, err := grpc.Dial("vizier-suggestion-"+in.SuggestAlgorithm+":6789", grpc.WithInsecure())
+	conn, err := grpc.Dial(s.suggestionEndpoint(in.SuggestAlgorithm), grpc.WithInsecure())
 	if err != nil {
 		log.Printf("could not connect: %v", err)
 		return &pb.InitializeSuggestServiceReply{}, err
@@ -243,7 +298,7 @@ func (s *server) SuggestTrials(ctx context.Context, in *pb.SuggestTrialsRequest)
 	var suggest_algo string
 
 	// TODO: only a few columns are needed but GetStudyConfig does a full retrieval
-	study, err := dbIf.GetStudyConfig(in.StudyId)
+	study, err := s.db.GetStudyConfig(in.StudyId)
 	if err != nil {
 		return nil, err
 	}
@@ -256,27 +311,81 @@ func (s *server) SuggestTrials(ctx context.Context, in *pb.SuggestTrialsRequest)
 		return &pb.SuggestTrialsReply{Completed: false}, errors.New("No suggest algorithm specified")
 	}
 
-	conn, err := grpc.Dial("vizier-suggestion-"+suggest_algo+":6789", grpc.WithInsecure())
+	conn, err := grpc.Dial(s.suggestionEndpoint(suggest_algo), grpc.WithInsecure())
 	if err != nil {
 		return &pb.SuggestTrialsReply{Completed: false}, err
 	}
 
 	defer conn.Close()
 	c := pb.NewSuggestionClient(conn)
-	cts := s.wIF.GetCompletedTrials(in.StudyId)
+	cts := s.completedTrials(in.StudyId)
 	rts := s.wIF.GetRunningTrials(in.StudyId)
-	req := &pb.GenerateTrialsRequest{StudyId: in.StudyId, Configs: in.Configs, CompletedTrials: cts, RunningTrials: rts}
-	r, err := c.GenerateTrials(context.Background(), req)
+	r, err := s.generateTrials(c, in.StudyId, in.Configs, cts, rts)
 	if err != nil {
 		return &pb.SuggestTrialsReply{Completed: false}, err
 	}
+	if r.Completed || study.DuplicatePolicy == pb.DuplicatePolicy_ALLOW_DUPLICATE {
+		return &pb.SuggestTrialsReply{Trials: r.Trials, Completed: r.Completed}, nil
+	}
+
+	trials, dropped, err := s.filterDuplicates(in.StudyId, study.DuplicatePolicy, r.Trials, cts, rts)
+	if err != nil {
+		return &pb.SuggestTrialsReply{Completed: false}, err
+	}
+	for i := 0; i < maxResuggest && dropped > 0 && study.DuplicatePolicy == pb.DuplicatePolicy_RESUGGEST; i++ {
+		// the trials kept so far are given as running so that the service suggests the others only
+		pending := append(append([]*pb.Trial{}, rts...), trials...)
+		r, err = s.generateTrials(c, in.StudyId, in.Configs, cts, pending)
+		if err != nil || r.Completed {
+			break
+		}
+		var more []*pb.Trial
+		more, dropped, err = s.filterDuplicates(in.StudyId, study.DuplicatePolicy, r.Trials, cts, pending)
+		if err != nil {
+			break
+		}
+		trials = append(trials, more...)
+	}
 
 	// TODO: do async
-	return &pb.SuggestTrialsReply{Trials: r.Trials, Completed: r.Completed}, nil
+	return &pb.SuggestTrialsReply{Trials: trials, Completed: false}, nil
 }
 
-func (s *server) CompleteTrial(context.Context, *pb.CompleteTrialRequest) (*pb.CompleteTrialReply, error) {
-	return nil, errors.New("not implemented")
+// generateTrials calls GenerateTrials of the suggestion service with the saved state of the study and saves the new state.
+func (s *server) generateTrials(c pb.SuggestionClient, studyId string, conf *pb.StudyConfig, cts []*pb.Trial, rts []*pb.Trial) (*pb.GenerateTrialsReply, error) {
+	state, err := s.db.GetSuggestionState(studyId)
+	if err != nil {
+		log.Printf("GetSuggestionState failed %v", err)
+	}
+	req := &pb.GenerateTrialsRequest{StudyId: studyId, Configs: conf, CompletedTrials: cts, RunningTrials: rts, State: state}
+	r, err := c.GenerateTrials(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.State) > 0 {
+		err = s.db.SetSuggestionState(studyId, r.State)
+		if err != nil {
+			log.Printf("SetSuggestionState failed %v", err)
+		}
+	}
+	return r, nil
+}
+
+// measurementReceiver returns the worker interface if its trials can send their metrics.
+func (s *server) measurementReceiver() (worker_interface.MeasurementReceiver, error) {
+	mr, ok := s.wIF.(worker_interface.MeasurementReceiver)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("The %v worker does not receive the metrics of the trials", *worker))
+	}
+	return mr, nil
+}
+
+func (s *server) CompleteTrial(ctx context.Context, in *pb.CompleteTrialRequest) (*pb.CompleteTrialReply, error) {
+	mr, err := s.measurementReceiver()
+	if err != nil {
+		return &pb.CompleteTrialReply{}, err
+	}
+	return &pb.CompleteTrialReply{}, mr.CompleteTrial(in.StudyId, in.TrialId, in.IsComplete)
 }
 func (s *server) ShouldTrialStop(context.Context, *pb.ShouldTrialStopRequest) (*pb.ShouldTrialStopReply, error) {
 	return nil, errors.New("not implemented")
@@ -285,9 +394,37 @@ func (s *server) GetObjectValue(context.Context, *pb.GetObjectValueRequest) (*pb
 	return nil, errors.New("not implemented")
 }
 
-func (s *server) AddMeasurementToTrials(context.Context, *pb.AddMeasurementToTrialsRequest) (*pb.AddMeasurementToTrialsReply, error) {
-
-	return &pb.AddMeasurementToTrialsReply{}, nil
+// AddMeasurementToTrials adds the evaluation logs to a running trial. metrics is a JSON object with the metrics
+// of the study and the step, e.g. {"step": 100, "loss": 0.3}, which is added as an evaluation log at the current time.
+func (s *server) AddMeasurementToTrials(ctx context.Context, in *pb.AddMeasurementToTrialsRequest) (*pb.AddMeasurementToTrialsReply, error) {
+	mr, err := s.measurementReceiver()
+	if err != nil {
+		return &pb.AddMeasurementToTrialsReply{}, err
+	}
+	els := in.EvalLogs
+	if in.Metrics != "" {
+		sc, err := s.db.GetStudyConfig(in.StudyId)
+		if err != nil {
+			return &pb.AddMeasurementToTrialsReply{}, err
+		}
+		mc, err := metricscollector.New(&pb.StudyConfig{MetricsCollector: &pb.MetricsCollectorConf{
+			Kind:     pb.MetricsCollectorConf_JSON_LINES,
+			StepName: sc.MetricsCollector.GetStepName(),
+		}}, in.StudyId, in.TrialId)
+		if err != nil {
+			return &pb.AddMeasurementToTrialsReply{}, err
+		}
+		line := metricscollector.Line{Time: time.Now().UTC().Format(time.RFC3339Nano), Text: in.Metrics}
+		es, err := mc.Collect([]metricscollector.Line{line}, metricscollector.WithObjective(sc.Metrics, sc.ObjectiveValueName))
+		if err != nil {
+			return &pb.AddMeasurementToTrialsReply{}, err
+		}
+		if len(es) == 0 {
+			return &pb.AddMeasurementToTrialsReply{}, errors.New(fmt.Sprintf("No metrics of Study %v are found in %v", in.StudyId, in.Metrics))
+		}
+		els = append(els, es...)
+	}
+	return &pb.AddMeasurementToTrialsReply{}, mr.AddMeasurement(in.StudyId, in.TrialId, els)
 }
 
 func main() {
@@ -295,7 +432,7 @@ func main() {
 
 	//	if *init_db {
 	var err error
-	dbIf = vdb.New()
+	dbIf := vdb.New()
 
 	dbIf.DB_Init()
 	//	} else {
@@ -316,14 +453,32 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		pb.RegisterManagerServer(s, &server{wIF: k8swif.NewKubernetesWorkerInterface(clientset, dbIf), StudyChList: make(map[string]studyCh)})
+		pb.RegisterManagerServer(s, newServer(k8swif.NewKubernetesWorkerInterface(clientset, dbIf), dbIf))
 		// XXX Is this useful?
+	case "kubeflow":
+		log.Printf("Worker: kubeflow\n")
+		kc, err := clientcmd.BuildConfigFromFlags("", "/conf/kubeconfig")
+		if err != nil {
+			log.Fatal(err)
+		}
+		clientset, err := kubernetes.NewForConfig(kc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dc, err := dynamic.NewForConfig(kc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pb.RegisterManagerServer(s, newServer(kfwif.NewKubeflowWorkerInterface(clientset, dc, dbIf), dbIf))
 	case "dlk":
 		log.Printf("Worker: dlk\n")
-		pb.RegisterManagerServer(s, &server{wIF: dlkwif.NewDlkWorkerInterface("http://dlk-manager:1323", k8s_namespace), StudyChList: make(map[string]studyCh)})
+		pb.RegisterManagerServer(s, newServer(dlkwif.NewDlkWorkerInterface("http://dlk-manager:1323", k8s_namespace), dbIf))
 	case "nv-docker":
 		log.Printf("Worker: nv-docker\n")
-		pb.RegisterManagerServer(s, &server{wIF: nvdwif.NewNvDockerWorkerInterface(), StudyChList: make(map[string]studyCh)})
+		pb.RegisterManagerServer(s, newServer(nvdwif.NewNvDockerWorkerInterface(), dbIf))
+	case "local":
+		log.Printf("Worker: local\n")
+		pb.RegisterManagerServer(s, newServer(localwif.NewLocalWorkerInterface(dbIf, *localDir, *localParallel), dbIf))
 	default:
 		log.Fatalf("Unknown worker")
 	}
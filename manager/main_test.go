@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/mlkube/katib/api"
+	vdb "github.com/mlkube/katib/db"
+	simwif "github.com/mlkube/katib/manager/worker_interface/simulated"
+	"github.com/mlkube/katib/suggestion"
+)
+
+// startSuggestion serves the random suggestion service on a local port and returns its address.
+func startSuggestion(t *testing.T) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterSuggestionServer(gs, suggestion.NewRandomSuggestService())
+	go gs.Serve(lis)
+	return lis.Addr().String(), gs.Stop
+}
+
+// newTestServer returns a server with its own DB and suggestion service, stopped by the returned function.
+func newTestServer(t *testing.T, conf simwif.Config) (*server, func()) {
+	addr, stop := startSuggestion(t)
+	db := vdb.NewMemoryDB()
+	s := newServer(simwif.NewSimulatedWorkerInterface(db, conf), db)
+	s.suggestionEndpoint = func(string) string { return addr }
+	return s, stop
+}
+
+func newStudyConfig(pcs []*pb.ParameterConfig, suggestionNum int, maxParallel int) *pb.StudyConfig {
+	return &pb.StudyConfig{
+		Name:               "test",
+		Owner:              "tester",
+		OptimizationType:   pb.OptimizationType_MINIMIZE,
+		ObjectiveValueName: "loss",
+		SuggestAlgorithm:   "random",
+		ParameterConfigs:   &pb.StudyConfig_ParameterConfigs{Configs: pcs},
+		SuggestionParameters: []*pb.SuggestionParameter{
+			{Name: "SuggestionNum", Value: strconv.Itoa(suggestionNum)},
+			{Name: "MaxParallel", Value: strconv.Itoa(maxParallel)},
+		},
+	}
+}
+
+// waitCompleted waits until the study has n completed trials.
+func waitCompleted(t *testing.T, s *server, studyId string, n int) []*pb.Trial {
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		if cts := s.completedTrials(studyId); len(cts) >= n {
+			return cts
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Study %v did not complete %v trials in time", studyId, n)
+	return nil
+}
+
+func paramValue(t *pb.Trial, name string) string {
+	for _, p := range t.ParameterSet {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func TestStudyCompletes(t *testing.T) {
+	objective := func(trial *pb.Trial) (float64, error) {
+		x, err := strconv.ParseFloat(paramValue(trial, "x"), 64)
+		if err != nil {
+			return 0, err
+		}
+		y, err := strconv.Atoi(paramValue(trial, "y"))
+		if err != nil {
+			return 0, err
+		}
+		return x*x + float64(y), nil
+	}
+	s, stop := newTestServer(t, simwif.Config{Objective: objective, Latency: 10 * time.Millisecond})
+	defer stop()
+	conf := newStudyConfig([]*pb.ParameterConfig{
+		{Name: "x", ParameterType: pb.ParameterType_DOUBLE, Feasible: &pb.FeasibleSpace{Min: "-1", Max: "1"}},
+		{Name: "y", ParameterType: pb.ParameterType_INT, Feasible: &pb.FeasibleSpace{Min: "0", Max: "10"}},
+	}, 5, 2)
+	r, err := s.CreateStudy(context.Background(), &pb.CreateStudyRequest{StudyConfig: conf})
+	if err != nil {
+		t.Fatalf("CreateStudy failed: %v", err)
+	}
+
+	cts := waitCompleted(t, s, r.StudyId, 5)
+	for _, ct := range cts {
+		if ct.Status != pb.TrialState_COMPLETED {
+			t.Errorf("Trial %v status %v, want COMPLETED", ct.TrialId, ct.Status)
+		}
+		want, _ := objective(ct)
+		if ct.ObjectiveValue != strconv.FormatFloat(want, 'f', -1, 64) {
+			t.Errorf("Trial %v objective value %v, want %v", ct.TrialId, ct.ObjectiveValue, want)
+		}
+		status, err := s.db.GetTrialStatus(ct.TrialId)
+		if err != nil || status != pb.TrialState_COMPLETED {
+			t.Errorf("Trial %v status in DB %v %v, want COMPLETED", ct.TrialId, status, err)
+		}
+	}
+	trials, _ := s.db.GetTrialList(r.StudyId)
+	if len(trials) != 5 {
+		t.Errorf("%v trials in DB, want 5", len(trials))
+	}
+	if sc, err := s.db.GetStudyConfig(r.StudyId); err != nil || len(sc.SuggestionParameters) != 3 {
+		t.Errorf("Study in DB %v %v, want a Seed suggestion parameter added", sc, err)
+	}
+	if st, _ := s.db.GetSuggestionState(r.StudyId); len(st) == 0 {
+		t.Errorf("Suggestion state is not saved")
+	}
+}
+
+func TestFailedTrials(t *testing.T) {
+	s, stop := newTestServer(t, simwif.Config{
+		Objective:   func(*pb.Trial) (float64, error) { return 0, nil },
+		Latency:     10 * time.Millisecond,
+		FailureRate: 1,
+	})
+	defer stop()
+	conf := newStudyConfig([]*pb.ParameterConfig{
+		{Name: "x", ParameterType: pb.ParameterType_DOUBLE, Feasible: &pb.FeasibleSpace{Min: "0", Max: "1"}},
+	}, 3, 3)
+	r, err := s.CreateStudy(context.Background(), &pb.CreateStudyRequest{StudyConfig: conf})
+	if err != nil {
+		t.Fatalf("CreateStudy failed: %v", err)
+	}
+	for _, ct := range waitCompleted(t, s, r.StudyId, 3) {
+		if ct.Status != pb.TrialState_ERROR || ct.ObjectiveValue != "" {
+			t.Errorf("Trial %v status %v value %q, want ERROR without value", ct.TrialId, ct.Status, ct.ObjectiveValue)
+		}
+	}
+}
+
+func TestReuseDuplicateResult(t *testing.T) {
+	for _, c := range []struct {
+		failureRate float64
+		// maxEvaluated is the largest number of evaluations, -1 for no limit
+		maxEvaluated int32
+	}{
+		{failureRate: 0, maxEvaluated: 2},
+		{failureRate: 0.5, maxEvaluated: -1},
+		{failureRate: 1, maxEvaluated: 0},
+	} {
+		var evaluated int32
+		s, stop := newTestServer(t, simwif.Config{
+			Objective: func(*pb.Trial) (float64, error) {
+				atomic.AddInt32(&evaluated, 1)
+				return 1, nil
+			},
+			Latency:     10 * time.Millisecond,
+			FailureRate: c.failureRate,
+			Seed:        1,
+		})
+		conf := newStudyConfig([]*pb.ParameterConfig{
+			{Name: "c", ParameterType: pb.ParameterType_CATEGORICAL, Feasible: &pb.FeasibleSpace{List: []string{"a", "b"}}},
+		}, 6, 1)
+		conf.DuplicatePolicy = pb.DuplicatePolicy_REUSE_RESULT
+		r, err := s.CreateStudy(context.Background(), &pb.CreateStudyRequest{StudyConfig: conf})
+		if err != nil {
+			t.Fatalf("FailureRate %v: CreateStudy failed: %v", c.failureRate, err)
+		}
+		cts := waitCompleted(t, s, r.StudyId, 6)
+		stop()
+		if n := atomic.LoadInt32(&evaluated); c.maxEvaluated >= 0 && n > c.maxEvaluated {
+			t.Errorf("FailureRate %v: %v trials evaluated, want at most %v", c.failureRate, n, c.maxEvaluated)
+		}
+		byId := make(map[string]*pb.Trial)
+		for _, ct := range cts {
+			byId[ct.TrialId] = ct
+		}
+		// a result is only reused from a successful trial, and the parameters of a failed trial are run again
+		for _, ct := range cts {
+			if ct.Status == pb.TrialState_ERROR && ct.ObjectiveValue == "" {
+				continue
+			}
+			if ct.Status != pb.TrialState_COMPLETED || ct.ObjectiveValue != "1" {
+				t.Errorf("FailureRate %v: Trial %v status %v value %q, want COMPLETED with 1", c.failureRate, ct.TrialId, ct.Status, ct.ObjectiveValue)
+			}
+			for _, tag := range ct.Tags {
+				if src := byId[tag.Value]; tag.Name == duplicateOfTag && (src == nil || src.Status != pb.TrialState_COMPLETED || src.ObjectiveValue != "1") {
+					t.Errorf("FailureRate %v: Trial %v reuses the result of %v", c.failureRate, ct.TrialId, src)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package metricscollector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+// evalLogs formats evaluation logs as "step:name=value,..." to compare them.
+func evalLogs(els []*api.EvaluationLog) []string {
+	var ret []string
+	for _, el := range els {
+		var ms []string
+		for _, m := range el.Metrics {
+			ms = append(ms, m.Name+"="+m.Value)
+		}
+		ret = append(ret, fmt.Sprintf("%v:%v", el.Step, strings.Join(ms, ",")))
+	}
+	return ret
+}
+
+func lines(texts ...string) []Line {
+	var ret []Line
+	for _, t := range texts {
+		ret = append(ret, Line{Time: "2018-05-01T00:00:00Z", Text: t})
+	}
+	return ret
+}
+
+func newCollector(t *testing.T, mc *api.MetricsCollectorConf) MetricsCollector {
+	c, err := New(&api.StudyConfig{MetricsCollector: mc}, "study", "trial")
+	if err != nil {
+		t.Fatalf("New %v: %v", mc, err)
+	}
+	return c
+}
+
+func TestLineCollectors(t *testing.T) {
+	metrics := []string{"loss", "accuracy"}
+	for _, c := range []struct {
+		name  string
+		mc    *api.MetricsCollectorConf
+		lines []Line
+		want  []string
+	}{
+		{
+			name:  "key value",
+			mc:    &api.MetricsCollectorConf{},
+			lines: lines("step=1 loss=0.5 accuracy=0.8 lr=0.1", "step=2 loss=0.3"),
+			want:  []string{"1:loss=0.5,accuracy=0.8", "2:loss=0.3"},
+		},
+		{
+			name:  "key value malformed",
+			mc:    &api.MetricsCollectorConf{Kind: api.MetricsCollectorConf_KEY_VALUE},
+			lines: lines("", "   ", "loss", "=0.5", "loss=", "==", "step=x loss=0.4", "epoch done"),
+			want:  []string{"0:loss=0.4"},
+		},
+		{
+			name:  "key value with = in the value",
+			mc:    &api.MetricsCollectorConf{StepName: "iter"},
+			lines: lines("iter=3 loss=0.1=0.2 accuracy==0.9"),
+			want:  []string{"3:loss=0.1=0.2,accuracy==0.9"},
+		},
+		{
+			name:  "regex",
+			mc:    &api.MetricsCollectorConf{Kind: api.MetricsCollectorConf_REGEX, Regex: `(?P<name>\w+): (?P<value>[-+.\deE]+)`},
+			lines: lines("Epoch 1 loss: 0.5 accuracy: 0.8 lr: 0.1", "no metrics", ""),
+			want:  []string{"0:loss=0.5,accuracy=0.8"},
+		},
+		{
+			name:  "regex with step",
+			mc:    &api.MetricsCollectorConf{Kind: api.MetricsCollectorConf_REGEX, Regex: `\[(?P<step>\d+)\] (?P<name>\w+)=(?P<value>\S+)`},
+			lines: lines("[7] loss=0.2", "[x] loss=0.1"),
+			want:  []string{"7:loss=0.2"},
+		},
+		{
+			name:  "json lines",
+			mc:    &api.MetricsCollectorConf{Kind: api.MetricsCollectorConf_JSON_LINES},
+			lines: lines(`{"step": 2, "accuracy": 0.9, "loss": "0.25", "lr": 0.1}`, `{"step": 3.0, "loss": 1e-3}`),
+			want:  []string{"2:loss=0.25,accuracy=0.9", "3:loss=1e-3"},
+		},
+		{
+			name: "json lines malformed",
+			mc:   &api.MetricsCollectorConf{Kind: api.MetricsCollectorConf_JSON_LINES},
+			lines: lines("", "loss=0.5", `{"loss": 0.5`, `{"loss": }`, `["loss", 0.5]`, `{"loss": null, "accuracy": true}`,
+				`{"loss": {"value": 0.5}}`, `{"step": "x", "loss": 0.4} trailing`),
+			want: []string{"0:loss=0.4"},
+		},
+	} {
+		es, err := newCollector(t, c.mc).Collect(c.lines, metrics)
+		if err != nil {
+			t.Errorf("%v: Collect: %v", c.name, err)
+			continue
+		}
+		if got := evalLogs(es); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: expected %v, got %v", c.name, c.want, got)
+		}
+		for _, el := range es {
+			if el.Time != c.lines[0].Time {
+				t.Errorf("%v: expected the time of the line, got %v", c.name, el.Time)
+			}
+		}
+	}
+}
+
+func TestNewCollectorErrors(t *testing.T) {
+	for _, mc := range []*api.MetricsCollectorConf{
+		{Kind: api.MetricsCollectorConf_REGEX, Regex: `\w+: [\d.]+`},
+		{Kind: api.MetricsCollectorConf_REGEX, Regex: `(\w+): ([\d.]+)`},
+		{Kind: api.MetricsCollectorConf_REGEX, Regex: `(?P<name>\w+): [\d.]+`},
+		{Kind: api.MetricsCollectorConf_REGEX, Regex: `(?P<name>\w+: (?P<value>[\d.]+)`},
+		{Kind: api.MetricsCollectorConf_FILE},
+		{Kind: api.MetricsCollectorConf_FILE, FilePath: "/tmp/metrics", FileFormat: api.MetricsCollectorConf_FILE},
+		{Kind: api.MetricsCollectorConf_TF_EVENT},
+	} {
+		if _, err := New(&api.StudyConfig{MetricsCollector: mc}, "study", "trial"); err == nil {
+			t.Errorf("Expected an error for %v", mc)
+		}
+	}
+}
+
+func TestFileCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := newCollector(t, &api.MetricsCollectorConf{
+		Kind:     api.MetricsCollectorConf_FILE,
+		FilePath: filepath.Join(dir, "{{TRIAL_ID}}.log"),
+	})
+	p := filepath.Join(dir, "trial.log")
+	collect := func(want ...string) {
+		es, err := c.Collect(lines("step=9 loss=9"), []string{"loss"})
+		if err != nil {
+			t.Fatalf("Collect: %v", err)
+		}
+		if got := evalLogs(es); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+	appendFile := func(s string) {
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		f.WriteString(s)
+	}
+
+	// not written yet, and the lines of the logs are ignored
+	collect()
+	appendFile("step=1 loss=0.5\nstep=2 lo")
+	collect("1:loss=0.5")
+	// the line being written is read once it ends
+	appendFile("ss=0.4\n\nstep=3 loss=0.3\n")
+	collect("2:loss=0.4", "3:loss=0.3")
+	collect()
+
+	// truncated and rewritten
+	if err := ioutil.WriteFile(p, []byte("step=1 loss=0.9\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	collect("1:loss=0.9")
+	// rotated
+	if err := os.Rename(p, p+".1"); err != nil {
+		t.Fatal(err)
+	}
+	collect()
+	appendFile("step=2 loss=0.8\n")
+	collect("2:loss=0.8")
+}
@@ -0,0 +1,60 @@
+package metricscollector
+
+import (
+	"bytes"
+	"github.com/mlkube/katib/api"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// FileCollector reads the metrics from the lines appended to a file since the last call, parsed by Format.
+// The file has to be readable by vizier-core, e.g. on a volume shared with the trial.
+// The lines get the time they are read.
+type FileCollector struct {
+	Path   string
+	Format MetricsCollector
+	offset int64
+	// file is the file read by the last call
+	file os.FileInfo
+}
+
+func (c *FileCollector) Collect(lines []Line, metrics []string) ([]*api.EvaluationLog, error) {
+	f, err := os.Open(c.Path)
+	if os.IsNotExist(err) {
+		// not written yet
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < c.offset || c.file != nil && !os.SameFile(fi, c.file) {
+		// truncated or rotated, read the new content from the start
+		c.offset = 0
+	}
+	c.file = fi
+	if _, err := f.Seek(c.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	// leave a line being written for the next call
+	n := bytes.LastIndexByte(b, '\n') + 1
+	c.offset += int64(n)
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	var fl []Line
+	for _, l := range strings.Split(string(b[:n]), "\n") {
+		if l != "" {
+			fl = append(fl, Line{Time: now, Text: l})
+		}
+	}
+	return c.Format.Collect(fl, metrics)
+}
@@ -0,0 +1,49 @@
+package metricscollector
+
+import (
+	"encoding/json"
+	"github.com/mlkube/katib/api"
+	"strings"
+)
+
+// JSONLinesCollector reads the metrics from the fields of JSON objects written one per line,
+// e.g. {"step": 10, "loss": 0.3, "accuracy": 0.9}. Other lines are skipped.
+type JSONLinesCollector struct {
+	StepName string
+}
+
+func (c *JSONLinesCollector) Collect(lines []Line, metrics []string) ([]*api.EvaluationLog, error) {
+	return collectLines(lines, metrics, c.parse), nil
+}
+
+func (c *JSONLinesCollector) parse(text string, metrics []string) ([]*api.Metrics, int64) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") {
+		return nil, 0
+	}
+	d := json.NewDecoder(strings.NewReader(text))
+	d.UseNumber()
+	var obj map[string]interface{}
+	if err := d.Decode(&obj); err != nil {
+		return nil, 0
+	}
+	var step int64
+	if n, ok := obj[c.StepName].(json.Number); ok {
+		if s, err := n.Int64(); err == nil {
+			step = s
+		} else if f, err := n.Float64(); err == nil {
+			step = int64(f)
+		}
+	}
+	var ret []*api.Metrics
+	// in the order of metrics, as the fields of an object have none
+	for _, m := range metrics {
+		switch v := obj[m].(type) {
+		case json.Number:
+			ret = append(ret, &api.Metrics{Name: m, Value: v.String()})
+		case string:
+			ret = append(ret, &api.Metrics{Name: m, Value: v})
+		}
+	}
+	return ret, step
+}
@@ -0,0 +1,37 @@
+package metricscollector
+
+import (
+	"github.com/mlkube/katib/api"
+	"strconv"
+	"strings"
+)
+
+// KeyValueCollector reads the metrics from name=value tokens separated by spaces, e.g. "step=10 loss=0.3 accuracy=0.9".
+type KeyValueCollector struct {
+	StepName string
+}
+
+func (c *KeyValueCollector) Collect(lines []Line, metrics []string) ([]*api.EvaluationLog, error) {
+	return collectLines(lines, metrics, c.parse), nil
+}
+
+func (c *KeyValueCollector) parse(text string, metrics []string) ([]*api.Metrics, int64) {
+	var ret []*api.Metrics
+	var step int64
+	for _, f := range strings.Fields(text) {
+		// the value is after the first =, e.g. a=b=c is a with b=c
+		v := strings.SplitN(f, "=", 2)
+		if len(v) != 2 || v[1] == "" {
+			continue
+		}
+		if v[0] == c.StepName {
+			if s, err := strconv.ParseInt(v[1], 10, 64); err == nil {
+				step = s
+			}
+		}
+		if isMetric(v[0], metrics) {
+			ret = append(ret, &api.Metrics{Name: v[0], Value: v[1]})
+		}
+	}
+	return ret, step
+}
@@ -0,0 +1,212 @@
+package metricscollector
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/manager/worker_interface"
+	"path"
+	"strings"
+	"sync"
+)
+
+// defaultStepName is the name of the step in KEY_VALUE, JSON_LINES and PROMETHEUS without StepName.
+const defaultStepName = "step"
+
+// Line is a line of the output of a trial and the time it was written, in RFC3339.
+type Line struct {
+	Time string
+	Text string
+}
+
+// MetricsCollector reads the metrics of a trial.
+type MetricsCollector interface {
+	// Collect returns an EvaluationLog for each line with any of the metrics.
+	// lines are the output of the trial since the last call. Collectors which read another source ignore them.
+	Collect(lines []Line, metrics []string) ([]*api.EvaluationLog, error)
+}
+
+// New returns the collector selected by the metrics collector config of the study for a trial.
+func New(sc *api.StudyConfig, studyId string, tID string) (MetricsCollector, error) {
+	mc := sc.MetricsCollector
+	if mc == nil {
+		mc = &api.MetricsCollectorConf{}
+	}
+	p := worker_interface.ReplacePlaceholders(mc.FilePath, sc.Mount, studyId, &api.Trial{TrialId: tID})
+	switch mc.Kind {
+	case api.MetricsCollectorConf_FILE:
+		if p == "" {
+			return nil, errors.New("file_path is required by the FILE metrics collector")
+		}
+		f, err := newLineCollector(mc, mc.FileFormat)
+		if err != nil {
+			return nil, err
+		}
+		return &FileCollector{Path: p, Format: f}, nil
+	case api.MetricsCollectorConf_TF_EVENT:
+		if p == "" {
+			if sc.Mount == nil || sc.Mount.Path == "" {
+				return nil, errors.New("file_path or mount is required by the TF_EVENT metrics collector")
+			}
+			// where TensorBoard of the trial reads
+			p = path.Join(sc.Mount.Path, "logs", studyId+"_"+tID)
+		}
+		return &TFEventCollector{Dir: p}, nil
+	case api.MetricsCollectorConf_PROMETHEUS:
+		return NewPrometheusCollector(mc)
+	}
+	return newLineCollector(mc, mc.Kind)
+}
+
+// Scraper is a collector which reads an endpoint of the trial instead of its output.
+type Scraper interface {
+	MetricsCollector
+	// SetHost sets the address of the trial, e.g. the IP of its pod.
+	SetHost(host string)
+}
+
+// Scrapes reports whether the collector of the study reads an endpoint of the running trial,
+// so the worker has to give it the host and can not collect the metrics after the trial ends.
+func Scrapes(sc *api.StudyConfig) bool {
+	return sc.MetricsCollector.GetKind() == api.MetricsCollectorConf_PROMETHEUS
+}
+
+// WithObjective returns metrics and objname, for the collectors which have to collect the objective value
+// while the trial runs, e.g. the ones which scrape it.
+func WithObjective(metrics []string, objname string) []string {
+	if isMetric(objname, metrics) {
+		return metrics
+	}
+	return append(append([]string{}, metrics...), objname)
+}
+
+// SetHost gives the address of the trial to mc if it is a Scraper.
+func SetHost(mc MetricsCollector, host string) {
+	if s, ok := mc.(Scraper); ok {
+		s.SetHost(host)
+	}
+}
+
+func newLineCollector(mc *api.MetricsCollectorConf, kind api.MetricsCollectorConf_Kind) (MetricsCollector, error) {
+	step := mc.StepName
+	if step == "" {
+		step = defaultStepName
+	}
+	switch kind {
+	case api.MetricsCollectorConf_KEY_VALUE:
+		return &KeyValueCollector{StepName: step}, nil
+	case api.MetricsCollectorConf_REGEX:
+		return NewRegexCollector(mc.Regex)
+	case api.MetricsCollectorConf_JSON_LINES:
+		return &JSONLinesCollector{StepName: step}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown metrics format %v", kind))
+}
+
+// lineParser returns the metrics in a line and its step.
+type lineParser func(text string, metrics []string) ([]*api.Metrics, int64)
+
+func collectLines(lines []Line, metrics []string, parse lineParser) []*api.EvaluationLog {
+	var ret []*api.EvaluationLog
+	for _, l := range lines {
+		ms, step := parse(l.Text, metrics)
+		if len(ms) > 0 {
+			ret = append(ret, &api.EvaluationLog{Time: l.Time, Metrics: ms, Step: step})
+		}
+	}
+	return ret
+}
+
+func isMetric(name string, metrics []string) bool {
+	for _, m := range metrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TimestampedLines splits log lines which start with a timestamp, as written by kubernetes and docker.
+func TimestampedLines(logs []string) []Line {
+	var ret []Line
+	for _, l := range logs {
+		if l == "" {
+			continue
+		}
+		ls := strings.SplitN(l, " ", 2)
+		if len(ls) < 2 {
+			ls = append(ls, "")
+		}
+		ret = append(ret, Line{Time: ls[0], Text: ls[1]})
+	}
+	return ret
+}
+
+// ObjectiveValue returns the last value of objname in the evaluation logs.
+func ObjectiveValue(els []*api.EvaluationLog, objname string) (string, error) {
+	for i := len(els) - 1; i >= 0; i-- {
+		for j := len(els[i].Metrics) - 1; j >= 0; j-- {
+			if els[i].Metrics[j].Name == objname {
+				return els[i].Metrics[j].Value, nil
+			}
+		}
+	}
+	return "", errors.New(fmt.Sprintf("No Objective Value Name %v is found in log", objname))
+}
+
+// Collectors keeps the collector of each running trial, so that the FILE collector reads each line once
+// and PROMETHEUS scrapes every scrape interval.
+type Collectors struct {
+	mux *sync.Mutex
+	m   map[string]MetricsCollector
+}
+
+func NewCollectors() *Collectors {
+	return &Collectors{
+		mux: new(sync.Mutex),
+		m:   make(map[string]MetricsCollector),
+	}
+}
+
+func (c *Collectors) get(sc *api.StudyConfig, studyId string, tID string) (MetricsCollector, error) {
+	mc, ok := c.m[tID]
+	if !ok {
+		var err error
+		mc, err = New(sc, studyId, tID)
+		if err != nil {
+			return nil, err
+		}
+		c.m[tID] = mc
+	}
+	return mc, nil
+}
+
+// Collect collects the metrics of a trial with its collector, which is created on the first call.
+func (c *Collectors) Collect(sc *api.StudyConfig, studyId string, tID string, lines []Line, metrics []string) ([]*api.EvaluationLog, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	mc, err := c.get(sc, studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	return mc.Collect(lines, metrics)
+}
+
+// SetHost gives the address of a trial to its collector if it is a Scraper.
+func (c *Collectors) SetHost(sc *api.StudyConfig, studyId string, tID string, host string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	mc, err := c.get(sc, studyId, tID)
+	if err != nil {
+		return err
+	}
+	SetHost(mc, host)
+	return nil
+}
+
+// Delete forgets the collector of a finished trial.
+func (c *Collectors) Delete(tID string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	delete(c.m, tID)
+}
@@ -0,0 +1,231 @@
+package metricscollector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMetricsPath    = "/metrics"
+	defaultScrapeInterval = 10 * time.Second
+)
+
+var scrapeClient = &http.Client{Timeout: 5 * time.Second}
+
+// PrometheusCollector scrapes the metrics endpoint of a trial in the Prometheus text format every Interval.
+// Each scrape with any of the metrics is an EvaluationLog with the time of the scrape.
+type PrometheusCollector struct {
+	// Host is the address of the trial, set by the worker once it is known.
+	Host     string
+	Port     int32
+	Path     string
+	StepName string
+	Interval time.Duration
+	// series are the Prometheus series by metric name
+	series map[string]*sample
+	last   time.Time
+}
+
+// sample is a sample of the exposition, or a series with the labels to match without a value.
+type sample struct {
+	name   string
+	labels map[string]string
+	value  string
+}
+
+func NewPrometheusCollector(mc *api.MetricsCollectorConf) (*PrometheusCollector, error) {
+	if mc.Port <= 0 {
+		return nil, errors.New("port is required by the PROMETHEUS metrics collector")
+	}
+	c := &PrometheusCollector{
+		Port:     mc.Port,
+		Path:     mc.Path,
+		StepName: mc.StepName,
+		Interval: time.Duration(mc.ScrapeInterval) * time.Second,
+		series:   make(map[string]*sample),
+	}
+	if c.Path == "" {
+		c.Path = defaultMetricsPath
+	} else if !strings.HasPrefix(c.Path, "/") {
+		c.Path = "/" + c.Path
+	}
+	if c.StepName == "" {
+		c.StepName = defaultStepName
+	}
+	if c.Interval <= 0 {
+		c.Interval = defaultScrapeInterval
+	}
+	for m, s := range mc.PrometheusMetrics {
+		name, labels, rest, err := parseSeries(s)
+		if err == nil && strings.TrimSpace(rest) != "" {
+			err = errors.New("unexpected " + rest)
+		}
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid Prometheus series %v of %v: %v", s, m, err))
+		}
+		c.series[m] = &sample{name: name, labels: labels}
+	}
+	return c, nil
+}
+
+func (c *PrometheusCollector) SetHost(host string) {
+	c.Host = host
+}
+
+func (c *PrometheusCollector) Collect(lines []Line, metrics []string) ([]*api.EvaluationLog, error) {
+	if c.Host == "" || time.Since(c.last) < c.Interval {
+		return nil, nil
+	}
+	c.last = time.Now()
+	samples, err := c.scrape()
+	if err != nil {
+		return nil, err
+	}
+	el := &api.EvaluationLog{Time: c.last.UTC().Format(time.RFC3339Nano)}
+	for _, m := range metrics {
+		if v := c.lookup(samples, m); v != "" {
+			el.Metrics = append(el.Metrics, &api.Metrics{Name: m, Value: v})
+		}
+	}
+	if len(el.Metrics) == 0 {
+		return nil, nil
+	}
+	if v := c.lookup(samples, c.StepName); v != "" {
+		if s, err := strconv.ParseFloat(v, 64); err == nil {
+			el.Step = int64(s)
+		}
+	}
+	return []*api.EvaluationLog{el}, nil
+}
+
+// lookup returns the value of the first sample of the series of a metric, or "" if there is none.
+func (c *PrometheusCollector) lookup(samples []*sample, metric string) string {
+	sel, ok := c.series[metric]
+	if !ok {
+		sel = &sample{name: metric}
+	}
+	for _, s := range samples {
+		if s.name != sel.name {
+			continue
+		}
+		match := true
+		for l, v := range sel.labels {
+			if s.labels[l] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			return s.value
+		}
+	}
+	return ""
+}
+
+// scrape returns the samples with a finite value at the endpoint of the trial.
+func (c *PrometheusCollector) scrape() ([]*sample, error) {
+	url := "http://" + net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port))) + c.Path
+	resp, err := scrapeClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Error scraping %v: %v", url, resp.Status))
+	}
+	var ret []*sample
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		name, labels, rest, err := parseSeries(l)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid sample %q at %v: %v", l, url, err))
+		}
+		// the value may be followed by a timestamp
+		fs := strings.Fields(rest)
+		if len(fs) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fs[0], 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		ret = append(ret, &sample{name: name, labels: labels, value: fs[0]})
+	}
+	return ret, s.Err()
+}
+
+// parseSeries parses a metric name with optional labels, e.g. model_accuracy{split="eval"}, and returns the rest of s.
+func parseSeries(s string) (string, map[string]string, string, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, "{ \t")
+	if i < 0 {
+		i = len(s)
+	}
+	name := s[:i]
+	if name == "" {
+		return "", nil, "", errors.New("no metric name")
+	}
+	s = s[i:]
+	if !strings.HasPrefix(s, "{") {
+		return name, nil, s, nil
+	}
+	s = s[1:]
+	labels := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if strings.HasPrefix(s, "}") {
+			return name, labels, s[1:], nil
+		}
+		j := strings.Index(s, "=")
+		if j <= 0 {
+			return "", nil, "", errors.New("invalid labels")
+		}
+		l := strings.TrimSpace(s[:j])
+		s = strings.TrimLeft(s[j+1:], " \t")
+		if !strings.HasPrefix(s, `"`) {
+			return "", nil, "", errors.New(fmt.Sprintf("label %v is not quoted", l))
+		}
+		v, rest, err := parseLabelValue(s[1:])
+		if err != nil {
+			return "", nil, "", err
+		}
+		labels[l] = v
+		s = rest
+	}
+}
+
+// parseLabelValue returns the label value up to the closing quote, with \\, \" and \n unescaped, and the rest of s.
+func parseLabelValue(s string) (string, string, error) {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return string(b), s[i+1:], nil
+		case '\\':
+			i++
+			if i == len(s) {
+				break
+			}
+			if s[i] == 'n' {
+				b = append(b, '\n')
+			} else {
+				b = append(b, s[i])
+			}
+		default:
+			b = append(b, s[i])
+		}
+	}
+	return "", "", errors.New("unterminated label value")
+}
@@ -0,0 +1,141 @@
+package metricscollector
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mlkube/katib/api"
+)
+
+const exposition = `# HELP model_accuracy Accuracy of the model.
+# TYPE model_accuracy gauge
+model_accuracy{split="train"} 0.95
+model_accuracy{split="eval",note="a \"quoted\" label"} 0.91
+# TYPE loss gauge
+loss 0.3 1520000000000
+global_step 120
+lr NaN
+`
+
+// newScraped returns a collector of a local endpoint serving body with status.
+func newScraped(t *testing.T, status int, body string, mc *api.MetricsCollectorConf) (*PrometheusCollector, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.Atoi(port)
+	mc.Kind = api.MetricsCollectorConf_PROMETHEUS
+	mc.Port = int32(p)
+	c, err := New(&api.StudyConfig{MetricsCollector: mc}, "study", "trial")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	SetHost(c, host)
+	return c.(*PrometheusCollector), ts.Close
+}
+
+func TestPrometheusCollect(t *testing.T) {
+	c, stop := newScraped(t, http.StatusOK, exposition, &api.MetricsCollectorConf{
+		PrometheusMetrics: map[string]string{
+			"accuracy": `model_accuracy{split="eval"}`,
+			"step":     "global_step",
+		},
+	})
+	defer stop()
+	es, err := c.Collect(nil, []string{"accuracy", "loss", "lr", "missing"})
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if len(es) != 1 {
+		t.Fatalf("Expected 1 evaluation log, got %v", es)
+	}
+	want := map[string]string{"accuracy": "0.91", "loss": "0.3"}
+	if len(es[0].Metrics) != len(want) {
+		t.Errorf("Expected metrics %v, got %v", want, es[0].Metrics)
+	}
+	for _, m := range es[0].Metrics {
+		if want[m.Name] != m.Value {
+			t.Errorf("Expected %v=%v, got %v", m.Name, want[m.Name], m.Value)
+		}
+	}
+	if es[0].Step != 120 {
+		t.Errorf("Expected step 120, got %v", es[0].Step)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, es[0].Time); err != nil {
+		t.Errorf("Invalid time %v: %v", es[0].Time, err)
+	}
+	v, err := ObjectiveValue(es, "accuracy")
+	if err != nil || v != "0.91" {
+		t.Errorf("Expected objective value 0.91, got %v %v", v, err)
+	}
+
+	// the next scrape waits for the scrape interval
+	es, err = c.Collect(nil, []string{"accuracy"})
+	if err != nil || len(es) != 0 {
+		t.Errorf("Expected no scrape within the interval, got %v %v", es, err)
+	}
+	c.last = time.Now().Add(-c.Interval)
+	es, err = c.Collect(nil, []string{"accuracy"})
+	if err != nil || len(es) != 1 {
+		t.Errorf("Expected a scrape after the interval, got %v %v", es, err)
+	}
+}
+
+func TestPrometheusCollectErrors(t *testing.T) {
+	c, stop := newScraped(t, http.StatusInternalServerError, "", &api.MetricsCollectorConf{})
+	defer stop()
+	if _, err := c.Collect(nil, []string{"loss"}); err == nil {
+		t.Errorf("Expected an error for status 500")
+	}
+
+	c, stop = newScraped(t, http.StatusOK, "loss{split=train} 0.3\n", &api.MetricsCollectorConf{})
+	defer stop()
+	if _, err := c.Collect(nil, []string{"loss"}); err == nil {
+		t.Errorf("Expected an error for an unquoted label")
+	}
+
+	// the trial has no address yet
+	c, stop = newScraped(t, http.StatusOK, exposition, &api.MetricsCollectorConf{})
+	defer stop()
+	c.SetHost("")
+	if es, err := c.Collect(nil, []string{"loss"}); err != nil || len(es) != 0 {
+		t.Errorf("Expected no scrape without a host, got %v %v", es, err)
+	}
+}
+
+func TestNewPrometheusCollector(t *testing.T) {
+	for _, mc := range []*api.MetricsCollectorConf{
+		{Kind: api.MetricsCollectorConf_PROMETHEUS},
+		{Kind: api.MetricsCollectorConf_PROMETHEUS, Port: 8080, PrometheusMetrics: map[string]string{"accuracy": `acc{split="eval"`}},
+		{Kind: api.MetricsCollectorConf_PROMETHEUS, Port: 8080, PrometheusMetrics: map[string]string{"accuracy": "acc extra"}},
+	} {
+		if _, err := New(&api.StudyConfig{MetricsCollector: mc}, "", ""); err == nil {
+			t.Errorf("Expected an error for %v", mc)
+		}
+	}
+	mc := &api.MetricsCollectorConf{Kind: api.MetricsCollectorConf_PROMETHEUS, Port: 8080, Path: "stats", ScrapeInterval: 30}
+	c, err := NewPrometheusCollector(mc)
+	if err != nil {
+		t.Fatalf("NewPrometheusCollector: %v", err)
+	}
+	if c.Path != "/stats" || c.Interval != 30*time.Second || c.StepName != "step" {
+		t.Errorf("Unexpected collector %+v", c)
+	}
+}
@@ -0,0 +1,60 @@
+package metricscollector
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"regexp"
+	"strconv"
+)
+
+// RegexCollector reads the metrics from the matches of a regular expression with the named groups name and value,
+// and optionally step, e.g. `(?P<name>\w+): (?P<value>[-+.\deE]+)`.
+type RegexCollector struct {
+	re    *regexp.Regexp
+	name  int
+	value int
+	step  int
+}
+
+func NewRegexCollector(expr string) (*RegexCollector, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid metrics regex %q: %v", expr, err))
+	}
+	c := &RegexCollector{re: re, name: -1, value: -1, step: -1}
+	for i, n := range re.SubexpNames() {
+		switch n {
+		case "name":
+			c.name = i
+		case "value":
+			c.value = i
+		case "step":
+			c.step = i
+		}
+	}
+	if c.name < 0 || c.value < 0 {
+		return nil, errors.New(fmt.Sprintf("Metrics regex %q has no groups named name and value", expr))
+	}
+	return c, nil
+}
+
+func (c *RegexCollector) Collect(lines []Line, metrics []string) ([]*api.EvaluationLog, error) {
+	return collectLines(lines, metrics, c.parse), nil
+}
+
+func (c *RegexCollector) parse(text string, metrics []string) ([]*api.Metrics, int64) {
+	var ret []*api.Metrics
+	var step int64
+	for _, m := range c.re.FindAllStringSubmatch(text, -1) {
+		if c.step >= 0 {
+			if s, err := strconv.ParseInt(m[c.step], 10, 64); err == nil {
+				step = s
+			}
+		}
+		if isMetric(m[c.name], metrics) {
+			ret = append(ret, &api.Metrics{Name: m[c.name], Value: m[c.value]})
+		}
+	}
+	return ret, step
+}
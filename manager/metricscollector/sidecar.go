@@ -0,0 +1,147 @@
+package metricscollector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The trial container writes its output to SidecarOutput in SidecarDir, a volume shared with the sidecar,
+// and its exit code to SidecarExitCode once the output is written.
+const (
+	SidecarDir      = "/var/log/katib"
+	SidecarOutput   = "output.log"
+	SidecarExitCode = "exit-code"
+)
+
+// flushRetries is how many times the sidecar retries to send the last metrics after the trial exits.
+const flushRetries = 10
+
+// Sidecar collects the metrics of a trial next to it, e.g. in its pod, and sends them to the manager
+// with AddMeasurementToTrials. When the trial exits, it sends the last metrics and calls CompleteTrial.
+type Sidecar struct {
+	Manager  api.ManagerClient
+	StudyId  string
+	TrialId  string
+	Config   *api.StudyConfig
+	Dir      string
+	Interval time.Duration
+}
+
+// Run collects and sends the metrics every Interval until the trial exits, or stop is closed
+// because the trial is being deleted. The metrics which could not be sent are sent again later.
+func (s *Sidecar) Run(stop <-chan struct{}) error {
+	mc, err := New(s.Config, s.StudyId, s.TrialId)
+	if err != nil {
+		return err
+	}
+	switch s.Config.MetricsCollector.GetKind() {
+	case api.MetricsCollectorConf_KEY_VALUE, api.MetricsCollectorConf_REGEX, api.MetricsCollectorConf_JSON_LINES:
+		// the output of the trial instead of its logs
+		mc = &FileCollector{Path: filepath.Join(s.Dir, SidecarOutput), Format: mc}
+	}
+	// the sidecar shares the network of the trial
+	SetHost(mc, "localhost")
+	metrics := WithObjective(s.Config.Metrics, s.Config.ObjectiveValueName)
+	var pending []*api.EvaluationLog
+	tk := time.NewTicker(s.Interval)
+	defer tk.Stop()
+	for {
+		// read the exit code before the metrics, so that no metrics written before the exit are missed
+		code, exited := s.exitCode()
+		if !exited || !Scrapes(s.Config) {
+			es, err := mc.Collect(nil, metrics)
+			if err != nil {
+				log.Printf("Error collecting metrics of %v: %v", s.TrialId, err)
+			}
+			pending = append(pending, es...)
+		}
+		if exited {
+			log.Printf("Trial %v exited with %v", s.TrialId, code)
+			return s.complete(pending, code == 0)
+		}
+		pending = s.send(pending)
+		select {
+		case <-tk.C:
+		case <-stop:
+			if s.flush(pending) {
+				return nil
+			}
+			return errors.New(fmt.Sprintf("Failed to send the metrics of %v", s.TrialId))
+		}
+	}
+}
+
+// exitCode returns the exit code of the trial, and whether it has exited.
+func (s *Sidecar) exitCode() (int, bool) {
+	b, err := ioutil.ReadFile(filepath.Join(s.Dir, SidecarExitCode))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading the exit code of %v: %v", s.TrialId, err)
+		}
+		return 0, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		// not a number, e.g. killed by a signal
+		return -1, true
+	}
+	return code, true
+}
+
+// send sends the evaluation logs to the manager, and returns them if they could not be sent.
+func (s *Sidecar) send(els []*api.EvaluationLog) []*api.EvaluationLog {
+	if len(els) == 0 {
+		return nil
+	}
+	_, err := s.Manager.AddMeasurementToTrials(context.Background(), &api.AddMeasurementToTrialsRequest{
+		StudyId:  s.StudyId,
+		TrialId:  s.TrialId,
+		EvalLogs: els,
+	})
+	if err != nil {
+		log.Printf("Error sending metrics of %v: %v", s.TrialId, err)
+		return els
+	}
+	return nil
+}
+
+// flush retries to send the evaluation logs, and reports whether they were sent.
+func (s *Sidecar) flush(els []*api.EvaluationLog) bool {
+	for i := 0; i < flushRetries; i++ {
+		if els = s.send(els); len(els) == 0 {
+			return true
+		}
+		time.Sleep(s.Interval)
+	}
+	return false
+}
+
+// complete sends the last evaluation logs and tells the manager that the trial has ended.
+func (s *Sidecar) complete(els []*api.EvaluationLog, isComplete bool) error {
+	if !s.flush(els) {
+		return errors.New(fmt.Sprintf("Failed to send the metrics of %v", s.TrialId))
+	}
+	var err error
+	for i := 0; i < flushRetries; i++ {
+		_, err = s.Manager.CompleteTrial(context.Background(), &api.CompleteTrialRequest{
+			StudyId:    s.StudyId,
+			TrialId:    s.TrialId,
+			IsComplete: isComplete,
+		})
+		if err == nil {
+			return nil
+		}
+		log.Printf("Error completing %v: %v", s.TrialId, err)
+		time.Sleep(s.Interval)
+	}
+	return err
+}
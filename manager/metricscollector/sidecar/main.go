@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"github.com/golang/protobuf/jsonpb"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/manager/metricscollector"
+)
+
+var manager = flag.String("m", "vizier-core.katib:6789", "vizier-core address")
+var studyId = flag.String("s", "", "Study ID")
+var trialId = flag.String("t", "", "Trial ID")
+var config = flag.String("c", "{}", "Study config in JSON, with the objective value name, the metrics, the metrics collector and the mount")
+var dir = flag.String("d", metricscollector.SidecarDir, "Directory of the output and the exit code of the trial")
+var interval = flag.Duration("i", time.Second, "Interval of collecting the metrics")
+
+// The sidecar always exits successfully, so that the pod of the trial succeeds or fails with the trial.
+func main() {
+	flag.Parse()
+	sc := &pb.StudyConfig{}
+	if err := jsonpb.UnmarshalString(*config, sc); err != nil {
+		log.Printf("Invalid study config: %v", err)
+		return
+	}
+	conn, err := grpc.Dial(*manager, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("could not connect: %v", err)
+		return
+	}
+	defer conn.Close()
+	stop := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		close(stop)
+	}()
+	s := &metricscollector.Sidecar{
+		Manager:  pb.NewManagerClient(conn),
+		StudyId:  *studyId,
+		TrialId:  *trialId,
+		Config:   sc,
+		Dir:      *dir,
+		Interval: *interval,
+	}
+	if err := s.Run(stop); err != nil {
+		log.Printf("Metrics collector of %v: %v", *trialId, err)
+	}
+}
@@ -0,0 +1,118 @@
+package metricscollector
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/mlkube/katib/api"
+)
+
+// fakeManager records the metrics and the completion sent by a sidecar, and fails the first failures calls.
+type fakeManager struct {
+	api.ManagerClient
+	mux      sync.Mutex
+	failures int
+	els      []*api.EvaluationLog
+	complete *api.CompleteTrialRequest
+}
+
+func (m *fakeManager) AddMeasurementToTrials(ctx context.Context, in *api.AddMeasurementToTrialsRequest, opts ...grpc.CallOption) (*api.AddMeasurementToTrialsReply, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.failures > 0 {
+		m.failures--
+		return nil, errors.New("unavailable")
+	}
+	m.els = append(m.els, in.EvalLogs...)
+	return &api.AddMeasurementToTrialsReply{}, nil
+}
+
+func (m *fakeManager) CompleteTrial(ctx context.Context, in *api.CompleteTrialRequest, opts ...grpc.CallOption) (*api.CompleteTrialReply, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.complete = in
+	return &api.CompleteTrialReply{}, nil
+}
+
+func TestSidecarRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	m := &fakeManager{failures: 1}
+	s := &Sidecar{
+		Manager:  m,
+		StudyId:  "study",
+		TrialId:  "trial",
+		Config:   &api.StudyConfig{ObjectiveValueName: "accuracy", Metrics: []string{"loss"}},
+		Dir:      dir,
+		Interval: 10 * time.Millisecond,
+	}
+	done := make(chan error)
+	go func() { done <- s.Run(nil) }()
+
+	out, err := os.Create(filepath.Join(dir, SidecarOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	out.WriteString("step=1 loss=0.5 accuracy=0.8\n")
+	time.Sleep(50 * time.Millisecond)
+	// the last line is written just before the exit
+	out.WriteString("epoch done\nstep=2 loss=0.3 accuracy=0.9\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, SidecarExitCode), []byte("0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The sidecar did not exit with the trial")
+	}
+
+	if m.complete == nil || !m.complete.IsComplete || m.complete.StudyId != "study" || m.complete.TrialId != "trial" {
+		t.Errorf("Expected the trial to be completed, got %v", m.complete)
+	}
+	if len(m.els) != 2 {
+		t.Fatalf("Expected 2 evaluation logs, got %v", m.els)
+	}
+	for i, el := range m.els {
+		if el.Step != int64(i+1) || len(el.Metrics) != 2 {
+			t.Errorf("Unexpected evaluation log %v", el)
+		}
+	}
+	v, err := ObjectiveValue(m.els, "accuracy")
+	if err != nil || v != "0.9" {
+		t.Errorf("Expected objective value 0.9, got %v %v", v, err)
+	}
+}
+
+func TestSidecarFailedTrial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, SidecarExitCode), []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &fakeManager{}
+	s := &Sidecar{Manager: m, Config: &api.StudyConfig{ObjectiveValueName: "loss"}, Dir: dir, Interval: 10 * time.Millisecond}
+	if err := s.Run(nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if m.complete == nil || m.complete.IsComplete {
+		t.Errorf("Expected the trial to fail, got %v", m.complete)
+	}
+}
@@ -0,0 +1,322 @@
+package metricscollector
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"hash/crc32"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Data types of TensorProto
+const (
+	dtFloat  = 1
+	dtDouble = 2
+	dtInt32  = 3
+	dtInt64  = 9
+)
+
+var (
+	crc32c          = crc32.MakeTable(crc32.Castagnoli)
+	errInvalidProto = errors.New("invalid protobuf message")
+	// errDataChecksum is the error of a record whose length is valid, so that it can be skipped.
+	errDataChecksum = errors.New("data checksum mismatch")
+)
+
+// TFEventCollector reads the scalar summaries of the TensorBoard event files in Dir and its subdirectories.
+// The tags of the summaries are the names of the metrics, and each event is an EvaluationLog with its wall time and step.
+type TFEventCollector struct {
+	Dir string
+	// offsets is the size of the records already read of each file
+	offsets map[string]int64
+	// failed is the offset of each file where an error was reported, so that it is reported once
+	failed map[string]int64
+}
+
+type scalarEvent struct {
+	wallTime float64
+	el       *api.EvaluationLog
+}
+
+func (c *TFEventCollector) Collect(lines []Line, metrics []string) ([]*api.EvaluationLog, error) {
+	if c.offsets == nil {
+		c.offsets = make(map[string]int64)
+		c.failed = make(map[string]int64)
+	}
+	var files []string
+	err := filepath.Walk(c.Dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.Contains(info.Name(), "tfevents") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		// not written yet
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var evs []*scalarEvent
+	for _, f := range files {
+		fe, offset, err := readTFEventFile(f, c.offsets[f], metrics)
+		if err != nil {
+			// keep the events of the other files, and read the file again next time
+			if o, ok := c.failed[f]; !ok || o != offset {
+				log.Printf("Error reading %v: %v", f, err)
+				c.failed[f] = offset
+			}
+		}
+		c.offsets[f] = offset
+		evs = append(evs, fe...)
+	}
+	// the events of train and eval are in different files
+	sort.SliceStable(evs, func(i, j int) bool { return evs[i].wallTime < evs[j].wallTime })
+	ret := make([]*api.EvaluationLog, len(evs))
+	for i, e := range evs {
+		ret[i] = e.el
+	}
+	return ret, nil
+}
+
+// readTFEventFile reads the scalar summaries of metrics in the records of an event file after offset,
+// and returns them with the offset after the last complete record.
+func readTFEventFile(name string, offset int64, metrics []string) ([]*scalarEvent, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+	r := bufio.NewReader(f)
+	var ret []*scalarEvent
+	for {
+		data, err := readTFRecord(r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// the last record may be being written
+			return ret, offset, nil
+		} else if err == errDataChecksum {
+			log.Printf("Skipping a corrupt record at %v of %v", offset, name)
+			offset += int64(len(data)) + 16
+			continue
+		} else if err != nil {
+			// the length of the record is not known, so the rest of the file can not be read
+			return ret, offset, errors.New(fmt.Sprintf("Invalid event file %v at %v: %v", name, offset, err))
+		}
+		offset += int64(len(data)) + 16
+		e, err := parseEvent(data, metrics)
+		if err != nil {
+			// skip it
+			log.Printf("Invalid event in %v: %v", name, err)
+		} else if e != nil {
+			ret = append(ret, e)
+		}
+	}
+}
+
+// readTFRecord reads a record of the TFRecord format: the length, its masked crc32c, the data and its masked crc32c.
+// A record whose data does not match its checksum is returned with errDataChecksum.
+func readTFRecord(r io.Reader) ([]byte, error) {
+	var h [12]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, err
+	}
+	if maskedCRC(h[:8]) != binary.LittleEndian.Uint32(h[8:]) {
+		return nil, errors.New("length checksum mismatch")
+	}
+	n := binary.LittleEndian.Uint64(h[:8])
+	data := make([]byte, n+4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if maskedCRC(data[:n]) != binary.LittleEndian.Uint32(data[n:]) {
+		return data[:n], errDataChecksum
+	}
+	return data[:n], nil
+}
+
+func maskedCRC(b []byte) uint32 {
+	c := crc32.Checksum(b, crc32c)
+	return (c>>15 | c<<17) + 0xa282ead8
+}
+
+// parseEvent returns the wall time, the step and the scalar summaries of metrics of an Event, or nil if it has none.
+func parseEvent(data []byte, metrics []string) (*scalarEvent, error) {
+	e := &scalarEvent{el: &api.EvaluationLog{}}
+	err := protoFields(data, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			e.wallTime = math.Float64frombits(v)
+		case 2:
+			e.el.Step = int64(v)
+		case 5:
+			// Summary
+			return protoFields(b, func(num int, _ uint64, b []byte) error {
+				if num != 1 {
+					return nil
+				}
+				m, err := parseSummaryValue(b)
+				if err == nil && m != nil && isMetric(m.Name, metrics) {
+					e.el.Metrics = append(e.el.Metrics, m)
+				}
+				return err
+			})
+		}
+		return nil
+	})
+	if err != nil || len(e.el.Metrics) == 0 {
+		return nil, err
+	}
+	sec, frac := math.Modf(e.wallTime)
+	e.el.Time = time.Unix(int64(sec), int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+	return e, nil
+}
+
+// parseSummaryValue returns the tag and the value of a Summary.Value with simple_value or a scalar tensor.
+func parseSummaryValue(data []byte) (*api.Metrics, error) {
+	var tag, value string
+	err := protoFields(data, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			tag = string(b)
+		case 2:
+			value = strconv.FormatFloat(float64(math.Float32frombits(uint32(v))), 'g', -1, 32)
+		case 8:
+			var err error
+			value, err = parseScalarTensor(b)
+			return err
+		}
+		return nil
+	})
+	if err != nil || tag == "" || value == "" {
+		return nil, err
+	}
+	return &api.Metrics{Name: tag, Value: value}, nil
+}
+
+// parseScalarTensor returns the value of a TensorProto with a single float, double or integer, and "" otherwise.
+func parseScalarTensor(data []byte) (string, error) {
+	var dtype uint64
+	var content []byte
+	var values []string
+	err := protoFields(data, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			dtype = v
+		case 4:
+			content = b
+		case 5:
+			// float_val, packed or not
+			if b == nil {
+				values = append(values, strconv.FormatFloat(float64(math.Float32frombits(uint32(v))), 'g', -1, 32))
+			}
+			for ; len(b) >= 4; b = b[4:] {
+				values = append(values, strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32))
+			}
+		case 6:
+			// double_val
+			if b == nil {
+				values = append(values, strconv.FormatFloat(math.Float64frombits(v), 'g', -1, 64))
+			}
+			for ; len(b) >= 8; b = b[8:] {
+				values = append(values, strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64))
+			}
+		case 7, 10:
+			// int_val and int64_val
+			if b == nil {
+				values = append(values, strconv.FormatInt(int64(v), 10))
+			}
+			for len(b) > 0 {
+				x, n := binary.Uvarint(b)
+				if n <= 0 {
+					return errInvalidProto
+				}
+				values = append(values, strconv.FormatInt(int64(x), 10))
+				b = b[n:]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case dtype == dtFloat && len(content) == 4:
+		values = append(values, strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(content))), 'g', -1, 32))
+	case dtype == dtDouble && len(content) == 8:
+		values = append(values, strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(content)), 'g', -1, 64))
+	case dtype == dtInt32 && len(content) == 4:
+		values = append(values, strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(content))), 10))
+	case dtype == dtInt64 && len(content) == 8:
+		values = append(values, strconv.FormatInt(int64(binary.LittleEndian.Uint64(content)), 10))
+	}
+	// histograms, images and other tensors are not metrics
+	if len(values) != 1 {
+		return "", nil
+	}
+	return values[0], nil
+}
+
+// protoFields calls f with each field of a protobuf message: the value of varint and fixed fields in v,
+// and the bytes of length-delimited fields in b.
+func protoFields(msg []byte, f func(num int, v uint64, b []byte) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errInvalidProto
+		}
+		msg = msg[n:]
+		var v uint64
+		var b []byte
+		switch key & 7 {
+		case 0:
+			v, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errInvalidProto
+			}
+			msg = msg[n:]
+		case 1:
+			if len(msg) < 8 {
+				return errInvalidProto
+			}
+			v = binary.LittleEndian.Uint64(msg)
+			msg = msg[8:]
+		case 2:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || l > uint64(len(msg)-n) {
+				return errInvalidProto
+			}
+			b = msg[n : n+int(l)]
+			msg = msg[n+int(l):]
+		case 5:
+			if len(msg) < 4 {
+				return errInvalidProto
+			}
+			v = uint64(binary.LittleEndian.Uint32(msg))
+			msg = msg[4:]
+		default:
+			return errInvalidProto
+		}
+		if err := f(int(key>>3), v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package metricscollector
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Protobuf and TFRecord encoding of the events written by TensorFlow.
+
+func uvarint(v uint64) []byte {
+	b := make([]byte, binary.MaxVarintLen64)
+	return b[:binary.PutUvarint(b, v)]
+}
+
+func fixed32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func fixed64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+func pbKey(num int, wire int) []byte {
+	return uvarint(uint64(num<<3 | wire))
+}
+
+func pbVarint(num int, v uint64) []byte {
+	return append(pbKey(num, 0), uvarint(v)...)
+}
+
+func pbDouble(num int, v float64) []byte {
+	return append(pbKey(num, 1), fixed64(math.Float64bits(v))...)
+}
+
+func pbFloat(num int, v float32) []byte {
+	return append(pbKey(num, 5), fixed32(math.Float32bits(v))...)
+}
+
+func pbBytes(num int, fields ...[]byte) []byte {
+	var b []byte
+	for _, f := range fields {
+		b = append(b, f...)
+	}
+	return append(append(pbKey(num, 2), uvarint(uint64(len(b)))...), b...)
+}
+
+func tfRecord(data []byte) []byte {
+	r := fixed64(uint64(len(data)))
+	r = append(r, fixed32(maskedCRC(r))...)
+	r = append(r, data...)
+	return append(r, fixed32(maskedCRC(data))...)
+}
+
+// event is an Event record with the summary values.
+func event(wallTime float64, step uint64, values ...[]byte) []byte {
+	return tfRecord(append(append(pbDouble(1, wallTime), pbVarint(2, step)...), pbBytes(5, values...)...))
+}
+
+// simpleValue is a Summary.Value with simple_value.
+func simpleValue(tag string, v float32) []byte {
+	return pbBytes(1, pbBytes(1, []byte(tag)), pbFloat(2, v))
+}
+
+// tensorValue is a Summary.Value with a float tensor of packed float_val.
+func tensorValue(tag string, vs ...float32) []byte {
+	var packed []byte
+	for _, v := range vs {
+		packed = append(packed, fixed32(math.Float32bits(v))...)
+	}
+	return pbBytes(1, pbBytes(1, []byte(tag)), pbBytes(8, pbVarint(1, dtFloat), pbBytes(5, packed)))
+}
+
+func TestTFEventCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfevent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &TFEventCollector{Dir: dir}
+	metrics := []string{"loss", "accuracy"}
+	collect := func(want ...string) {
+		es, err := c.Collect(nil, metrics)
+		if err != nil {
+			t.Fatalf("Collect: %v", err)
+		}
+		if got := evalLogs(es); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+	// not written yet
+	collect()
+
+	if err := os.Mkdir(filepath.Join(dir, "eval"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	train := filepath.Join(dir, "events.out.tfevents.1.host")
+	eval := filepath.Join(dir, "eval", "events.out.tfevents.2.host")
+	// the first event of a file has the version, and the other files are not event files
+	ioutil.WriteFile(filepath.Join(dir, "checkpoint"), []byte("not an event file"), 0644)
+	ioutil.WriteFile(eval, event(1000.5, 1, tensorValue("accuracy", 0.75), tensorValue("histogram", 1, 2)), 0644)
+	version := tfRecord(append(pbDouble(1, 999), pbBytes(3, []byte("brain.Event:2"))...))
+	second := event(1001, 2, simpleValue("loss", 0.25), simpleValue("lr", 0.1))
+	records := append(append(version, event(1000, 1, simpleValue("loss", 0.5))...), second...)
+	// the last record is being written
+	ioutil.WriteFile(train, records[:len(records)-5], 0644)
+	collect("1:loss=0.5", "1:accuracy=0.75")
+	es, _ := (&TFEventCollector{Dir: dir}).Collect(nil, metrics)
+	if len(es) != 2 || es[0].Time != "1970-01-01T00:16:40Z" || es[1].Time != "1970-01-01T00:16:40.5Z" {
+		t.Errorf("Unexpected times of %v", es)
+	}
+
+	// resumed from the offset of the last complete record
+	f, err := os.OpenFile(train, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Write(records[len(records)-5:])
+	collect("2:loss=0.25")
+	collect()
+
+	// a corrupt record is skipped once, and the next records are read
+	corrupt := event(1002, 3, simpleValue("loss", 0.2))
+	corrupt[len(corrupt)-6] ^= 0xff
+	f.Write(corrupt)
+	f.Write(event(1003, 4, simpleValue("loss", 0.1)))
+	collect("4:loss=0.1")
+	collect()
+
+	// a corrupt length stops the reading of the file, but not of the others
+	bad := event(1004, 5, simpleValue("loss", 0.05))
+	bad[0] ^= 0xff
+	f.Write(bad)
+	ef, err := os.OpenFile(eval, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ef.Close()
+	ef.Write(event(1005, 6, tensorValue("accuracy", 0.8)))
+	collect("6:accuracy=0.8")
+	if c.failed[train] != c.offsets[train] {
+		t.Errorf("Expected the error at %v to be reported, got %v", c.offsets[train], c.failed)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"github.com/mlkube/katib/api"
 	"github.com/mlkube/katib/db"
+	"github.com/mlkube/katib/manager/metricscollector"
 	"github.com/mlkube/katib/manager/modeldb"
+	"github.com/mlkube/katib/manager/worker_interface"
 	dlkapi "github.com/osrg/dlk/dlkmanager/api"
 	"github.com/osrg/dlk/dlkmanager/datastore"
 	"io/ioutil"
@@ -27,6 +29,7 @@ type DlkWorkerInterface struct {
 	namespace          string
 	mux                *sync.Mutex
 	dbIf               db.VizierDBInterface
+	collectors         *metricscollector.Collectors
 }
 
 func NewDlkWorkerInterface(s string, n string) *DlkWorkerInterface {
@@ -37,6 +40,7 @@ func NewDlkWorkerInterface(s string, n string) *DlkWorkerInterface {
 		namespace:          n,
 		mux:                new(sync.Mutex),
 		dbIf:               db.New(),
+		collectors:         metricscollector.NewCollectors(),
 	}
 }
 
@@ -95,53 +99,47 @@ func (d *DlkWorkerInterface) IsTrialComplete(studyId string, tID string) (bool,
 	return false, nil
 }
 
-func (d *DlkWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
-	ltlogs, _ := d.getLtLogs(tID, "")
-	for _, pl := range ltlogs.PodLogs {
-		for i := len(pl.Logs) - 1; i >= 0; i-- {
-			ls := strings.Fields(pl.Logs[i].Value)
-			for _, l := range ls {
-				v := strings.Split(l, "=")
-				if v[0] == objname {
-					if len(v) > 1 {
-						return v[1], nil
-					}
-				}
-			}
-		}
-	}
-	return "", errors.New(fmt.Sprintf("No Objective Value Name %v  is found in log", objname))
-}
-func (d *DlkWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
-	var ret []*api.EvaluationLog
-	ltlogs, err := d.getLtLogs(tID, sinceTime)
+// ltLines returns the log lines of the worker of the trial since stime.
+func (d *DlkWorkerInterface) ltLines(tID string, stime string) ([]metricscollector.Line, error) {
+	ltlogs, err := d.getLtLogs(tID, stime)
 	if err != nil {
 		return nil, err
-	} else if ltlogs == nil {
-		return ret, nil
 	}
+	var ret []metricscollector.Line
 	for _, pl := range ltlogs.PodLogs {
 		for _, l := range pl.Logs {
 			if l.Value != "" {
-				lsf := strings.Fields(l.Value)
-				e := &api.EvaluationLog{Time: l.Time}
-				for _, l := range lsf {
-					v := strings.Split(l, "=")
-					for _, m := range metrics {
-						if v[0] == m && len(v) > 1 {
-							e.Metrics = append(e.Metrics, &api.Metrics{Name: m, Value: v[1]})
-						}
-					}
-				}
-				if len(e.Metrics) > 0 {
-					ret = append(ret, e)
-				}
+				ret = append(ret, metricscollector.Line{Time: l.Time, Text: l.Value})
 			}
 		}
 	}
 	return ret, nil
 }
 
+func (d *DlkWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
+	es, err := d.GetTrialEvLogs(studyId, tID, []string{objname}, "")
+	if err != nil {
+		return "", err
+	}
+	return metricscollector.ObjectiveValue(es, objname)
+}
+
+func (d *DlkWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
+	sc, err := d.dbIf.GetStudyConfig(studyId)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := metricscollector.New(sc, studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := d.ltLines(tID, sinceTime)
+	if err != nil {
+		return nil, err
+	}
+	return mc.Collect(lines, metrics)
+}
+
 func (d *DlkWorkerInterface) CheckRunningTrials(studyId string, objname string, metrics []string) error {
 	d.mux.Lock()
 	defer d.mux.Unlock()
@@ -157,19 +155,20 @@ func (d *DlkWorkerInterface) CheckRunningTrials(studyId string, objname string,
 		}
 		if status == api.TrialState_RUNNING {
 			c, _ := d.IsTrialComplete(studyId, t.TrialId)
-			var es []*api.EvaluationLog
-			if len(t.EvalLogs) == 0 {
-				es, err = d.GetTrialEvLogs(studyId, t.TrialId, metrics, "")
-			} else {
-				es, err = d.GetTrialEvLogs(studyId, t.TrialId, metrics, t.EvalLogs[len(t.EvalLogs)-1].Time)
+			var since string
+			if len(t.EvalLogs) > 0 {
+				since = t.EvalLogs[len(t.EvalLogs)-1].Time
 			}
+			lines, err := d.ltLines(t.TrialId, since)
 			if err != nil {
 				log.Printf("GetTrialEvLogs Err %v", err)
 				return err
 			}
-			if len(es) > 0 {
-				t.EvalLogs = append(t.EvalLogs, es...)
+			es, err := d.collectors.Collect(sc, studyId, t.TrialId, lines, metrics)
+			if err != nil {
+				log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
 			}
+			t.EvalLogs = append(t.EvalLogs, es...)
 			if c {
 				o, _ := d.GetTrialObjValue(studyId, t.TrialId, objname)
 				t.ObjectiveValue = o
@@ -221,6 +220,7 @@ func (d *DlkWorkerInterface) CheckRunningTrials(studyId string, objname string,
 				mif.SendReq(mr)
 				log.Printf("Trial %v is completed.", t.TrialId)
 				log.Printf("Objective Value: %v", t.ObjectiveValue)
+				d.collectors.Delete(t.TrialId)
 				d.CompletedTrialList[studyId] = append(d.CompletedTrialList[studyId], t)
 				if len(d.RunningTrialList[studyId]) <= 1 {
 					d.RunningTrialList[studyId] = []*api.Trial{}
@@ -238,23 +238,41 @@ func (d *DlkWorkerInterface) CheckRunningTrials(studyId string, objname string,
 	}
 	return nil
 }
-func (d *DlkWorkerInterface) convertTrialToManifest(trials []*api.Trial, studyId string) []*dlkapi.LTConfig {
+func (d *DlkWorkerInterface) convertTrialToManifest(trials []*api.Trial, studyId string) ([]*dlkapi.LTConfig, error) {
 	sc, _ := d.dbIf.GetStudyConfig(studyId)
 	ret := make([]*dlkapi.LTConfig, len(trials))
+	// dlk-manager only takes the number of GPUs of a learning task
+	if r := sc.Resources; r != nil && (len(r.Requests) > 0 || len(r.Limits) > 0 || len(r.NodeSelector) > 0 || len(r.Tolerations) > 0 || r.Affinity != "") {
+		return nil, errors.New(fmt.Sprintf("Resources of Study %v are not supported by the dlk worker", studyId))
+	}
+	if sc.ParameterInjection != nil && sc.ParameterInjection.Mode == api.ParameterInjection_FILE {
+		return nil, errors.New(fmt.Sprintf("Parameter injection mode %v of Study %v is not supported by the dlk worker", sc.ParameterInjection.Mode, studyId))
+	}
+	if metricscollector.Scrapes(sc) {
+		return nil, errors.New(fmt.Sprintf("Metrics collector %v of Study %v is not supported by the dlk worker", sc.MetricsCollector.Kind, studyId))
+	}
 	command := strings.Join(sc.Command, " ")
 	d.mux.Lock()
 	defer d.mux.Unlock()
 	for i, t := range trials {
-		d.RunningTrialList[studyId] = append(d.RunningTrialList[studyId], t)
+		c, err := worker_interface.ExpandCommand(command, sc, studyId, t)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid command of Study %v: %v", studyId, err))
+		}
+		pargs, err := worker_interface.ParameterArgs(sc, t)
+		if err != nil {
+			return nil, err
+		}
 		var param string
-		for _, v := range t.ParameterSet {
-			param += " " + v.Name + "=" + v.Value
+		for _, v := range pargs {
+			param += " " + v
 		}
-		e := []dlkapi.EnvConf{
-			dlkapi.EnvConf{Name: "STUDY_ID", Value: studyId},
-			dlkapi.EnvConf{Name: "TRIAL_ID", Value: t.TrialId},
+		e := []dlkapi.EnvConf{}
+		envs := append(worker_interface.TrialEnvs(sc.Mount, studyId, t), worker_interface.ParameterEnvs(sc, t)...)
+		for _, v := range envs {
+			e = append(e, dlkapi.EnvConf{Name: v.Name, Value: v.Value})
 		}
-		c := strings.Replace(strings.Replace(command, "{{STUDY_ID}}", studyId, -1), "{{TRIAL_ID}}", t.TrialId, -1)
+		d.RunningTrialList[studyId] = append(d.RunningTrialList[studyId], t)
 		var sched = "default-scheduler"
 		if sc.Scheduler != "" {
 			sched = sc.Scheduler
@@ -281,10 +299,13 @@ func (d *DlkWorkerInterface) convertTrialToManifest(trials []*api.Trial, studyId
 		}
 		ret[i] = j
 	}
-	return ret
+	return ret, nil
 }
 func (d *DlkWorkerInterface) SpawnWorkers(trials []*api.Trial, studyId string) error {
-	runp := d.convertTrialToManifest(trials, studyId)
+	runp, err := d.convertTrialToManifest(trials, studyId)
+	if err != nil {
+		return err
+	}
 	url := fmt.Sprintf("%s/learningTask", d.dlkmanager)
 	for _, j := range runp {
 		//encode json
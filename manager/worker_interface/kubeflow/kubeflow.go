@@ -0,0 +1,503 @@
+package kubeflow
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/db"
+	"github.com/mlkube/katib/manager/metricscollector"
+	"github.com/mlkube/katib/manager/worker_interface"
+	k8swif "github.com/mlkube/katib/manager/worker_interface/kubernetes"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replicaTypeLabel is set on the pods of each replica type, to find the pod which reports the metrics.
+const replicaTypeLabel = "katib-replica-type"
+
+// pollInterval is how often the job of a running trial is checked.
+const pollInterval = 10 * time.Second
+
+const defaultAPIVersion = "kubeflow.org/v1alpha2"
+
+type jobKind struct {
+	resource     string
+	replicaSpecs string
+	// container is the name of the container the operator runs the training in
+	container string
+	// masters are the replica types whose first pod reports the metrics, in order of preference
+	masters []string
+}
+
+var jobKinds = map[string]*jobKind{
+	"TFJob":      {resource: "tfjobs", replicaSpecs: "tfReplicaSpecs", container: "tensorflow", masters: []string{"Chief", "Master", "Worker"}},
+	"PyTorchJob": {resource: "pytorchjobs", replicaSpecs: "pytorchReplicaSpecs", container: "pytorch", masters: []string{"Master"}},
+	"MXJob":      {resource: "mxjobs", replicaSpecs: "mxReplicaSpecs", container: "mxnet", masters: []string{"Worker"}},
+}
+
+// study is what the worker needs of a study config, parsed once.
+type study struct {
+	namespace string
+	kind      *jobKind
+	gvr       schema.GroupVersionResource
+	master    string
+	template  *unstructured.Unstructured
+	config    *api.StudyConfig
+}
+
+// KubeflowWorkerInterface runs each trial as a distributed training job of a Kubeflow operator.
+// The job is built on StudyConfig.JobTemplate, a TFJob, PyTorchJob or MXJob manifest,
+// or is a TFJob with a single worker when the study has no template.
+type KubeflowWorkerInterface struct {
+	RunningTrialList   map[string][]*api.Trial
+	CompletedTrialList map[string][]*api.Trial
+	clientset          *kubernetes.Clientset
+	dynamic            dynamic.Interface
+	mux                *sync.Mutex
+	db                 db.VizierDBInterface
+	studies            map[string]*study
+	lastPoll           map[string]time.Time
+	// logSince is the time of the last log line stored for each trial
+	logSince   map[string]time.Time
+	collectors *metricscollector.Collectors
+}
+
+func NewKubeflowWorkerInterface(cs *kubernetes.Clientset, dc dynamic.Interface, db db.VizierDBInterface) *KubeflowWorkerInterface {
+	return &KubeflowWorkerInterface{
+		RunningTrialList:   make(map[string][]*api.Trial),
+		CompletedTrialList: make(map[string][]*api.Trial),
+		clientset:          cs,
+		dynamic:            dc,
+		mux:                new(sync.Mutex),
+		db:                 db,
+		studies:            make(map[string]*study),
+		lastPoll:           make(map[string]time.Time),
+		logSince:           make(map[string]time.Time),
+		collectors:         metricscollector.NewCollectors(),
+	}
+}
+
+func (d *KubeflowWorkerInterface) getStudy(studyId string) (*study, error) {
+	if s, ok := d.studies[studyId]; ok {
+		return s, nil
+	}
+	sc, err := d.db.GetStudyConfig(studyId)
+	if err != nil {
+		return nil, err
+	}
+	s := &study{namespace: worker_interface.StudyNamespace(sc), config: sc, template: &unstructured.Unstructured{}}
+	if sc.JobTemplate != "" {
+		err = k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(sc.JobTemplate), 1024).Decode(&s.template.Object)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid job template of Study %v: %v", studyId, err))
+		}
+	} else {
+		s.template.SetAPIVersion(defaultAPIVersion)
+		s.template.SetKind("TFJob")
+		s.template.Object["spec"] = map[string]interface{}{
+			"tfReplicaSpecs": map[string]interface{}{
+				"Worker": map[string]interface{}{"replicas": int64(1)},
+			},
+		}
+	}
+	s.kind = jobKinds[s.template.GetKind()]
+	if s.kind == nil {
+		return nil, errors.New(fmt.Sprintf("Unsupported kind %q in job template of Study %v", s.template.GetKind(), studyId))
+	}
+	gv, err := schema.ParseGroupVersion(s.template.GetAPIVersion())
+	if err != nil || gv.Group == "" {
+		return nil, errors.New(fmt.Sprintf("Invalid apiVersion %q in job template of Study %v", s.template.GetAPIVersion(), studyId))
+	}
+	s.gvr = gv.WithResource(s.kind.resource)
+	rs, _, _ := unstructured.NestedMap(s.template.Object, "spec", s.kind.replicaSpecs)
+	for _, m := range s.kind.masters {
+		if _, ok := rs[m]; ok {
+			s.master = m
+			break
+		}
+	}
+	if s.master == "" {
+		return nil, errors.New(fmt.Sprintf("Job template of Study %v has none of the replicas %v", studyId, s.kind.masters))
+	}
+	d.studies[studyId] = s
+	return s, nil
+}
+
+// convertTrialToManifest sets up the container of the operator in every replica of the job of each trial.
+func (d *KubeflowWorkerInterface) convertTrialToManifest(trials []*api.Trial, studyId string) ([]*unstructured.Unstructured, error) {
+	s, err := d.getStudy(studyId)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*unstructured.Unstructured, len(trials))
+	for i, t := range trials {
+		job := s.template.DeepCopy()
+		job.SetName(t.TrialId)
+		job.SetNamespace(s.namespace)
+		l := job.GetLabels()
+		if l == nil {
+			l = map[string]string{}
+		}
+		l[k8swif.StudyIdLabel] = studyId
+		l[k8swif.TrialIdLabel] = t.TrialId
+		job.SetLabels(l)
+		rs, _, err := unstructured.NestedMap(job.Object, "spec", s.kind.replicaSpecs)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid %v in job template of Study %v: %v", s.kind.replicaSpecs, studyId, err))
+		}
+		for rt, v := range rs {
+			r, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Invalid replica %v in job template of Study %v", rt, studyId))
+			}
+			var pt apiv1.PodTemplateSpec
+			if tm, ok := r["template"].(map[string]interface{}); ok {
+				err = runtime.DefaultUnstructuredConverter.FromUnstructured(tm, &pt)
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("Invalid pod template of replica %v of Study %v: %v", rt, studyId, err))
+				}
+			}
+			if pt.Labels == nil {
+				pt.Labels = map[string]string{}
+			}
+			pt.Labels[k8swif.StudyIdLabel] = studyId
+			pt.Labels[k8swif.TrialIdLabel] = t.TrialId
+			pt.Labels[replicaTypeLabel] = rt
+			ps := &pt.Spec
+			ci := -1
+			for j := range ps.Containers {
+				if ps.Containers[j].Name == s.kind.container {
+					ci = j
+					break
+				}
+			}
+			if ci < 0 {
+				ps.Containers = append(ps.Containers, apiv1.Container{Name: s.kind.container})
+				ci = len(ps.Containers) - 1
+			}
+			err = k8swif.SetupTrialPod(ps, &ps.Containers[ci], s.config, studyId, t)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid trial %v of Study %v: %v", t.TrialId, studyId, err))
+			}
+			r["template"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(&pt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = unstructured.SetNestedMap(job.Object, rs, "spec", s.kind.replicaSpecs)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = job
+	}
+	return ret, nil
+}
+
+// jobState reads the conditions of the job of the trial, and returns COMPLETED or ERROR when it has finished.
+func (d *KubeflowWorkerInterface) jobState(studyId string, tID string) (api.TrialState, error) {
+	s, err := d.getStudy(studyId)
+	if err != nil {
+		return api.TrialState_ERROR, err
+	}
+	job, err := d.dynamic.Resource(s.gvr).Namespace(s.namespace).Get(tID, metav1.GetOptions{})
+	if err != nil {
+		return api.TrialState_ERROR, err
+	}
+	conds, _, _ := unstructured.NestedSlice(job.Object, "status", "conditions")
+	for _, c := range conds {
+		cm, ok := c.(map[string]interface{})
+		if !ok || cm["status"] != "True" {
+			continue
+		}
+		switch cm["type"] {
+		case "Succeeded":
+			return api.TrialState_COMPLETED, nil
+		case "Failed":
+			return api.TrialState_ERROR, nil
+		}
+	}
+	return api.TrialState_RUNNING, nil
+}
+
+// getMasterPod returns the first pod of the replica which reports the metrics.
+func (d *KubeflowWorkerInterface) getMasterPod(studyId string, tID string) (*apiv1.Pod, error) {
+	s, err := d.getStudy(studyId)
+	if err != nil {
+		return nil, err
+	}
+	sel := labels.SelectorFromSet(labels.Set{k8swif.TrialIdLabel: tID, replicaTypeLabel: s.master})
+	pl, err := d.clientset.CoreV1().Pods(s.namespace).List(metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return nil, err
+	}
+	if len(pl.Items) == 0 {
+		return nil, errors.New(fmt.Sprintf("No %v Pods are found in Job %v", s.master, tID))
+	}
+	// the operators name the pods <job>-<replica type>-<index>
+	sort.Slice(pl.Items, func(i, j int) bool { return pl.Items[i].Name < pl.Items[j].Name })
+	return &pl.Items[0], nil
+}
+
+// setHost gives the IP of the master to the collector of the trial while the master runs.
+func (d *KubeflowWorkerInterface) setHost(sc *api.StudyConfig, studyId string, tID string) error {
+	pod, err := d.getMasterPod(studyId, tID)
+	if err != nil {
+		return err
+	}
+	return d.collectors.SetHost(sc, studyId, tID, k8swif.PodHost(pod))
+}
+
+// fetchLogs stores the log lines the master wrote since the last fetch in the DB, and returns them.
+func (d *KubeflowWorkerInterface) fetchLogs(studyId string, tID string) ([]string, error) {
+	pod, err := d.getMasterPod(studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase == apiv1.PodPending || pod.Status.Phase == apiv1.PodUnknown {
+		return nil, nil
+	}
+	since, ok := d.logSince[tID]
+	if !ok {
+		mt, err := d.db.GetTrialTimestamp(tID)
+		if err != nil {
+			return nil, err
+		}
+		if mt != nil {
+			since = *mt
+		}
+	}
+	// the study is cached by getMasterPod
+	ret, since, err := k8swif.FetchLogs(d.clientset.CoreV1().Pods(pod.Namespace), pod.Name, d.studies[studyId].kind.container, since)
+	if err != nil {
+		return nil, err
+	}
+	d.logSince[tID] = since
+	if len(ret) == 0 {
+		return nil, nil
+	}
+	return ret, d.db.StoreTrialLogs(tID, ret)
+}
+
+func (d *KubeflowWorkerInterface) IsTrialComplete(studyId string, tID string) (bool, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	st, err := d.jobState(studyId, tID)
+	return st == api.TrialState_COMPLETED, err
+}
+
+func (d *KubeflowWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.objValue(studyId, tID, objname)
+}
+
+func (d *KubeflowWorkerInterface) objValue(studyId string, tID string, objname string) (string, error) {
+	es, err := d.evLogs(studyId, tID, []string{objname}, time.Time{})
+	if err != nil {
+		return "", err
+	}
+	return metricscollector.ObjectiveValue(es, objname)
+}
+
+func (d *KubeflowWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var since time.Time
+	if sinceTime != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339Nano, sinceTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d.evLogs(studyId, tID, metrics, since)
+}
+
+// evLogs collects the metrics the master wrote after since with a new collector of the trial.
+func (d *KubeflowWorkerInterface) evLogs(studyId string, tID string, metrics []string, since time.Time) ([]*api.EvaluationLog, error) {
+	s, err := d.getStudy(studyId)
+	if err != nil {
+		return nil, err
+	}
+	pod, err := d.getMasterPod(studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	return k8swif.CollectPodMetrics(d.clientset.CoreV1().Pods(pod.Namespace), pod, s.kind.container, since, s.config, studyId, tID, metrics)
+}
+
+// CheckRunningTrials checks the job of each running trial every pollInterval.
+// Each finished trial is saved to the DB and moved to the completed list.
+func (d *KubeflowWorkerInterface) CheckRunningTrials(studyId string, objname string, metrics []string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if len(d.RunningTrialList[studyId]) == 0 {
+		return nil
+	}
+	s, err := d.getStudy(studyId)
+	if err != nil {
+		return err
+	}
+	scrapes := metricscollector.Scrapes(s.config)
+	if scrapes {
+		metrics = metricscollector.WithObjective(metrics, objname)
+	}
+	var running []*api.Trial
+	for _, t := range d.RunningTrialList[studyId] {
+		if t.Status != api.TrialState_RUNNING || time.Since(d.lastPoll[t.TrialId]) < pollInterval {
+			if t.Status == api.TrialState_RUNNING && scrapes {
+				// the collector scrapes the master every scrape interval
+				es, err := d.collectors.Collect(s.config, studyId, t.TrialId, nil, metrics)
+				if err != nil {
+					log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
+				}
+				t.EvalLogs = append(t.EvalLogs, es...)
+			}
+			running = append(running, t)
+			continue
+		}
+		d.lastPoll[t.TrialId] = time.Now()
+		st, err := d.jobState(studyId, t.TrialId)
+		if err != nil {
+			log.Printf("Error getting state of %s: %v", t.TrialId, err)
+			running = append(running, t)
+			continue
+		}
+		lines, err := d.fetchLogs(studyId, t.TrialId)
+		if err != nil {
+			log.Printf("Error storing trial log of %s: %v", t.TrialId, err)
+		}
+		if scrapes {
+			if err := d.setHost(s.config, studyId, t.TrialId); err != nil {
+				log.Printf("Error getting pod IP of %s: %v", t.TrialId, err)
+			}
+		}
+		es, err := d.collectors.Collect(s.config, studyId, t.TrialId, metricscollector.TimestampedLines(lines), metrics)
+		if err != nil {
+			log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
+		}
+		t.EvalLogs = append(t.EvalLogs, es...)
+		if st == api.TrialState_RUNNING {
+			running = append(running, t)
+			continue
+		}
+		t.Status = st
+		err = d.db.UpdateTrial(t.TrialId, t.Status)
+		if err != nil {
+			log.Printf("Error updating status for %s: %v", t.TrialId, err)
+		}
+		if st == api.TrialState_COMPLETED {
+			if scrapes {
+				// the endpoint is gone with the master
+				t.ObjectiveValue, err = metricscollector.ObjectiveValue(t.EvalLogs, objname)
+			} else {
+				t.ObjectiveValue, err = d.objValue(studyId, t.TrialId, objname)
+			}
+			if err != nil {
+				log.Printf("Trial %v: %v", t.TrialId, err)
+			}
+			err = d.db.UpdateTrialObjectiveValue(t.TrialId, t.ObjectiveValue)
+			if err != nil {
+				log.Printf("Error updating objective value for %s: %v", t.TrialId, err)
+			}
+			log.Printf("Trial %v is completed.", t.TrialId)
+			log.Printf("Objective Value: %v", t.ObjectiveValue)
+		} else {
+			log.Printf("Trial %v is %v.", t.TrialId, t.Status)
+		}
+		delete(d.lastPoll, t.TrialId)
+		delete(d.logSince, t.TrialId)
+		d.collectors.Delete(t.TrialId)
+		d.CompletedTrialList[studyId] = append(d.CompletedTrialList[studyId], t)
+	}
+	d.RunningTrialList[studyId] = running
+	return nil
+}
+
+func (d *KubeflowWorkerInterface) SpawnWorkers(trials []*api.Trial, studyId string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	jobs, err := d.convertTrialToManifest(trials, studyId)
+	if err != nil {
+		return err
+	}
+	s := d.studies[studyId]
+	d.RunningTrialList[studyId] = append(d.RunningTrialList[studyId], trials...)
+	for i, j := range jobs {
+		cm, err := k8swif.CreateParameterConfigMap(d.clientset, s.namespace, s.config, studyId, trials[i])
+		if err != nil {
+			return err
+		}
+		result, err := d.dynamic.Resource(s.gvr).Namespace(s.namespace).Create(j)
+		if err != nil {
+			k8swif.DeleteParameterConfigMap(d.clientset, s.namespace, cm)
+			return err
+		}
+		owner := metav1.OwnerReference{
+			APIVersion: result.GetAPIVersion(),
+			Kind:       result.GetKind(),
+			Name:       result.GetName(),
+			UID:        result.GetUID(),
+		}
+		err = k8swif.SetParameterConfigMapOwner(d.clientset, s.namespace, cm, owner)
+		if err != nil {
+			return err
+		}
+		trials[i].Status = api.TrialState_RUNNING
+		err = d.db.UpdateTrial(trials[i].TrialId, api.TrialState_RUNNING)
+		if err != nil {
+			log.Printf("Error updating status for %s: %v", trials[i].TrialId, err)
+		}
+		log.Printf("Created %v %q.", j.GetKind(), j.GetName())
+	}
+	return nil
+}
+
+func (d *KubeflowWorkerInterface) GetRunningTrials(studyId string) []*api.Trial {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return append([]*api.Trial{}, d.RunningTrialList[studyId]...)
+}
+
+func (d *KubeflowWorkerInterface) GetCompletedTrials(studyId string) []*api.Trial {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return append([]*api.Trial{}, d.CompletedTrialList[studyId]...)
+}
+
+func (d *KubeflowWorkerInterface) CleanWorkers(studyId string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	s, err := d.getStudy(studyId)
+	if err != nil {
+		return err
+	}
+	// the pods are deleted with the job, as the job owns them
+	bg := metav1.DeletePropagationBackground
+	for _, tl := range [][]*api.Trial{d.RunningTrialList[studyId], d.CompletedTrialList[studyId]} {
+		for _, t := range tl {
+			err = d.dynamic.Resource(s.gvr).Namespace(s.namespace).Delete(t.TrialId, &metav1.DeleteOptions{PropagationPolicy: &bg})
+			if err != nil {
+				log.Printf("Error deleting %v %v: %v", s.kind.resource, t.TrialId, err)
+			}
+			delete(d.lastPoll, t.TrialId)
+			delete(d.logSince, t.TrialId)
+		}
+	}
+	delete(d.RunningTrialList, studyId)
+	delete(d.CompletedTrialList, studyId)
+	delete(d.studies, studyId)
+	return nil
+}
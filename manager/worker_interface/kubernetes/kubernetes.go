@@ -1,24 +1,57 @@
 package kubernetes
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/mlkube/katib/api"
 	"github.com/mlkube/katib/db"
 	"github.com/mlkube/katib/earlystopping"
-	"io/ioutil"
+	"github.com/mlkube/katib/manager/metricscollector"
+	"github.com/mlkube/katib/manager/worker_interface"
 	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"log"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+// gpuResource is the resource name of the GPUs of the NVIDIA device plugin.
+const gpuResource = apiv1.ResourceName("nvidia.com/gpu")
+
+// Jobs and their pods are labeled with the study and trial IDs, and the informers watch only labeled objects.
+const (
+	StudyIdLabel = "katib-study-id"
+	TrialIdLabel = "katib-trial-id"
+)
+
+const (
+	sidecarVolume         = "katib-metrics"
+	defaultSidecarImage   = "katib/metrics-collector"
+	defaultManagerAddress = "vizier-core.katib:6789"
+)
+
+const (
+	informerResync = 5 * time.Minute
+	// logPollInterval is how often the logs of a running trial are fetched when its pod has no events.
+	logPollInterval = 30 * time.Second
+	// sidecarReportTimeout is how long the sidecar has to report a trial after its container terminated.
+	sidecarReportTimeout = time.Minute
+)
+
 type KubernetesWorkerInterface struct {
 	//Support MultiStudy
 	RunningTrialList   map[string][]*api.Trial
@@ -26,122 +59,515 @@ type KubernetesWorkerInterface struct {
 	clientset          *kubernetes.Clientset
 	mux                *sync.Mutex
 	db                 db.VizierDBInterface
+	// namespaces caches the namespace of each study
+	namespaces map[string]string
+	nsMux      *sync.Mutex
+	jobLister  batchlisters.JobLister
+	podLister  corelisters.PodLister
+	// changed is the set of trials whose job or pod had events since they were last checked
+	changed  map[string]bool
+	chMux    *sync.Mutex
+	lastPoll map[string]time.Time
+	// logSince is the time of the last log line stored for each trial
+	logSince map[string]time.Time
+	// reported is whether each trial succeeded, as reported by its sidecar with CompleteTrial
+	reported   map[string]bool
+	collectors *metricscollector.Collectors
+	stopCh     chan struct{}
 }
 
 func NewKubernetesWorkerInterface(cs *kubernetes.Clientset, db db.VizierDBInterface) *KubernetesWorkerInterface {
-	return &KubernetesWorkerInterface{
+	d := &KubernetesWorkerInterface{
 		RunningTrialList:   make(map[string][]*api.Trial),
 		CompletedTrialList: make(map[string][]*api.Trial),
 		clientset:          cs,
 		mux:                new(sync.Mutex),
 		db:                 db,
+		namespaces:         make(map[string]string),
+		nsMux:              new(sync.Mutex),
+		changed:            make(map[string]bool),
+		chMux:              new(sync.Mutex),
+		lastPoll:           make(map[string]time.Time),
+		logSince:           make(map[string]time.Time),
+		reported:           make(map[string]bool),
+		collectors:         metricscollector.NewCollectors(),
+		stopCh:             make(chan struct{}),
+	}
+	factory := informers.NewFilteredSharedInformerFactory(cs, informerResync, metav1.NamespaceAll, func(o *metav1.ListOptions) {
+		o.LabelSelector = TrialIdLabel
+	})
+	ji := factory.Batch().V1().Jobs()
+	pi := factory.Core().V1().Pods()
+	h := cache.ResourceEventHandlerFuncs{
+		AddFunc:    d.onChange,
+		UpdateFunc: func(_, obj interface{}) { d.onChange(obj) },
+		DeleteFunc: d.onChange,
 	}
+	ji.Informer().AddEventHandler(h)
+	pi.Informer().AddEventHandler(h)
+	d.jobLister = ji.Lister()
+	d.podLister = pi.Lister()
+	factory.Start(d.stopCh)
+	for typ, ok := range factory.WaitForCacheSync(d.stopCh) {
+		if !ok {
+			log.Printf("Failed to sync informer cache of %v", typ)
+		}
+	}
+	return d
+}
+
+// onChange marks the trial of a job or pod event to be checked.
+func (d *KubernetesWorkerInterface) onChange(obj interface{}) {
+	if tomb, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tomb.Obj
+	}
+	o, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+	tID := o.GetLabels()[TrialIdLabel]
+	if tID == "" {
+		return
+	}
+	d.chMux.Lock()
+	d.changed[tID] = true
+	d.chMux.Unlock()
 }
 
-func (d *KubernetesWorkerInterface) convertTrialToManifest(trials []*api.Trial, tFile []byte, studyId string) []batchv1.Job {
+// takeChanged reports whether the trial had events, and clears its mark.
+func (d *KubernetesWorkerInterface) takeChanged(tID string) bool {
+	d.chMux.Lock()
+	defer d.chMux.Unlock()
+	c := d.changed[tID]
+	delete(d.changed, tID)
+	return c
+}
+
+// namespace returns the namespace of the jobs of the study, StudyConfig.Namespace or the default namespace.
+func (d *KubernetesWorkerInterface) namespace(studyId string) string {
+	d.nsMux.Lock()
+	defer d.nsMux.Unlock()
+	if ns, ok := d.namespaces[studyId]; ok {
+		return ns
+	}
+	sc, err := d.db.GetStudyConfig(studyId)
+	if err != nil {
+		log.Printf("GetStudyConfig failed %v", err)
+		return worker_interface.DefaultNamespace
+	}
+	ns := worker_interface.StudyNamespace(sc)
+	d.namespaces[studyId] = ns
+	return ns
+}
+
+// convertTrialToManifest builds the job of each trial from the study config.
+// The job is built on StudyConfig.JobTemplate when the study has one, and the first container runs the trial.
+func (d *KubernetesWorkerInterface) convertTrialToManifest(trials []*api.Trial, studyId string) ([]batchv1.Job, error) {
 	ret := make([]batchv1.Job, len(trials))
 	BUFSIZE := 1024
-	d.mux.Lock()
-	defer d.mux.Unlock()
+	sc, err := d.db.GetStudyConfig(studyId)
+	if err != nil {
+		return nil, err
+	}
 	for i, t := range trials {
-		d.RunningTrialList[studyId] = append(d.RunningTrialList[studyId], t)
-		k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(tFile), BUFSIZE).Decode(&ret[i])
-		var args = []string{}
-		for _, v := range t.ParameterSet {
-			args = append(args, v.Name+"="+v.Value)
+		if sc.JobTemplate != "" {
+			err = k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(sc.JobTemplate), BUFSIZE).Decode(&ret[i])
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid job template of Study %v: %v", studyId, err))
+			}
 		}
 		ret[i].ObjectMeta.Name = t.TrialId
-		ret[i].Spec.Template.Spec.Containers[0].Name = t.TrialId + "-worker"
-		ret[i].Spec.Template.Spec.Containers[0].Args = args
+		ret[i].ObjectMeta.Namespace = d.namespace(studyId)
+		for _, m := range []*metav1.ObjectMeta{&ret[i].ObjectMeta, &ret[i].Spec.Template.ObjectMeta} {
+			if m.Labels == nil {
+				m.Labels = map[string]string{}
+			}
+			m.Labels[StudyIdLabel] = studyId
+			m.Labels[TrialIdLabel] = t.TrialId
+		}
+		ps := &ret[i].Spec.Template.Spec
+		if ps.RestartPolicy == "" {
+			ps.RestartPolicy = apiv1.RestartPolicyNever
+		}
+		if len(ps.Containers) == 0 {
+			ps.Containers = append(ps.Containers, apiv1.Container{})
+		}
+		ps.Containers[0].Name = workerContainer(t.TrialId)
+		err = SetupTrialPod(ps, &ps.Containers[0], sc, studyId, t)
+		if err == nil && sc.MetricsCollector.GetSidecar() != nil {
+			err = addMetricsSidecar(ps, 0, sc, studyId, t)
+			// the sidecar completes the trial when its first pod ends, so the job must not start another one
+			var backoffLimit int32
+			ret[i].Spec.BackoffLimit = &backoffLimit
+		}
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid trial %v of Study %v: %v", t.TrialId, studyId, err))
+		}
 	}
-	return ret
+	return ret, nil
 }
 
-func (d *KubernetesWorkerInterface) storeTrialLog(tID string) error {
-	pl, _ := d.clientset.CoreV1().Pods("").List(metav1.ListOptions{LabelSelector: "job-name=" + tID})
-	if len(pl.Items) == 0 {
-		return errors.New(fmt.Sprintf("No Pods are found in Job %v", tID))
-	}
+// workerContainer is the name of the container which runs the trial.
+func workerContainer(tID string) string {
+	return tID + "-worker"
+}
 
-	mt, err := d.db.GetTrialTimestamp(tID)
+// addMetricsSidecar adds the metrics collector sidecar to the pod of a trial, with the volumes of the container ci which runs the trial.
+// The command of the container is wrapped to write its output and exit code for the sidecar to a shared emptyDir.
+func addMetricsSidecar(ps *apiv1.PodSpec, ci int, sc *api.StudyConfig, studyId string, t *api.Trial) error {
+	c := &ps.Containers[ci]
+	if len(c.Command) == 0 {
+		return errors.New("the metrics collector sidecar requires the command of the trial")
+	}
+	out := path.Join(metricscollector.SidecarDir, metricscollector.SidecarOutput)
+	code := path.Join(metricscollector.SidecarDir, metricscollector.SidecarExitCode)
+	// the exit code is written after tee has written all the output, and renamed to be read at once
+	script := fmt.Sprintf(`rm -f %[1]s; { "$@"; echo $? > %[1]s.tmp; } 2>&1 | tee %[2]s; mv %[1]s.tmp %[1]s; exit $(cat %[1]s)`, code, out)
+	c.Command = append(append([]string{"sh", "-c", script, "sh"}, c.Command...), c.Args...)
+	c.Args = nil
+	// a restarted container would run the trial again after the sidecar reported it
+	ps.RestartPolicy = apiv1.RestartPolicyNever
+	ps.Volumes = append(ps.Volumes, apiv1.Volume{
+		Name:         sidecarVolume,
+		VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}},
+	})
+	c.VolumeMounts = append(c.VolumeMounts, apiv1.VolumeMount{Name: sidecarVolume, MountPath: metricscollector.SidecarDir})
+	// the sidecar reads only what it needs of the study config, e.g. metrics files on the mount
+	conf, err := (&jsonpb.Marshaler{}).MarshalToString(&api.StudyConfig{
+		ObjectiveValueName: sc.ObjectiveValueName,
+		Metrics:            sc.Metrics,
+		MetricsCollector:   sc.MetricsCollector,
+		Mount:              sc.Mount,
+	})
 	if err != nil {
 		return err
 	}
-	logopt := apiv1.PodLogOptions{Timestamps: true}
-	if mt != nil {
-		logopt.SinceTime = &metav1.Time{Time: *mt}
+	sidecar := sc.MetricsCollector.Sidecar
+	image := sidecar.Image
+	if image == "" {
+		image = defaultSidecarImage
 	}
+	manager := sidecar.ManagerAddress
+	if manager == "" {
+		manager = defaultManagerAddress
+	}
+	ps.Containers = append(ps.Containers, apiv1.Container{
+		Name:         "metrics-collector",
+		Image:        image,
+		Command:      []string{"./metrics-collector", "-m", manager, "-s", studyId, "-t", t.TrialId, "-c", conf},
+		VolumeMounts: append([]apiv1.VolumeMount{}, ps.Containers[ci].VolumeMounts...),
+	})
+	return nil
+}
 
-	logs, err := d.clientset.CoreV1().Pods(apiv1.NamespaceDefault).GetLogs(pl.Items[0].ObjectMeta.Name, &logopt).Do().Raw()
+// SetupTrialPod applies the study config to the pod of a trial. c is the container of ps which runs the trial.
+func SetupTrialPod(ps *apiv1.PodSpec, c *apiv1.Container, sc *api.StudyConfig, studyId string, t *api.Trial) error {
+	if sc.Scheduler != "" {
+		ps.SchedulerName = sc.Scheduler
+	}
+	if sc.PullSecret != "" {
+		ps.ImagePullSecrets = append(ps.ImagePullSecrets, apiv1.LocalObjectReference{Name: sc.PullSecret})
+	}
+	if sc.Image != "" {
+		c.Image = sc.Image
+	}
+	if len(sc.Command) > 0 {
+		c.Command = sc.Command
+	}
+	command := make([]string, len(c.Command))
+	for j, v := range c.Command {
+		cmd, err := worker_interface.ExpandCommand(v, sc, studyId, t)
+		if err != nil {
+			return err
+		}
+		command[j] = cmd
+	}
+	c.Command = command
+	var args = []string{}
+	for _, v := range c.Args {
+		arg, err := worker_interface.ExpandCommand(v, sc, studyId, t)
+		if err != nil {
+			return err
+		}
+		args = append(args, arg)
+	}
+	pargs, err := worker_interface.ParameterArgs(sc, t)
 	if err != nil {
 		return err
 	}
-	if len(logs) == 0 {
+	c.Args = append(args, pargs...)
+	envs := append(worker_interface.TrialEnvs(sc.Mount, studyId, t), worker_interface.ParameterEnvs(sc, t)...)
+	for _, e := range envs {
+		c.Env = append(c.Env, apiv1.EnvVar{Name: e.Name, Value: e.Value})
+	}
+	fp, _, err := worker_interface.ParameterFile(sc, t)
+	if err != nil {
+		return err
+	}
+	if fp != "" {
+		ps.Volumes = append(ps.Volumes, apiv1.Volume{
+			Name: "parameters",
+			VolumeSource: apiv1.VolumeSource{
+				ConfigMap: &apiv1.ConfigMapVolumeSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: ParameterConfigMapName(t.TrialId)},
+				},
+			},
+		})
+		c.VolumeMounts = append(c.VolumeMounts, apiv1.VolumeMount{
+			Name:      "parameters",
+			MountPath: fp,
+			SubPath:   path.Base(fp),
+		})
+		c.Env = append(c.Env, apiv1.EnvVar{Name: worker_interface.ParameterFileEnv, Value: fp})
+	}
+	if err := ApplyResourceConf(ps, c, sc.Resources); err != nil {
+		return err
+	}
+	if sc.Gpu > 0 {
+		if c.Resources.Limits == nil {
+			c.Resources.Limits = apiv1.ResourceList{}
+		}
+		c.Resources.Limits[gpuResource] = *resource.NewQuantity(int64(sc.Gpu), resource.DecimalSI)
+	}
+	if sc.Mount != nil && sc.Mount.Pvc != "" {
+		ps.Volumes = append(ps.Volumes, apiv1.Volume{
+			Name: "pvc-mount-point",
+			VolumeSource: apiv1.VolumeSource{
+				PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
+					ClaimName: sc.Mount.Pvc,
+				},
+			},
+		})
+		c.VolumeMounts = append(c.VolumeMounts, apiv1.VolumeMount{
+			Name:      "pvc-mount-point",
+			MountPath: sc.Mount.Path,
+		})
+	}
+	return nil
+}
+
+// ParameterConfigMapName is the name of the ConfigMap with the parameter file of a trial in FILE mode.
+func ParameterConfigMapName(tID string) string {
+	return tID + "-parameters"
+}
+
+// CreateParameterConfigMap creates the ConfigMap with the parameter file of the trial in FILE mode, and returns nil in the other modes.
+// It is created before the trial job, so that its pods find it, and then owned by the job with SetParameterConfigMapOwner.
+func CreateParameterConfigMap(cs kubernetes.Interface, ns string, sc *api.StudyConfig, studyId string, t *api.Trial) (*apiv1.ConfigMap, error) {
+	fp, b, err := worker_interface.ParameterFile(sc, t)
+	if err != nil || fp == "" {
+		return nil, err
+	}
+	cm := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   ParameterConfigMapName(t.TrialId),
+			Labels: map[string]string{StudyIdLabel: studyId, TrialIdLabel: t.TrialId},
+		},
+		Data: map[string]string{path.Base(fp): string(b)},
+	}
+	return cs.CoreV1().ConfigMaps(ns).Create(cm)
+}
+
+// SetParameterConfigMapOwner makes the trial job the owner of the ConfigMap, so that it is deleted with the job.
+func SetParameterConfigMapOwner(cs kubernetes.Interface, ns string, cm *apiv1.ConfigMap, owner metav1.OwnerReference) error {
+	if cm == nil {
 		return nil
 	}
-	err = d.db.StoreTrialLogs(tID, strings.Split(string(logs), "\n"))
+	cm.OwnerReferences = append(cm.OwnerReferences, owner)
+	_, err := cs.CoreV1().ConfigMaps(ns).Update(cm)
 	return err
 }
 
-func (d *KubernetesWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
-	pl, _ := d.clientset.CoreV1().Pods("").List(metav1.ListOptions{LabelSelector: "job-name=" + tID})
-	if len(pl.Items) == 0 {
-		return "", errors.New(fmt.Sprintf("No Pods are found in Job %v", tID))
-	}
-	logs, _ := d.clientset.CoreV1().Pods(apiv1.NamespaceDefault).GetLogs(pl.Items[0].ObjectMeta.Name, &apiv1.PodLogOptions{}).Do().Raw()
-	logf := strings.Split(string(logs), "\n")
-	for i := len(logf) - 1; i >= 0; i-- {
-		ls := strings.Split(logf[i], " ")
-		for _, l := range ls {
-			v := strings.Split(l, "=")
-			if v[0] == objname {
-				return v[1], nil
-			}
+// DeleteParameterConfigMap deletes the ConfigMap of a trial whose job could not be created.
+func DeleteParameterConfigMap(cs kubernetes.Interface, ns string, cm *apiv1.ConfigMap) {
+	if cm == nil {
+		return
+	}
+	err := cs.CoreV1().ConfigMaps(ns).Delete(cm.Name, &metav1.DeleteOptions{})
+	if err != nil {
+		log.Printf("Error deleting ConfigMap %v: %v", cm.Name, err)
+	}
+}
+
+// ApplyResourceConf adds the resources, node selector, tolerations and affinity of rc to the pod, over the ones it has.
+func ApplyResourceConf(ps *apiv1.PodSpec, c *apiv1.Container, rc *api.ResourceConf) error {
+	if rc == nil {
+		return nil
+	}
+	var err error
+	c.Resources.Requests, err = addResources(c.Resources.Requests, rc.Requests)
+	if err != nil {
+		return err
+	}
+	c.Resources.Limits, err = addResources(c.Resources.Limits, rc.Limits)
+	if err != nil {
+		return err
+	}
+	for k, v := range rc.NodeSelector {
+		if ps.NodeSelector == nil {
+			ps.NodeSelector = map[string]string{}
+		}
+		ps.NodeSelector[k] = v
+	}
+	for _, t := range rc.Tolerations {
+		ps.Tolerations = append(ps.Tolerations, apiv1.Toleration{
+			Key:      t.Key,
+			Operator: apiv1.TolerationOperator(t.Operator),
+			Value:    t.Value,
+			Effect:   apiv1.TaintEffect(t.Effect),
+		})
+	}
+	if rc.Affinity != "" {
+		ps.Affinity = &apiv1.Affinity{}
+		err = k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(rc.Affinity), 1024).Decode(ps.Affinity)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid affinity: %v", err))
+		}
+	}
+	return nil
+}
+
+func addResources(rl apiv1.ResourceList, quantities map[string]string) (apiv1.ResourceList, error) {
+	for k, v := range quantities {
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid quantity %q of %v: %v", v, k, err))
+		}
+		if rl == nil {
+			rl = apiv1.ResourceList{}
 		}
+		rl[apiv1.ResourceName(k)] = q
 	}
-	return "", errors.New(fmt.Sprintf("No Objective Value Name %v  is found in log", objname))
+	return rl, nil
 }
 
-func (d *KubernetesWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
-	pl, _ := d.clientset.CoreV1().Pods("").List(metav1.ListOptions{LabelSelector: "job-name=" + tID})
-	if len(pl.Items) == 0 {
+// getPod returns the latest pod of the trial from the informer cache.
+func (d *KubernetesWorkerInterface) getPod(studyId string, tID string) (*apiv1.Pod, error) {
+	pl, err := d.podLister.Pods(d.namespace(studyId)).List(labels.SelectorFromSet(labels.Set{TrialIdLabel: tID}))
+	if err != nil {
+		return nil, err
+	}
+	if len(pl) == 0 {
 		return nil, errors.New(fmt.Sprintf("No Pods are found in Job %v", tID))
 	}
-	var logf []string
-	var ret []*api.EvaluationLog
-	if sinceTime != "" {
-		t, err := time.Parse(time.RFC3339, sinceTime)
+	sort.Slice(pl, func(i, j int) bool {
+		return pl[j].CreationTimestamp.Before(&pl[i].CreationTimestamp)
+	})
+	return pl[0], nil
+}
+
+// fetchLogs stores the log lines the trial wrote since the last fetch in the DB, and returns them.
+func (d *KubernetesWorkerInterface) fetchLogs(studyId string, tID string) ([]string, error) {
+	pod, err := d.getPod(studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase == apiv1.PodPending || pod.Status.Phase == apiv1.PodUnknown {
+		return nil, nil
+	}
+	since, ok := d.logSince[tID]
+	if !ok {
+		mt, err := d.db.GetTrialTimestamp(tID)
 		if err != nil {
 			return nil, err
 		}
-		mt := metav1.Time{Time: t}
-		logs, _ := d.clientset.CoreV1().Pods(apiv1.NamespaceDefault).GetLogs(pl.Items[0].ObjectMeta.Name, &apiv1.PodLogOptions{SinceTime: &mt, Timestamps: true}).Do().Raw()
-		logf = strings.Split(string(logs), "\n")[1:]
-	} else {
-		logs, _ := d.clientset.CoreV1().Pods(apiv1.NamespaceDefault).GetLogs(pl.Items[0].ObjectMeta.Name, &apiv1.PodLogOptions{Timestamps: true}).Do().Raw()
-		if len(logs) > 1 && pl.Items[0].Status.Phase != apiv1.PodPending && pl.Items[0].Status.Phase != apiv1.PodUnknown {
-			logf = strings.Split(string(logs), "\n")
-		} else {
-			return ret, nil
+		if mt != nil {
+			since = *mt
 		}
 	}
-	for _, ls := range logf {
-		if ls == "" {
+	ret, since, err := FetchLogs(d.clientset.CoreV1().Pods(pod.Namespace), pod.Name, workerContainer(tID), since)
+	if err != nil {
+		return nil, err
+	}
+	d.logSince[tID] = since
+	if len(ret) == 0 {
+		return nil, nil
+	}
+	return ret, d.db.StoreTrialLogs(tID, ret)
+}
+
+// FetchLogs returns the timestamped log lines of a container of a pod written after since, and the time of the last one.
+func FetchLogs(pcl corev1.PodInterface, name string, container string, since time.Time) ([]string, time.Time, error) {
+	logopt := apiv1.PodLogOptions{Container: container, Timestamps: true}
+	if !since.IsZero() {
+		logopt.SinceTime = &metav1.Time{Time: since}
+	}
+	logs, err := pcl.GetLogs(name, &logopt).Do().Raw()
+	if err != nil {
+		return nil, since, err
+	}
+	var ret []string
+	for _, l := range strings.Split(string(logs), "\n") {
+		ls := strings.SplitN(l, " ", 2)
+		lt, err := time.Parse(time.RFC3339Nano, ls[0])
+		if err != nil {
 			continue
 		}
-		lsf := strings.Split(ls, " ")
-		e := &api.EvaluationLog{Time: lsf[0]}
-		for _, l := range lsf {
-			v := strings.Split(l, "=")
-			for _, m := range metrics {
-				if v[0] == m {
-					e.Metrics = append(e.Metrics, &api.Metrics{Name: m, Value: v[1]})
-				}
-			}
+		// SinceTime is inclusive and the DB keeps microseconds, so skip the lines already stored.
+		lt = lt.Truncate(time.Microsecond)
+		if !lt.After(since) {
+			continue
 		}
-		ret = append(ret, e)
+		since = lt
+		ret = append(ret, l)
 	}
-	return ret, nil
+	return ret, since, nil
+}
+
+// CollectPodMetrics collects the metrics in the logs of a container of a pod written after since, with a new collector of the trial.
+// A collector which scrapes the pod reads its current metrics instead.
+func CollectPodMetrics(pcl corev1.PodInterface, pod *apiv1.Pod, container string, since time.Time, sc *api.StudyConfig, studyId string, tID string, metrics []string) ([]*api.EvaluationLog, error) {
+	mc, err := metricscollector.New(sc, studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	if metricscollector.Scrapes(sc) {
+		metricscollector.SetHost(mc, PodHost(pod))
+		return mc.Collect(nil, metrics)
+	}
+	logs, _, err := FetchLogs(pcl, pod.Name, container, since)
+	if err != nil {
+		return nil, err
+	}
+	return mc.Collect(metricscollector.TimestampedLines(logs), metrics)
+}
+
+func (d *KubernetesWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
+	pod, err := d.getPod(studyId, tID)
+	if err != nil {
+		return "", err
+	}
+	sc, err := d.db.GetStudyConfig(studyId)
+	if err != nil {
+		return "", err
+	}
+	es, err := CollectPodMetrics(d.clientset.CoreV1().Pods(pod.Namespace), pod, workerContainer(tID), time.Time{}, sc, studyId, tID, []string{objname})
+	if err != nil {
+		return "", err
+	}
+	return metricscollector.ObjectiveValue(es, objname)
+}
+
+func (d *KubernetesWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
+	pod, err := d.getPod(studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase == apiv1.PodPending || pod.Status.Phase == apiv1.PodUnknown {
+		return nil, nil
+	}
+	var since time.Time
+	if sinceTime != "" {
+		since, err = time.Parse(time.RFC3339Nano, sinceTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sc, err := d.db.GetStudyConfig(studyId)
+	if err != nil {
+		return nil, err
+	}
+	return CollectPodMetrics(d.clientset.CoreV1().Pods(pod.Namespace), pod, workerContainer(tID), since, sc, studyId, tID, metrics)
 }
 
 func (d *KubernetesWorkerInterface) PollingShouldStop(ess earlystopping.EarlyStoppingService, studyId string) chan bool {
@@ -155,12 +581,8 @@ func (d *KubernetesWorkerInterface) PollingShouldStop(ess earlystopping.EarlySto
 				tm.Reset(60 * time.Second)
 				d.mux.Lock()
 				st := ess.ShouldStoppingTrial(d.RunningTrialList[studyId], d.CompletedTrialList[studyId], 10)
-				jcl := d.clientset.BatchV1().Jobs(apiv1.NamespaceDefault)
-				pcl := d.clientset.CoreV1().Pods(apiv1.NamespaceDefault)
 				for _, t := range st {
-					jcl.Delete(t.TrialId, &metav1.DeleteOptions{})
-					pl, _ := pcl.List(metav1.ListOptions{LabelSelector: "job-name=" + t.TrialId})
-					pcl.Delete(pl.Items[0].ObjectMeta.Name, &metav1.DeleteOptions{})
+					d.deleteJob(studyId, t.TrialId)
 					log.Printf("Trial %v is Killed.", t.TrialId)
 					for i := range d.RunningTrialList[studyId] {
 						if d.RunningTrialList[studyId][i].TrialId == t.TrialId {
@@ -179,91 +601,262 @@ func (d *KubernetesWorkerInterface) PollingShouldStop(ess earlystopping.EarlySto
 }
 
 func (d *KubernetesWorkerInterface) IsTrialComplete(studyId string, tID string) (bool, error) {
-	jcl := d.clientset.BatchV1().Jobs(apiv1.NamespaceDefault)
-	ji, err := jcl.Get(tID, metav1.GetOptions{})
+	ji, err := d.jobLister.Jobs(d.namespace(studyId)).Get(tID)
 	if err != nil {
 		return false, err
 	}
 	if ji.Status.Succeeded == 0 {
 		return false, nil
 	}
-	pl, _ := d.clientset.CoreV1().Pods("").List(metav1.ListOptions{LabelSelector: "job-name=" + tID})
-	if len(pl.Items) == 0 {
-		return false, errors.New(fmt.Sprintf("No Pods are found in Job %v", tID))
+	pod, err := d.getPod(studyId, tID)
+	if err != nil {
+		return false, err
 	}
-	if pl.Items[0].Status.Phase == "Succeeded" {
+	if pod.Status.Phase == apiv1.PodSucceeded {
 		return true, nil
 	}
 	return false, nil
 }
 
+// isJobFailed reports whether the job of the trial has failed, e.g. after its backoff limit.
+func (d *KubernetesWorkerInterface) isJobFailed(studyId string, tID string) bool {
+	ji, err := d.jobLister.Jobs(d.namespace(studyId)).Get(tID)
+	if err != nil {
+		return false
+	}
+	for _, c := range ji.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == apiv1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRunningTrials checks only the trials whose job or pod changed, and the others every logPollInterval.
+// Each finished trial is saved to the DB and moved to the completed list.
 func (d *KubernetesWorkerInterface) CheckRunningTrials(studyId string, objname string, metrics []string) error {
-	allcomp := true
 	d.mux.Lock()
 	defer d.mux.Unlock()
 	if len(d.RunningTrialList[studyId]) == 0 {
 		return nil
 	}
-	for i, t := range d.RunningTrialList[studyId] {
-		status, err := d.db.GetTrialStatus(t.TrialId)
-		if err != nil {
-			log.Printf("Error getting status of %s: %v", t.TrialId, err)
+	sc, err := d.db.GetStudyConfig(studyId)
+	if err != nil {
+		return err
+	}
+	// the sidecar collects the metrics in the pods, and scrapes them itself
+	sidecar := sc.MetricsCollector.GetSidecar() != nil
+	scrapes := metricscollector.Scrapes(sc) && !sidecar
+	if scrapes {
+		metrics = metricscollector.WithObjective(metrics, objname)
+	}
+	var running []*api.Trial
+	for _, t := range d.RunningTrialList[studyId] {
+		switch t.Status {
+		case api.TrialState_PENDING:
+			running = append(running, t)
 			continue
-		}
-		if status == api.TrialState_RUNNING {
-			err = d.storeTrialLog(t.TrialId)
-			if err != nil {
-				log.Printf("Error storing trial log of %s: %v", t.TrialId, err)
+		case api.TrialState_RUNNING:
+			if !d.takeChanged(t.TrialId) && time.Since(d.lastPoll[t.TrialId]) < logPollInterval {
+				if scrapes {
+					// the collector scrapes the pod every scrape interval
+					es, err := d.collectors.Collect(sc, studyId, t.TrialId, nil, metrics)
+					if err != nil {
+						log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
+					}
+					t.EvalLogs = append(t.EvalLogs, es...)
+				}
+				running = append(running, t)
+				continue
 			}
+			d.lastPoll[t.TrialId] = time.Now()
 			c, err := d.IsTrialComplete(studyId, t.TrialId)
 			if err != nil {
 				log.Printf("IsTrialComplete: %v", err)
 			}
-			if c {
-				o, _ := d.GetTrialObjValue(studyId, t.TrialId, objname)
-				d.RunningTrialList[studyId][i].ObjectiveValue = o
-				d.RunningTrialList[studyId][i].Status = api.TrialState_COMPLETED
+			f := d.isJobFailed(studyId, t.TrialId)
+			if sidecar {
+				// the sidecar sends the metrics, and then whether the trial succeeded
+				if ok, reported := d.reported[t.TrialId]; reported {
+					c, f = ok, !ok
+				} else if ts := d.workerTerminated(studyId, t.TrialId); ts != nil && !c && !f {
+					// e.g. killed with the shell which writes the exit code, so the sidecar never reports it and the pod keeps running
+					log.Printf("Trial %v exited with %v, and the sidecar did not report it", t.TrialId, ts.ExitCode)
+					c, f = ts.ExitCode == 0, ts.ExitCode != 0
+					d.deleteJob(studyId, t.TrialId)
+				}
 			} else {
-				allcomp = false
-				var es []*api.EvaluationLog
-				if len(d.RunningTrialList[studyId][i].EvalLogs) == 0 {
-					es, _ = d.GetTrialEvLogs(studyId, t.TrialId, metrics, "")
+				lines, err := d.fetchLogs(studyId, t.TrialId)
+				if err != nil {
+					log.Printf("Error storing trial log of %s: %v", t.TrialId, err)
+				}
+				if scrapes {
+					// the pod changed, and may have got its IP
+					if err := d.setHost(sc, studyId, t.TrialId); err != nil {
+						log.Printf("Error getting pod IP of %s: %v", t.TrialId, err)
+					}
+				}
+				es, err := d.collectors.Collect(sc, studyId, t.TrialId, metricscollector.TimestampedLines(lines), metrics)
+				if err != nil {
+					log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
+				}
+				t.EvalLogs = append(t.EvalLogs, es...)
+			}
+			if c {
+				var o string
+				if sidecar || scrapes {
+					// the metrics can not be read after the pod ends
+					o, err = metricscollector.ObjectiveValue(t.EvalLogs, objname)
 				} else {
-					es, _ = d.GetTrialEvLogs(studyId, t.TrialId, metrics, d.RunningTrialList[studyId][i].EvalLogs[len(d.RunningTrialList[studyId][i].EvalLogs)-1].Time)
+					o, err = d.GetTrialObjValue(studyId, t.TrialId, objname)
 				}
-				if len(es) > 0 {
-					d.RunningTrialList[studyId][i].EvalLogs = append(d.RunningTrialList[studyId][i].EvalLogs, es...)
+				if err != nil {
+					log.Printf("Trial %v: %v", t.TrialId, err)
 				}
+				t.ObjectiveValue = o
+				t.Status = api.TrialState_COMPLETED
+			} else if f {
+				t.Status = api.TrialState_ERROR
+			} else {
+				running = append(running, t)
+				continue
 			}
-		} else if status == api.TrialState_PENDING {
-			allcomp = false
 		}
-	}
-	if allcomp {
-		for i, t := range d.RunningTrialList[studyId] {
-			log.Printf("%v is completed.", t.TrialId)
-			log.Printf("Objective Value: %v", d.RunningTrialList[studyId][i].ObjectiveValue)
-			log.Printf("Tags: %v", t.Tags)
-			//			for _, l := range t.EvalLogs {
-			//				log.Printf("\tEval Logs %v %v\n", l.Time, l.Value)
-			//			}
+		// the trial is completed, failed or killed
+		err := d.db.UpdateTrial(t.TrialId, t.Status)
+		if err != nil {
+			log.Printf("Error updating status for %s: %v", t.TrialId, err)
 		}
-		d.CompletedTrialList[studyId] = append(d.CompletedTrialList[studyId], d.RunningTrialList[studyId]...)
-		d.RunningTrialList[studyId] = []*api.Trial{}
+		if t.Status == api.TrialState_COMPLETED {
+			err = d.db.UpdateTrialObjectiveValue(t.TrialId, t.ObjectiveValue)
+			if err != nil {
+				log.Printf("Error updating objective value for %s: %v", t.TrialId, err)
+			}
+			log.Printf("Trial %v is completed.", t.TrialId)
+			log.Printf("Objective Value: %v", t.ObjectiveValue)
+		} else {
+			log.Printf("Trial %v is %v.", t.TrialId, t.Status)
+		}
+		d.forget(t.TrialId)
+		d.CompletedTrialList[studyId] = append(d.CompletedTrialList[studyId], t)
+	}
+	d.RunningTrialList[studyId] = running
+	return nil
+}
+
+// workerTerminated returns the state of the container which runs the trial
+// if it terminated more than sidecarReportTimeout ago, and nil otherwise.
+func (d *KubernetesWorkerInterface) workerTerminated(studyId string, tID string) *apiv1.ContainerStateTerminated {
+	pod, err := d.getPod(studyId, tID)
+	if err != nil {
 		return nil
 	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != workerContainer(tID) {
+			continue
+		}
+		if ts := cs.State.Terminated; ts != nil && time.Since(ts.FinishedAt.Time) > sidecarReportTimeout {
+			return ts
+		}
+	}
 	return nil
 }
 
+// runningTrial returns the running trial tID of the study.
+func (d *KubernetesWorkerInterface) runningTrial(studyId string, tID string) (*api.Trial, error) {
+	for _, t := range d.RunningTrialList[studyId] {
+		if t.TrialId == tID {
+			return t, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Trial %v of Study %v is not running", tID, studyId))
+}
+
+func (d *KubernetesWorkerInterface) AddMeasurement(studyId string, tID string, els []*api.EvaluationLog) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	t, err := d.runningTrial(studyId, tID)
+	if err != nil {
+		return err
+	}
+	t.EvalLogs = append(t.EvalLogs, els...)
+	return nil
+}
+
+// CompleteTrial records the result the sidecar reports, and has the trial checked at once.
+func (d *KubernetesWorkerInterface) CompleteTrial(studyId string, tID string, isComplete bool) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if _, err := d.runningTrial(studyId, tID); err != nil {
+		return err
+	}
+	d.reported[tID] = isComplete
+	d.chMux.Lock()
+	d.changed[tID] = true
+	d.chMux.Unlock()
+	return nil
+}
+
+// setHost gives the IP of the pod of the trial to its collector while the pod runs.
+func (d *KubernetesWorkerInterface) setHost(sc *api.StudyConfig, studyId string, tID string) error {
+	pod, err := d.getPod(studyId, tID)
+	if err != nil {
+		return err
+	}
+	return d.collectors.SetHost(sc, studyId, tID, PodHost(pod))
+}
+
+// PodHost returns the IP of a running pod, and "" otherwise.
+func PodHost(pod *apiv1.Pod) string {
+	if pod.Status.Phase != apiv1.PodRunning {
+		return ""
+	}
+	return pod.Status.PodIP
+}
+
+// forget drops the polling state of a trial which is no longer running.
+func (d *KubernetesWorkerInterface) forget(tID string) {
+	delete(d.lastPoll, tID)
+	delete(d.logSince, tID)
+	delete(d.reported, tID)
+	d.collectors.Delete(tID)
+	d.takeChanged(tID)
+}
+
 func (d *KubernetesWorkerInterface) SpawnWorkers(trials []*api.Trial, studyId string) error {
-	tFile, _ := ioutil.ReadFile("/conf/template.yml")
-	jobs := d.convertTrialToManifest(trials, tFile, studyId)
-	jcl := d.clientset.BatchV1().Jobs(apiv1.NamespaceDefault)
-	for _, j := range jobs {
+	jobs, err := d.convertTrialToManifest(trials, studyId)
+	if err != nil {
+		return err
+	}
+	sc, err := d.db.GetStudyConfig(studyId)
+	if err != nil {
+		return err
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.RunningTrialList[studyId] = append(d.RunningTrialList[studyId], trials...)
+	jcl := d.clientset.BatchV1().Jobs(d.namespace(studyId))
+	for i, j := range jobs {
+		cm, err := CreateParameterConfigMap(d.clientset, d.namespace(studyId), sc, studyId, trials[i])
+		if err != nil {
+			return err
+		}
 		result, err := jcl.Create(&j)
+		if err != nil {
+			DeleteParameterConfigMap(d.clientset, d.namespace(studyId), cm)
+			return err
+		}
+		owner := metav1.OwnerReference{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+			Name:       result.Name,
+			UID:        result.UID,
+		}
+		err = SetParameterConfigMapOwner(d.clientset, d.namespace(studyId), cm, owner)
 		if err != nil {
 			return err
 		}
+		trials[i].Status = api.TrialState_RUNNING
 		err = d.db.UpdateTrial(j.ObjectMeta.Name, api.TrialState_RUNNING)
 		if err != nil {
 			log.Printf("Error updating status for %s: %v", j.ObjectMeta.Name, err)
@@ -275,27 +868,41 @@ func (d *KubernetesWorkerInterface) SpawnWorkers(trials []*api.Trial, studyId st
 }
 
 func (d *KubernetesWorkerInterface) GetRunningTrials(studyId string) []*api.Trial {
-	return d.RunningTrialList[studyId]
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return append([]*api.Trial{}, d.RunningTrialList[studyId]...)
 }
 
 func (d *KubernetesWorkerInterface) GetCompletedTrials(studyId string) []*api.Trial {
-	return d.CompletedTrialList[studyId]
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return append([]*api.Trial{}, d.CompletedTrialList[studyId]...)
+}
+
+// deleteJob deletes the job of the trial and its pods.
+func (d *KubernetesWorkerInterface) deleteJob(studyId string, tID string) {
+	ns := d.namespace(studyId)
+	d.clientset.BatchV1().Jobs(ns).Delete(tID, &metav1.DeleteOptions{})
+	pl, _ := d.podLister.Pods(ns).List(labels.SelectorFromSet(labels.Set{TrialIdLabel: tID}))
+	for _, p := range pl {
+		d.clientset.CoreV1().Pods(ns).Delete(p.Name, &metav1.DeleteOptions{})
+	}
 }
 
 func (d *KubernetesWorkerInterface) CleanWorkers(studyId string) error {
-	jcl := d.clientset.BatchV1().Jobs(apiv1.NamespaceDefault)
-	pcl := d.clientset.CoreV1().Pods(apiv1.NamespaceDefault)
+	d.mux.Lock()
+	defer d.mux.Unlock()
 	for _, t := range d.RunningTrialList[studyId] {
-		jcl.Delete(t.TrialId, &metav1.DeleteOptions{})
-		pl, _ := pcl.List(metav1.ListOptions{LabelSelector: "job-name=" + t.TrialId})
-		pcl.Delete(pl.Items[0].ObjectMeta.Name, &metav1.DeleteOptions{})
+		d.deleteJob(studyId, t.TrialId)
+		d.forget(t.TrialId)
 	}
 	for _, t := range d.CompletedTrialList[studyId] {
-		jcl.Delete(t.TrialId, &metav1.DeleteOptions{})
-		pl, _ := pcl.List(metav1.ListOptions{LabelSelector: "job-name=" + t.TrialId})
-		pcl.Delete(pl.Items[0].ObjectMeta.Name, &metav1.DeleteOptions{})
+		d.deleteJob(studyId, t.TrialId)
 	}
 	delete(d.RunningTrialList, studyId)
 	delete(d.CompletedTrialList, studyId)
+	d.nsMux.Lock()
+	delete(d.namespaces, studyId)
+	d.nsMux.Unlock()
 	return nil
 }
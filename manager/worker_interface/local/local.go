@@ -0,0 +1,449 @@
+package local
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/db"
+	"github.com/mlkube/katib/manager/metricscollector"
+	"github.com/mlkube/katib/manager/worker_interface"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// logTimeFormat is RFC3339Nano with a fixed width, so that the lines of the log files sort by time.
+const logTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+type logLine struct {
+	time time.Time
+	text string
+}
+
+// process is a running trial. Its stdout and stderr lines are written to files with a timestamp,
+// and kept to parse the metrics.
+type process struct {
+	cmd    *exec.Cmd
+	dir    string
+	done   chan bool
+	err    error
+	mux    sync.Mutex
+	lines  []logLine
+	stored int
+	// mc collects the metrics of the lines after collected
+	mc        metricscollector.MetricsCollector
+	collected int
+	// scrapes is whether mc scrapes the process, which it can do only while the process runs
+	scrapes bool
+}
+
+func (p *process) capture(r io.Reader, f *os.File, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer f.Close()
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		l := logLine{time: time.Now().UTC(), text: s.Text()}
+		fmt.Fprintf(f, "%s %s\n", l.time.Format(logTimeFormat), l.text)
+		p.mux.Lock()
+		p.lines = append(p.lines, l)
+		p.mux.Unlock()
+	}
+	if err := s.Err(); err != nil {
+		log.Printf("Reading the log of %v failed: %v", p.dir, err)
+	}
+	// keep the pipe open until the process exits
+	io.Copy(ioutil.Discard, r)
+}
+
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// LocalWorkerInterface runs the trials as subprocesses of the manager.
+// The command of a trial is StudyConfig.Command with the parameters given as set by StudyConfig.ParameterInjection,
+// and its stdout and stderr are written to stdout.log and stderr.log in workDir/studyId/trialId.
+type LocalWorkerInterface struct {
+	PendingTrialList   map[string][]*api.Trial
+	RunningTrialList   map[string][]*api.Trial
+	CompletedTrialList map[string][]*api.Trial
+	procs              map[string]*process
+	mux                *sync.Mutex
+	db                 db.VizierDBInterface
+	workDir            string
+	// maxParallel limits the running trials of a study without a MaxParallel suggestion parameter. 0 is no limit.
+	maxParallel int
+}
+
+func NewLocalWorkerInterface(db db.VizierDBInterface, workDir string, maxParallel int) *LocalWorkerInterface {
+	return &LocalWorkerInterface{
+		PendingTrialList:   make(map[string][]*api.Trial),
+		RunningTrialList:   make(map[string][]*api.Trial),
+		CompletedTrialList: make(map[string][]*api.Trial),
+		procs:              make(map[string]*process),
+		mux:                new(sync.Mutex),
+		db:                 db,
+		workDir:            workDir,
+		maxParallel:        maxParallel,
+	}
+}
+
+func (l *LocalWorkerInterface) getProcess(tID string) (*process, error) {
+	p, ok := l.procs[tID]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No process TID %v", tID))
+	}
+	return p, nil
+}
+
+func (l *LocalWorkerInterface) parallelLimit(sc *api.StudyConfig) int {
+	for _, sp := range sc.SuggestionParameters {
+		if sp.Name == "MaxParallel" {
+			n, err := strconv.Atoi(sp.Value)
+			if err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return l.maxParallel
+}
+
+func (l *LocalWorkerInterface) start(sc *api.StudyConfig, studyId string, t *api.Trial) (*process, error) {
+	if len(sc.Command) == 0 {
+		return nil, errors.New("Command is required to run a trial locally")
+	}
+	mc, err := metricscollector.New(sc, studyId, t.TrialId)
+	if err != nil {
+		return nil, err
+	}
+	metricscollector.SetHost(mc, "localhost")
+	dir := filepath.Join(l.workDir, studyId, t.TrialId)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var args []string
+	for _, c := range sc.Command {
+		arg, err := worker_interface.ExpandCommand(c, sc, studyId, t)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	pargs, err := worker_interface.ParameterArgs(sc, t)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, pargs...)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = os.Environ()
+	envs := append(worker_interface.TrialEnvs(sc.Mount, studyId, t), worker_interface.ParameterEnvs(sc, t)...)
+	for _, e := range envs {
+		cmd.Env = append(cmd.Env, e.Name+"="+e.Value)
+	}
+	// the parameter file is written in the directory of the trial rather than at its path
+	fp, b, err := worker_interface.ParameterFile(sc, t)
+	if err != nil {
+		return nil, err
+	}
+	if fp != "" {
+		lp := filepath.Join(dir, filepath.Base(fp))
+		if err := ioutil.WriteFile(lp, b, 0644); err != nil {
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, worker_interface.ParameterFileEnv+"="+lp)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	fout, err := os.Create(filepath.Join(dir, "stdout.log"))
+	if err != nil {
+		return nil, err
+	}
+	ferr, err := os.Create(filepath.Join(dir, "stderr.log"))
+	if err != nil {
+		fout.Close()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		fout.Close()
+		ferr.Close()
+		return nil, err
+	}
+	p := &process{cmd: cmd, dir: dir, done: make(chan bool), mc: mc, scrapes: metricscollector.Scrapes(sc)}
+	wg := new(sync.WaitGroup)
+	wg.Add(2)
+	go p.capture(stdout, fout, wg)
+	go p.capture(stderr, ferr, wg)
+	go func() {
+		wg.Wait()
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+	log.Printf("Trial %v started. pid %v, logs in %v", t.TrialId, cmd.Process.Pid, dir)
+	return p, nil
+}
+
+// startPending starts the pending trials of the study as long as the parallel limit allows. l.mux must be held.
+func (l *LocalWorkerInterface) startPending(studyId string) {
+	if len(l.PendingTrialList[studyId]) == 0 {
+		return
+	}
+	sc, err := l.db.GetStudyConfig(studyId)
+	if err != nil {
+		log.Printf("GetStudyConfig failed %v", err)
+		return
+	}
+	limit := l.parallelLimit(sc)
+	for len(l.PendingTrialList[studyId]) > 0 && (limit <= 0 || len(l.RunningTrialList[studyId]) < limit) {
+		t := l.PendingTrialList[studyId][0]
+		l.PendingTrialList[studyId] = l.PendingTrialList[studyId][1:]
+		p, err := l.start(sc, studyId, t)
+		if err != nil {
+			log.Printf("Trial %v failed to start: %v", t.TrialId, err)
+			t.Status = api.TrialState_ERROR
+			l.db.UpdateTrial(t.TrialId, api.TrialState_ERROR)
+			l.CompletedTrialList[studyId] = append(l.CompletedTrialList[studyId], t)
+			continue
+		}
+		l.procs[t.TrialId] = p
+		t.Status = api.TrialState_RUNNING
+		err = l.db.UpdateTrial(t.TrialId, api.TrialState_RUNNING)
+		if err != nil {
+			log.Printf("Error updating status for %s: %v", t.TrialId, err)
+		}
+		l.RunningTrialList[studyId] = append(l.RunningTrialList[studyId], t)
+	}
+}
+
+func (l *LocalWorkerInterface) IsTrialComplete(studyId string, tID string) (bool, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	p, err := l.getProcess(tID)
+	if err != nil {
+		return false, err
+	}
+	return p.exited(), nil
+}
+
+// linesSince returns the lines written after since, or all of them when since is "".
+func (p *process) linesSince(sinceTime string) ([]metricscollector.Line, error) {
+	var since time.Time
+	if sinceTime != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339Nano, sinceTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	var ret []metricscollector.Line
+	for _, ln := range p.lines {
+		if sinceTime != "" && !ln.time.After(since) {
+			continue
+		}
+		ret = append(ret, metricscollector.Line{Time: ln.time.Format(time.RFC3339Nano), Text: ln.text})
+	}
+	return ret, nil
+}
+
+// collect collects the metrics of the lines not collected yet with the collector of the process.
+func (p *process) collect(metrics []string) ([]*api.EvaluationLog, error) {
+	if p.scrapes && p.exited() {
+		return nil, nil
+	}
+	p.mux.Lock()
+	var lines []metricscollector.Line
+	for _, ln := range p.lines[p.collected:] {
+		lines = append(lines, metricscollector.Line{Time: ln.time.Format(time.RFC3339Nano), Text: ln.text})
+	}
+	p.collected = len(p.lines)
+	p.mux.Unlock()
+	return p.mc.Collect(lines, metrics)
+}
+
+// evLogs collects the metrics of the lines written after sinceTime with a new collector of the trial.
+func (l *LocalWorkerInterface) evLogs(p *process, studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
+	sc, err := l.db.GetStudyConfig(studyId)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := metricscollector.New(sc, studyId, tID)
+	if err != nil {
+		return nil, err
+	}
+	if p.scrapes {
+		if p.exited() {
+			return nil, nil
+		}
+		metricscollector.SetHost(mc, "localhost")
+		return mc.Collect(nil, metrics)
+	}
+	lines, err := p.linesSince(sinceTime)
+	if err != nil {
+		return nil, err
+	}
+	return mc.Collect(lines, metrics)
+}
+
+func (l *LocalWorkerInterface) objValue(p *process, studyId string, tID string, objname string) (string, error) {
+	es, err := l.evLogs(p, studyId, tID, []string{objname}, "")
+	if err != nil {
+		return "", err
+	}
+	return metricscollector.ObjectiveValue(es, objname)
+}
+
+func (l *LocalWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
+	l.mux.Lock()
+	p, err := l.getProcess(tID)
+	l.mux.Unlock()
+	if err != nil {
+		return "", err
+	}
+	return l.objValue(p, studyId, tID, objname)
+}
+
+func (l *LocalWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
+	l.mux.Lock()
+	p, err := l.getProcess(tID)
+	l.mux.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return l.evLogs(p, studyId, tID, metrics, sinceTime)
+}
+
+// storeLogs stores the lines not stored yet in the DB.
+func (l *LocalWorkerInterface) storeLogs(tID string, p *process) error {
+	p.mux.Lock()
+	var logs []string
+	for _, ln := range p.lines[p.stored:] {
+		logs = append(logs, ln.time.Format(time.RFC3339Nano)+" "+ln.text)
+	}
+	p.stored = len(p.lines)
+	p.mux.Unlock()
+	if len(logs) == 0 {
+		return nil
+	}
+	return l.db.StoreTrialLogs(tID, logs)
+}
+
+func (l *LocalWorkerInterface) CheckRunningTrials(studyId string, objname string, metrics []string) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	var running []*api.Trial
+	for _, t := range l.RunningTrialList[studyId] {
+		p, err := l.getProcess(t.TrialId)
+		if err != nil {
+			log.Printf("%v", err)
+			continue
+		}
+		// read exited before the logs, so that no line written before the exit is missed
+		c := p.exited()
+		if err := l.storeLogs(t.TrialId, p); err != nil {
+			log.Printf("Error storing trial log of %s: %v", t.TrialId, err)
+		}
+		ms := metrics
+		if p.scrapes {
+			ms = metricscollector.WithObjective(metrics, objname)
+		}
+		es, err := p.collect(ms)
+		if err != nil {
+			log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
+		}
+		t.EvalLogs = append(t.EvalLogs, es...)
+		if !c {
+			running = append(running, t)
+			continue
+		}
+		if p.err != nil {
+			log.Printf("Trial %v failed: %v. See the logs in %v", t.TrialId, p.err, p.dir)
+			t.Status = api.TrialState_ERROR
+		} else {
+			var o string
+			if p.scrapes {
+				// the endpoint is gone with the process
+				o, err = metricscollector.ObjectiveValue(t.EvalLogs, objname)
+			} else {
+				o, err = l.objValue(p, studyId, t.TrialId, objname)
+			}
+			if err != nil {
+				log.Printf("Trial %v: %v", t.TrialId, err)
+			}
+			t.ObjectiveValue = o
+			t.Status = api.TrialState_COMPLETED
+			log.Printf("Trial %v is completed.", t.TrialId)
+			log.Printf("Objective Value: %v", t.ObjectiveValue)
+		}
+		err = l.db.UpdateTrial(t.TrialId, t.Status)
+		if err != nil {
+			log.Printf("Error updating status for %s: %v", t.TrialId, err)
+		}
+		delete(l.procs, t.TrialId)
+		l.CompletedTrialList[studyId] = append(l.CompletedTrialList[studyId], t)
+	}
+	l.RunningTrialList[studyId] = running
+	l.startPending(studyId)
+	return nil
+}
+
+func (l *LocalWorkerInterface) SpawnWorkers(trials []*api.Trial, studyId string) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.PendingTrialList[studyId] = append(l.PendingTrialList[studyId], trials...)
+	l.startPending(studyId)
+	return nil
+}
+
+func (l *LocalWorkerInterface) GetRunningTrials(studyId string) []*api.Trial {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	ret := append([]*api.Trial{}, l.PendingTrialList[studyId]...)
+	return append(ret, l.RunningTrialList[studyId]...)
+}
+
+func (l *LocalWorkerInterface) GetCompletedTrials(studyId string) []*api.Trial {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return append([]*api.Trial{}, l.CompletedTrialList[studyId]...)
+}
+
+func (l *LocalWorkerInterface) CleanWorkers(studyId string) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for _, t := range l.RunningTrialList[studyId] {
+		p, err := l.getProcess(t.TrialId)
+		if err != nil {
+			continue
+		}
+		if !p.exited() {
+			if err := p.cmd.Process.Kill(); err != nil {
+				log.Printf("Kill Trial %v err %v", t.TrialId, err)
+			} else {
+				log.Printf("Trial %v is Killed.", t.TrialId)
+			}
+		}
+		delete(l.procs, t.TrialId)
+	}
+	delete(l.PendingTrialList, studyId)
+	delete(l.RunningTrialList, studyId)
+	return nil
+}
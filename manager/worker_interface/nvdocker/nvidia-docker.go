@@ -1,6 +1,7 @@
 package nvdocker
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
 	"errors"
@@ -10,9 +11,12 @@ import (
 	dclient "github.com/docker/docker/client"
 	"github.com/mlkube/katib/api"
 	"github.com/mlkube/katib/db"
+	"github.com/mlkube/katib/manager/metricscollector"
 	"github.com/mlkube/katib/manager/modeldb"
+	"github.com/mlkube/katib/manager/worker_interface"
 	"io"
 	"io/ioutil"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"log"
 	"os"
 	"path"
@@ -121,6 +125,7 @@ type NvDockerWorkerInterface struct {
 	dcli               *dclient.Client
 	mux                *sync.Mutex
 	dbIf               db.VizierDBInterface
+	collectors         *metricscollector.Collectors
 	tidToCid           map[string]string
 	ngm                *nvGPUManager
 	addTrialQueCh      chan spawnReq
@@ -146,6 +151,7 @@ func NewNvDockerWorkerInterface() *NvDockerWorkerInterface {
 		dcli:               dc,
 		mux:                new(sync.Mutex),
 		dbIf:               db.New(),
+		collectors:         metricscollector.NewCollectors(),
 		ngm:                ngm,
 		tidToCid:           make(map[string]string),
 		addTrialQueCh:      make(chan spawnReq),
@@ -200,53 +206,71 @@ func (n *NvDockerWorkerInterface) IsTrialComplete(studyId string, tID string) (b
 	return false, nil
 }
 
-func (n *NvDockerWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
-	cl, err := n.getConLog(tID, "")
+// conLines returns the log lines of the container of the trial since sinceTime.
+func (n *NvDockerWorkerInterface) conLines(tID string, sinceTime string) ([]metricscollector.Line, error) {
+	cl, err := n.getConLog(tID, sinceTime)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	for i := len(cl) - 1; i >= 0; i-- {
-		ls := strings.Fields(cl[i])
-		for _, l := range ls {
-			v := strings.Split(l, "=")
-			if v[0] == objname {
-				return v[1], nil
-			}
+	var logs []string
+	for _, l := range cl {
+		// each line starts with the 8 bytes header of the docker log stream
+		if len(l) > 8 {
+			logs = append(logs, l[8:])
+		}
+	}
+	var ret []metricscollector.Line
+	for _, l := range metricscollector.TimestampedLines(logs) {
+		if l.Time != sinceTime {
+			ret = append(ret, l)
 		}
 	}
-	return "", errors.New(fmt.Sprintf("No Objective Value Name %v  is found in log", objname))
+	return ret, nil
+}
+
+// conHost returns the IP of the container of the trial while it runs, and "" otherwise.
+func (n *NvDockerWorkerInterface) conHost(tID string) string {
+	c, err := n.getCon(tID)
+	if err != nil || !c.State.Running || c.NetworkSettings == nil {
+		return ""
+	}
+	if c.NetworkSettings.IPAddress != "" {
+		return c.NetworkSettings.IPAddress
+	}
+	for _, nw := range c.NetworkSettings.Networks {
+		if nw.IPAddress != "" {
+			return nw.IPAddress
+		}
+	}
+	return ""
+}
+
+func (n *NvDockerWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
+	es, err := n.GetTrialEvLogs(studyId, tID, []string{objname}, "")
+	if err != nil {
+		return "", err
+	}
+	return metricscollector.ObjectiveValue(es, objname)
 }
 
 func (n *NvDockerWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
-	var ret []*api.EvaluationLog
-	clog, err := n.getConLog(tID, sinceTime)
+	sc, err := n.dbIf.GetStudyConfig(studyId)
 	if err != nil {
 		return nil, err
-	} else if clog == nil {
-		return ret, nil
 	}
-	for _, ls := range clog {
-		lsf := strings.Fields(ls)
-		if len(lsf) == 0 {
-			break
-		}
-		if lsf[0][8:] == sinceTime {
-			continue
-		}
-		e := &api.EvaluationLog{Time: lsf[0][8:]}
-		for _, l := range lsf[0:] {
-			v := strings.Split(l, "=")
-			for _, m := range metrics {
-				if v[0] == m {
-					e.Metrics = append(e.Metrics, &api.Metrics{Name: m, Value: v[1]})
-				}
-			}
-		}
-		if len(e.Metrics) > 0 {
-			ret = append(ret, e)
-		}
+	mc, err := metricscollector.New(sc, studyId, tID)
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	if metricscollector.Scrapes(sc) {
+		metricscollector.SetHost(mc, n.conHost(tID))
+		return mc.Collect(nil, metrics)
+	}
+	lines, err := n.conLines(tID, sinceTime)
+	if err != nil {
+		return nil, err
+	}
+	return mc.Collect(lines, metrics)
 }
 
 func (n *NvDockerWorkerInterface) CheckRunningTrials(studyId string, objname string, metrics []string) error {
@@ -256,6 +280,9 @@ func (n *NvDockerWorkerInterface) CheckRunningTrials(studyId string, objname str
 		return nil
 	}
 	sc, _ := n.dbIf.GetStudyConfig(studyId)
+	if metricscollector.Scrapes(sc) {
+		metrics = metricscollector.WithObjective(metrics, objname)
+	}
 	for _, t := range n.RunningTrialList[studyId] {
 		status, err := n.dbIf.GetTrialStatus(t.TrialId)
 		if err != nil {
@@ -264,21 +291,33 @@ func (n *NvDockerWorkerInterface) CheckRunningTrials(studyId string, objname str
 		}
 		if status == api.TrialState_RUNNING {
 			c, _ := n.IsTrialComplete(studyId, t.TrialId)
-			var es []*api.EvaluationLog
-			if len(t.EvalLogs) == 0 {
-				es, err = n.GetTrialEvLogs(studyId, t.TrialId, metrics, "")
-			} else {
-				es, err = n.GetTrialEvLogs(studyId, t.TrialId, metrics, t.EvalLogs[len(t.EvalLogs)-1].Time)
+			var since string
+			if len(t.EvalLogs) > 0 {
+				since = t.EvalLogs[len(t.EvalLogs)-1].Time
 			}
+			lines, err := n.conLines(t.TrialId, since)
 			if err != nil {
 				log.Printf("GetTrialEvLogs Err %v", err)
 				return err
 			}
-			if len(es) > 0 {
-				t.EvalLogs = append(t.EvalLogs, es...)
+			if metricscollector.Scrapes(sc) {
+				if err := n.collectors.SetHost(sc, studyId, t.TrialId, n.conHost(t.TrialId)); err != nil {
+					log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
+				}
+			}
+			es, err := n.collectors.Collect(sc, studyId, t.TrialId, lines, metrics)
+			if err != nil {
+				log.Printf("Error collecting metrics of %s: %v", t.TrialId, err)
 			}
+			t.EvalLogs = append(t.EvalLogs, es...)
 			if c {
-				o, _ := n.GetTrialObjValue(studyId, t.TrialId, objname)
+				var o string
+				if metricscollector.Scrapes(sc) {
+					// the endpoint is gone with the container
+					o, _ = metricscollector.ObjectiveValue(t.EvalLogs, objname)
+				} else {
+					o, _ = n.GetTrialObjValue(studyId, t.TrialId, objname)
+				}
 				t.ObjectiveValue = o
 				t.Status = api.TrialState_COMPLETED
 				mif := modeldb.ModelDbIF{}
@@ -329,6 +368,7 @@ func (n *NvDockerWorkerInterface) CheckRunningTrials(studyId string, objname str
 					log.Printf("Container delete err %v", err)
 				}
 				delete(n.tidToCid, t.TrialId)
+				n.collectors.Delete(t.TrialId)
 				if len(n.RunningTrialList[studyId]) <= 1 {
 					n.RunningTrialList[studyId] = []*api.Trial{}
 				} else {
@@ -346,24 +386,75 @@ func (n *NvDockerWorkerInterface) CheckRunningTrials(studyId string, objname str
 	return nil
 }
 
-func (n *NvDockerWorkerInterface) convertTrialToContainer(trials []*api.Trial, studyId string) []*container.Config {
+// copyParameterFile copies the parameter file of a trial in FILE mode to the created container.
+// The archive has the parent directories of the file, so that they need not exist in the image.
+func (n *NvDockerWorkerInterface) copyParameterFile(cid string, fp string, b []byte) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	var dirs []string
+	for d := path.Dir(path.Clean("/" + fp)); d != "/"; d = path.Dir(d) {
+		dirs = append([]string{d}, dirs...)
+	}
+	for _, d := range dirs {
+		err := tw.WriteHeader(&tar.Header{Name: d[1:] + "/", Mode: 0755, Typeflag: tar.TypeDir, ModTime: time.Now()})
+		if err != nil {
+			return err
+		}
+	}
+	err := tw.WriteHeader(&tar.Header{Name: path.Clean("/" + fp)[1:], Mode: 0644, Size: int64(len(b)), ModTime: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err = tw.Write(b); err != nil {
+		return err
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return n.dcli.CopyToContainer(context.Background(), cid, "/", &buf, types.CopyToContainerOptions{})
+}
+
+func (n *NvDockerWorkerInterface) convertTrialToContainer(trials []*api.Trial, studyId string) ([]*container.Config, error) {
 	sc, _ := n.dbIf.GetStudyConfig(studyId)
 	ret := make([]*container.Config, len(trials))
-	n.mux.Lock()
-	defer n.mux.Unlock()
 	for i, t := range trials {
-		n.PendingTrialList[studyId] = append(n.PendingTrialList[studyId], t)
-		command := sc.Command
-		for _, v := range t.ParameterSet {
-			command = append(command, v.Name+"="+v.Value)
+		command := make([]string, len(sc.Command))
+		for j, c := range sc.Command {
+			cmd, err := worker_interface.ExpandCommand(c, sc, studyId, t)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid command of Study %v: %v", studyId, err))
+			}
+			command[j] = cmd
+		}
+		pargs, err := worker_interface.ParameterArgs(sc, t)
+		if err != nil {
+			return nil, err
+		}
+		command = append(command, pargs...)
+		var env []string
+		envs := append(worker_interface.TrialEnvs(sc.Mount, studyId, t), worker_interface.ParameterEnvs(sc, t)...)
+		for _, v := range envs {
+			env = append(env, v.Name+"="+v.Value)
+		}
+		// the file is copied to the container before it starts
+		fp, _, err := worker_interface.ParameterFile(sc, t)
+		if err != nil {
+			return nil, err
+		}
+		if fp != "" {
+			env = append(env, worker_interface.ParameterFileEnv+"="+fp)
 		}
 		j := &container.Config{
 			Image: sc.Image,
 			Cmd:   command,
+			Env:   env,
 		}
 		ret[i] = j
 	}
-	return ret
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	n.PendingTrialList[studyId] = append(n.PendingTrialList[studyId], trials...)
+	return ret, nil
 }
 
 type trialQueueObj struct {
@@ -395,6 +486,8 @@ func (n *NvDockerWorkerInterface) schedulingLoop() error {
 				if sc.Mount.Pvc != "" {
 					chc.Binds = []string{sc.Mount.Pvc + ":" + sc.Mount.Path}
 				}
+				// checked in SpawnWorkers
+				hostResources(sc.Resources, &chc.Resources)
 				if sc.Gpu > 0 {
 					ok, gid, err := n.ngm.AllocGPU(int(sc.Gpu), t.Trial.TrialId)
 					if err != nil {
@@ -404,7 +497,7 @@ func (n *NvDockerWorkerInterface) schedulingLoop() error {
 					if !ok {
 						break
 					}
-					t.CConf.Env = []string{"NVIDIA_VISIBLE_DEVICES=" + strings.Join(gid, ",")}
+					t.CConf.Env = append(t.CConf.Env, "NVIDIA_VISIBLE_DEVICES="+strings.Join(gid, ","))
 					chc.Runtime = "nvidia"
 				}
 				resp, err := n.dcli.ContainerCreate(context.Background(), t.CConf, chc, nil, t.Trial.TrialId)
@@ -426,6 +519,17 @@ func (n *NvDockerWorkerInterface) schedulingLoop() error {
 						break
 					}
 				}
+				// checked in convertTrialToContainer
+				if fp, b, _ := worker_interface.ParameterFile(sc, t.Trial); fp != "" {
+					if err := n.copyParameterFile(resp.ID, fp, b); err != nil {
+						log.Printf("Parameter file copy err %v", err)
+						n.dcli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
+						if sc.Gpu > 0 {
+							n.ngm.ReleaseGPU(t.Trial.TrialId)
+						}
+						break
+					}
+				}
 				if err := n.dcli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{}); err != nil {
 					if sc.Gpu > 0 {
 						n.ngm.ReleaseGPU(t.Trial.TrialId)
@@ -466,8 +570,50 @@ func (n *NvDockerWorkerInterface) schedulingLoop() error {
 	}
 }
 
+// hostResources sets the cpu and memory of rc to the resources of a container.
+// Requests are soft limits (cpu shares and memory reservation), and the other resources are ignored.
+func hostResources(rc *api.ResourceConf, r *container.Resources) error {
+	if rc == nil {
+		return nil
+	}
+	for _, rl := range []struct {
+		quantities map[string]string
+		limit      bool
+	}{{rc.Requests, false}, {rc.Limits, true}} {
+		for k, v := range rl.quantities {
+			q, err := resource.ParseQuantity(v)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Invalid quantity %q of %v: %v", v, k, err))
+			}
+			switch {
+			case k == "cpu" && rl.limit:
+				r.NanoCPUs = q.MilliValue() * 1000000
+			case k == "cpu":
+				r.CPUShares = q.MilliValue() * 1024 / 1000
+			case k == "memory" && rl.limit:
+				r.Memory = q.Value()
+			case k == "memory":
+				r.MemoryReservation = q.Value()
+			}
+		}
+	}
+	return nil
+}
+
 func (n *NvDockerWorkerInterface) SpawnWorkers(trials []*api.Trial, studyId string) error {
-	n.addTrialQueCh <- spawnReq{StudyID: studyId, Trials: trials, CConf: n.convertTrialToContainer(trials, studyId)}
+	sc, err := n.dbIf.GetStudyConfig(studyId)
+	if err != nil {
+		return err
+	}
+	err = hostResources(sc.Resources, &container.Resources{})
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid resources of Study %v: %v", studyId, err))
+	}
+	cc, err := n.convertTrialToContainer(trials, studyId)
+	if err != nil {
+		return err
+	}
+	n.addTrialQueCh <- spawnReq{StudyID: studyId, Trials: trials, CConf: cc}
 	return nil
 }
 
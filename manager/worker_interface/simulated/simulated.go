@@ -0,0 +1,170 @@
+package simulated
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/db"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Objective evaluates a trial. A trial whose Objective returns an error fails.
+type Objective func(t *api.Trial) (float64, error)
+
+type Config struct {
+	Objective Objective
+	// Latency is how long a trial runs.
+	Latency time.Duration
+	// FailureRate is the probability that a trial fails without evaluating Objective.
+	FailureRate float64
+	Seed        int64
+}
+
+type result struct {
+	end   time.Time
+	value float64
+	err   error
+}
+
+// SimulatedWorkerInterface "runs" a trial by evaluating Objective when it is spawned,
+// and reports it as completed once Latency has passed. It lets the manager be tested without a cluster.
+type SimulatedWorkerInterface struct {
+	RunningTrialList   map[string][]*api.Trial
+	CompletedTrialList map[string][]*api.Trial
+	results            map[string]*result
+	mux                *sync.Mutex
+	db                 db.VizierDBInterface
+	conf               Config
+	rng                *rand.Rand
+}
+
+func NewSimulatedWorkerInterface(db db.VizierDBInterface, conf Config) *SimulatedWorkerInterface {
+	return &SimulatedWorkerInterface{
+		RunningTrialList:   make(map[string][]*api.Trial),
+		CompletedTrialList: make(map[string][]*api.Trial),
+		results:            make(map[string]*result),
+		mux:                new(sync.Mutex),
+		db:                 db,
+		conf:               conf,
+		rng:                rand.New(rand.NewSource(conf.Seed)),
+	}
+}
+
+func (s *SimulatedWorkerInterface) getResult(tID string) (*result, error) {
+	r, ok := s.results[tID]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No simulated trial TID %v", tID))
+	}
+	return r, nil
+}
+
+func (s *SimulatedWorkerInterface) IsTrialComplete(studyId string, tID string) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	r, err := s.getResult(tID)
+	if err != nil {
+		return false, err
+	}
+	return !time.Now().Before(r.end), nil
+}
+
+func (s *SimulatedWorkerInterface) GetTrialObjValue(studyId string, tID string, objname string) (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	r, err := s.getResult(tID)
+	if err != nil {
+		return "", err
+	}
+	if r.err != nil {
+		return "", r.err
+	}
+	return strconv.FormatFloat(r.value, 'f', -1, 64), nil
+}
+
+func (s *SimulatedWorkerInterface) GetTrialEvLogs(studyId string, tID string, metrics []string, sinceTime string) ([]*api.EvaluationLog, error) {
+	return nil, nil
+}
+
+func (s *SimulatedWorkerInterface) CheckRunningTrials(studyId string, objname string, metrics []string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	now := time.Now()
+	var running []*api.Trial
+	for _, t := range s.RunningTrialList[studyId] {
+		r, err := s.getResult(t.TrialId)
+		if err != nil {
+			log.Printf("%v", err)
+			continue
+		}
+		if now.Before(r.end) {
+			running = append(running, t)
+			continue
+		}
+		if r.err != nil {
+			log.Printf("Trial %v failed: %v", t.TrialId, r.err)
+			t.Status = api.TrialState_ERROR
+		} else {
+			t.ObjectiveValue = strconv.FormatFloat(r.value, 'f', -1, 64)
+			t.Status = api.TrialState_COMPLETED
+			t.EvalLogs = append(t.EvalLogs, &api.EvaluationLog{
+				Time:    r.end.UTC().Format(time.RFC3339Nano),
+				Metrics: []*api.Metrics{{Name: objname, Value: t.ObjectiveValue}},
+			})
+		}
+		err = s.db.UpdateTrial(t.TrialId, t.Status)
+		if err != nil {
+			log.Printf("Error updating status for %s: %v", t.TrialId, err)
+		}
+		delete(s.results, t.TrialId)
+		s.CompletedTrialList[studyId] = append(s.CompletedTrialList[studyId], t)
+	}
+	s.RunningTrialList[studyId] = running
+	return nil
+}
+
+func (s *SimulatedWorkerInterface) SpawnWorkers(trials []*api.Trial, studyId string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, t := range trials {
+		r := &result{end: time.Now().Add(s.conf.Latency)}
+		if s.rng.Float64() < s.conf.FailureRate {
+			r.err = errors.New("simulated failure")
+		} else {
+			r.value, r.err = s.conf.Objective(t)
+		}
+		s.results[t.TrialId] = r
+		t.Status = api.TrialState_RUNNING
+		err := s.db.UpdateTrial(t.TrialId, api.TrialState_RUNNING)
+		if err != nil {
+			log.Printf("Error updating status for %s: %v", t.TrialId, err)
+		}
+		s.RunningTrialList[studyId] = append(s.RunningTrialList[studyId], t)
+	}
+	return nil
+}
+
+func (s *SimulatedWorkerInterface) GetRunningTrials(studyId string) []*api.Trial {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]*api.Trial{}, s.RunningTrialList[studyId]...)
+}
+
+func (s *SimulatedWorkerInterface) GetCompletedTrials(studyId string) []*api.Trial {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]*api.Trial{}, s.CompletedTrialList[studyId]...)
+}
+
+func (s *SimulatedWorkerInterface) CleanWorkers(studyId string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, t := range s.RunningTrialList[studyId] {
+		delete(s.results, t.TrialId)
+	}
+	delete(s.RunningTrialList, studyId)
+	return nil
+}
@@ -1,7 +1,17 @@
 package worker_interface
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/mlkube/katib/api"
+	yaml "gopkg.in/yaml.v2"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
 )
 
 type WorkerInterface interface {
@@ -14,3 +24,230 @@ type WorkerInterface interface {
 	GetCompletedTrials(studyId string) []*api.Trial
 	CleanWorkers(studyId string) error
 }
+
+// MeasurementReceiver is a WorkerInterface whose trials send their metrics to the manager,
+// e.g. from the metrics collector sidecar, with the AddMeasurementToTrials and CompleteTrial RPCs.
+type MeasurementReceiver interface {
+	AddMeasurement(studyId string, tID string, els []*api.EvaluationLog) error
+	// CompleteTrial tells that the trial has ended, successfully if isComplete.
+	CompleteTrial(studyId string, tID string, isComplete bool) error
+}
+
+// DefaultNamespace is the namespace of the jobs of the trials and of TensorBoard when StudyConfig.Namespace is not set,
+// the namespace of Katib, where the PVC of the study is.
+const DefaultNamespace = "katib"
+
+// StudyNamespace returns the kubernetes namespace of the study.
+func StudyNamespace(sc *api.StudyConfig) string {
+	if sc.Namespace != "" {
+		return sc.Namespace
+	}
+	return DefaultNamespace
+}
+
+// CheckpointDir is the directory on the study's MountConf PVC where a trial writes its checkpoint.
+func CheckpointDir(mount *api.MountConf, studyId string, trialId string) string {
+	if mount == nil || mount.Path == "" || trialId == "" {
+		return ""
+	}
+	return path.Join(mount.Path, "checkpoints", studyId, trialId)
+}
+
+// RestoreDir is the checkpoint directory of the trial named by the api.RestoreTrialTag of t, if any.
+func RestoreDir(mount *api.MountConf, studyId string, t *api.Trial) string {
+	for _, tag := range t.Tags {
+		if tag.Name == api.RestoreTrialTag {
+			return CheckpointDir(mount, studyId, tag.Value)
+		}
+	}
+	return ""
+}
+
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// TrialEnvs returns the environment variables every worker passes to a trial.
+func TrialEnvs(mount *api.MountConf, studyId string, t *api.Trial) []EnvVar {
+	return []EnvVar{
+		{Name: "STUDY_ID", Value: studyId},
+		{Name: "TRIAL_ID", Value: t.TrialId},
+		{Name: "CHECKPOINT_DIR", Value: CheckpointDir(mount, studyId, t.TrialId)},
+		{Name: "RESTORE_DIR", Value: RestoreDir(mount, studyId, t)},
+	}
+}
+
+// ReplacePlaceholders substitutes {{STUDY_ID}}, {{TRIAL_ID}}, {{CHECKPOINT_DIR}} and {{RESTORE_DIR}} in s.
+func ReplacePlaceholders(s string, mount *api.MountConf, studyId string, t *api.Trial) string {
+	for _, e := range TrialEnvs(mount, studyId, t) {
+		s = strings.Replace(s, "{{"+e.Name+"}}", e.Value, -1)
+	}
+	return s
+}
+
+const (
+	defaultArgFormat     = "{{.Name}}={{.Value}}"
+	defaultParameterFile = "/etc/katib/parameters.json"
+	// ParameterFileEnv is the environment variable with the path of the parameter file in FILE mode.
+	ParameterFileEnv = "PARAMETER_FILE"
+)
+
+var envNameRE = regexp.MustCompile(`[^A-Z0-9_]`)
+
+func parameterInjection(sc *api.StudyConfig) *api.ParameterInjection {
+	if sc.ParameterInjection != nil {
+		return sc.ParameterInjection
+	}
+	return &api.ParameterInjection{}
+}
+
+// parameterKey is the name of a parameter without the leading dashes, e.g. lr for --lr.
+func parameterKey(name string) string {
+	return strings.TrimLeft(name, "-")
+}
+
+// ExpandCommand replaces the placeholders in s, an element of the command of the trial.
+// In TEMPLATE mode, s is then rendered as a Go template with the parameters as .Params, e.g. {{.Params.lr}}.
+func ExpandCommand(s string, sc *api.StudyConfig, studyId string, t *api.Trial) (string, error) {
+	s = ReplacePlaceholders(s, sc.Mount, studyId, t)
+	if parameterInjection(sc).Mode != api.ParameterInjection_TEMPLATE {
+		return s, nil
+	}
+	tmpl, err := template.New("command").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	params := make(map[string]string)
+	for _, p := range t.ParameterSet {
+		params[parameterKey(p.Name)] = p.Value
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct{ Params map[string]string }{params})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ParameterArgs returns the arguments of the parameters of the trial in ARGS mode.
+func ParameterArgs(sc *api.StudyConfig, t *api.Trial) ([]string, error) {
+	pi := parameterInjection(sc)
+	if pi.Mode != api.ParameterInjection_ARGS {
+		return nil, nil
+	}
+	format := pi.ArgFormat
+	if format == "" {
+		format = defaultArgFormat
+	}
+	var tmpls []*template.Template
+	for _, f := range splitArgFormat(format) {
+		tmpl, err := template.New("arg").Parse(f)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid arg format %q: %v", format, err))
+		}
+		tmpls = append(tmpls, tmpl)
+	}
+	var ret []string
+	for _, p := range t.ParameterSet {
+		for _, tmpl := range tmpls {
+			var buf bytes.Buffer
+			err := tmpl.Execute(&buf, p)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, buf.String())
+		}
+	}
+	return ret, nil
+}
+
+// splitArgFormat splits the arg format into the templates of the arguments at the spaces out of the actions,
+// so that {{ .Value }} is one action and a value with spaces is one argument.
+func splitArgFormat(format string) []string {
+	var ret []string
+	start, inAction := -1, false
+	for i := 0; i < len(format); i++ {
+		switch {
+		case strings.HasPrefix(format[i:], "{{"):
+			inAction = true
+		case strings.HasPrefix(format[i:], "}}"):
+			inAction = false
+		case !inAction && (format[i] == ' ' || format[i] == '\t' || format[i] == '\n'):
+			if start >= 0 {
+				ret = append(ret, format[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		ret = append(ret, format[start:])
+	}
+	return ret
+}
+
+// ParameterEnvs returns the parameters of the trial as environment variables in ENV mode, e.g. LR for --lr.
+func ParameterEnvs(sc *api.StudyConfig, t *api.Trial) []EnvVar {
+	pi := parameterInjection(sc)
+	if pi.Mode != api.ParameterInjection_ENV {
+		return nil
+	}
+	var ret []EnvVar
+	for _, p := range t.ParameterSet {
+		name := envNameRE.ReplaceAllString(strings.ToUpper(parameterKey(p.Name)), "_")
+		ret = append(ret, EnvVar{Name: pi.EnvPrefix + name, Value: p.Value})
+	}
+	return ret
+}
+
+// ParameterFile returns the path and the content of the parameter file of the trial in FILE mode, and "" otherwise.
+// INT and DOUBLE parameters are written as numbers. The types come from the ParameterConfigs of the study,
+// since some suggestion services leave the ParameterType of the trial parameters unset.
+func ParameterFile(sc *api.StudyConfig, t *api.Trial) (string, []byte, error) {
+	pi := parameterInjection(sc)
+	if pi.Mode != api.ParameterInjection_FILE {
+		return "", nil, nil
+	}
+	fp := pi.FilePath
+	if fp == "" {
+		fp = defaultParameterFile
+	}
+	types := make(map[string]api.ParameterType)
+	if sc.ParameterConfigs != nil {
+		for _, pc := range sc.ParameterConfigs.Configs {
+			types[pc.Name] = pc.ParameterType
+		}
+	}
+	params := make(map[string]interface{})
+	for _, p := range t.ParameterSet {
+		var v interface{} = p.Value
+		pt, ok := types[p.Name]
+		if !ok {
+			pt = p.ParameterType
+		}
+		switch pt {
+		case api.ParameterType_INT:
+			if i, err := strconv.ParseInt(p.Value, 10, 64); err == nil {
+				v = i
+			}
+		case api.ParameterType_DOUBLE:
+			if f, err := strconv.ParseFloat(p.Value, 64); err == nil {
+				v = f
+			}
+		}
+		params[parameterKey(p.Name)] = v
+	}
+	var b []byte
+	var err error
+	switch path.Ext(fp) {
+	case ".yaml", ".yml":
+		b, err = yaml.Marshal(params)
+	default:
+		b, err = json.MarshalIndent(params, "", "  ")
+	}
+	return fp, b, err
+}
@@ -0,0 +1,210 @@
+package worker_interface
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/mlkube/katib/api"
+)
+
+func newTrial() *api.Trial {
+	return &api.Trial{
+		TrialId: "trial",
+		ParameterSet: []*api.Parameter{
+			{Name: "--lr", ParameterType: api.ParameterType_DOUBLE, Value: "0.01"},
+			{Name: "--num-layers", ParameterType: api.ParameterType_INT, Value: "3"},
+			{Name: "optimizer", ParameterType: api.ParameterType_CATEGORICAL, Value: "adam sgd"},
+		},
+	}
+}
+
+func injection(pi *api.ParameterInjection) *api.StudyConfig {
+	return &api.StudyConfig{ParameterInjection: pi}
+}
+
+func TestParameterArgs(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		sc   *api.StudyConfig
+		want []string
+		err  bool
+	}{
+		{
+			name: "default",
+			sc:   &api.StudyConfig{},
+			want: []string{"--lr=0.01", "--num-layers=3", "optimizer=adam sgd"},
+		},
+		{
+			name: "separate name and value",
+			sc:   injection(&api.ParameterInjection{ArgFormat: "{{.Name}} {{.Value}}"}),
+			want: []string{"--lr", "0.01", "--num-layers", "3", "optimizer", "adam sgd"},
+		},
+		{
+			name: "spaces in actions",
+			sc:   injection(&api.ParameterInjection{ArgFormat: "  -p {{ .Name }}={{ printf \"%s\" .Value }} "}),
+			want: []string{"-p", "--lr=0.01", "-p", "--num-layers=3", "-p", "optimizer=adam sgd"},
+		},
+		{
+			name: "other mode",
+			sc:   injection(&api.ParameterInjection{Mode: api.ParameterInjection_ENV}),
+		},
+		{
+			name: "unclosed action",
+			sc:   injection(&api.ParameterInjection{ArgFormat: "{{.Name}}={{.Value"}),
+			err:  true,
+		},
+		{
+			name: "unknown field",
+			sc:   injection(&api.ParameterInjection{ArgFormat: "{{.Missing}}"}),
+			err:  true,
+		},
+	} {
+		args, err := ParameterArgs(c.sc, newTrial())
+		if c.err {
+			if err == nil {
+				t.Errorf("%v: expected an error, got %q", c.name, args)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(args, c.want) {
+			t.Errorf("%v: expected %q, got %q %v", c.name, c.want, args, err)
+		}
+	}
+}
+
+func TestParameterEnvs(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		sc   *api.StudyConfig
+		want []EnvVar
+	}{
+		{
+			name: "env",
+			sc:   injection(&api.ParameterInjection{Mode: api.ParameterInjection_ENV}),
+			want: []EnvVar{{"LR", "0.01"}, {"NUM_LAYERS", "3"}, {"OPTIMIZER", "adam sgd"}},
+		},
+		{
+			name: "prefix",
+			sc:   injection(&api.ParameterInjection{Mode: api.ParameterInjection_ENV, EnvPrefix: "HP_"}),
+			want: []EnvVar{{"HP_LR", "0.01"}, {"HP_NUM_LAYERS", "3"}, {"HP_OPTIMIZER", "adam sgd"}},
+		},
+		{
+			name: "default mode",
+			sc:   &api.StudyConfig{},
+		},
+	} {
+		if envs := ParameterEnvs(c.sc, newTrial()); !reflect.DeepEqual(envs, c.want) {
+			t.Errorf("%v: expected %v, got %v", c.name, c.want, envs)
+		}
+	}
+}
+
+func TestParameterFile(t *testing.T) {
+	want := map[string]interface{}{"lr": 0.01, "num-layers": 3, "optimizer": "adam sgd"}
+	// untyped is a trial of a suggestion service that does not set the types, e.g. random or hyperband
+	untyped := newTrial()
+	for _, p := range untyped.ParameterSet {
+		p.ParameterType = api.ParameterType_UNKNOWN_TYPE
+	}
+	configs := &api.StudyConfig_ParameterConfigs{Configs: []*api.ParameterConfig{
+		{Name: "--lr", ParameterType: api.ParameterType_DOUBLE},
+		{Name: "--num-layers", ParameterType: api.ParameterType_INT},
+		{Name: "optimizer", ParameterType: api.ParameterType_CATEGORICAL},
+	}}
+	for _, c := range []struct {
+		name      string
+		sc        *api.StudyConfig
+		trial     *api.Trial
+		path      string
+		unmarshal func([]byte, interface{}) error
+	}{
+		{
+			name:      "default path",
+			sc:        injection(&api.ParameterInjection{Mode: api.ParameterInjection_FILE}),
+			path:      "/etc/katib/parameters.json",
+			unmarshal: json.Unmarshal,
+		},
+		{
+			name:      "yaml",
+			sc:        injection(&api.ParameterInjection{Mode: api.ParameterInjection_FILE, FilePath: "/opt/params.yaml"}),
+			path:      "/opt/params.yaml",
+			unmarshal: yaml.Unmarshal,
+		},
+		{
+			name:      "types of the study",
+			sc:        &api.StudyConfig{ParameterConfigs: configs, ParameterInjection: &api.ParameterInjection{Mode: api.ParameterInjection_FILE}},
+			trial:     untyped,
+			path:      "/etc/katib/parameters.json",
+			unmarshal: json.Unmarshal,
+		},
+		{
+			name:      "types of the study in yaml",
+			sc:        &api.StudyConfig{ParameterConfigs: configs, ParameterInjection: &api.ParameterInjection{Mode: api.ParameterInjection_FILE, FilePath: "/opt/params.yml"}},
+			trial:     untyped,
+			path:      "/opt/params.yml",
+			unmarshal: yaml.Unmarshal,
+		},
+	} {
+		trial := c.trial
+		if trial == nil {
+			trial = newTrial()
+		}
+		fp, b, err := ParameterFile(c.sc, trial)
+		if err != nil || fp != c.path {
+			t.Errorf("%v: expected %v, got %v %v", c.name, c.path, fp, err)
+			continue
+		}
+		var got map[string]interface{}
+		if err := c.unmarshal(b, &got); err != nil {
+			t.Errorf("%v: invalid file %s: %v", c.name, b, err)
+			continue
+		}
+		// JSON numbers are float64 and YAML integers are int
+		for k, v := range want {
+			if fmt.Sprint(got[k]) != fmt.Sprint(v) {
+				t.Errorf("%v: expected %v=%v, got %v", c.name, k, v, got[k])
+			}
+			if _, isString := got[k].(string); isString != (k == "optimizer") {
+				t.Errorf("%v: expected %v as a number only for INT and DOUBLE, got %#v", c.name, k, got[k])
+			}
+		}
+	}
+	if fp, b, err := ParameterFile(&api.StudyConfig{}, newTrial()); fp != "" || b != nil || err != nil {
+		t.Errorf("Expected no file in the default mode, got %v %s %v", fp, b, err)
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	mount := &api.MountConf{Pvc: "pvc", Path: "/data"}
+	for _, c := range []struct {
+		name string
+		s    string
+		mode api.ParameterInjection_Mode
+		want string
+		err  bool
+	}{
+		{name: "placeholders", s: "--out={{CHECKPOINT_DIR}}", want: "--out=/data/checkpoints/study/trial"},
+		{name: "not a template out of TEMPLATE mode", s: "{{.Params.lr}}", want: "{{.Params.lr}}"},
+		{name: "parameter name with a dash", s: "{{ .Params.num-layers }}", mode: api.ParameterInjection_TEMPLATE, err: true},
+		{name: "template with index", s: `--lr={{.Params.lr}} --layers={{ index .Params "num-layers" }}`, mode: api.ParameterInjection_TEMPLATE, want: "--lr=0.01 --layers=3"},
+		{name: "template and placeholders", s: "{{TRIAL_ID}}-{{.Params.optimizer}}", mode: api.ParameterInjection_TEMPLATE, want: "trial-adam sgd"},
+		{name: "missing parameter", s: "{{.Params.momentum}}", mode: api.ParameterInjection_TEMPLATE, err: true},
+		{name: "invalid template", s: "{{.Params.lr", mode: api.ParameterInjection_TEMPLATE, err: true},
+	} {
+		sc := &api.StudyConfig{Mount: mount, ParameterInjection: &api.ParameterInjection{Mode: c.mode}}
+		got, err := ExpandCommand(c.s, sc, "study", newTrial())
+		if c.err {
+			if err == nil {
+				t.Errorf("%v: expected an error, got %q", c.name, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%v: expected %q, got %q %v", c.name, c.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/suggestion"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	configIdTag = "ASHA_ConfigID"
+	rungTag     = "ASHA_Rung"
+	resourceTag = "ASHA_r"
+)
+
+type ASHAParameters struct {
+	eta           float64
+	r_min         float64
+	r_max         float64
+	s             int
+	topRung       int
+	SuggestionNum int
+	MaxParallel   int
+	ResourceName  string
+	samplerKind   string
+	dim           int
+	sampler       suggestion.Sampler
+	drawn         int
+	rng           *suggestion.Rand
+}
+
+// ashaState is the position of the sampler and of the generator of the configuration IDs.
+type ashaState struct {
+	Drawn int
+	Rand  suggestion.RandState
+}
+
+type ASHASuggestService struct {
+}
+
+// rungTrial is the latest known state of one configuration at one rung.
+type rungTrial struct {
+	configId string
+	rung     int
+	value    float64
+	params   []*api.Parameter
+	running  bool
+}
+
+func NewASHASuggestService() *suggestion.Service {
+	return suggestion.NewService(&ASHASuggestService{})
+}
+
+// generate_randid draws the ID from the study generator, so that the same seed gives the same trials.
+func (p *ASHAParameters) generate_randid() string {
+	return fmt.Sprintf("%016x", p.rng.Uint64())
+}
+
+func (a *ASHASuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (suggestion.Study, error) {
+	d := suggestion.NewParameterDecoder(in.SuggestionParameters)
+	p := &ASHAParameters{
+		eta:           d.Float("Eta", 0),
+		r_max:         d.Float("R", 0),
+		r_min:         d.Float("r", 1),
+		s:             d.Int("EarlyStoppingRate", 0),
+		ResourceName:  d.String("ResourceName", ""),
+		SuggestionNum: d.Int("SuggestionNum", 0),
+		MaxParallel:   d.Int("MaxParallel", 0),
+		samplerKind:   d.String("Sampler", "random"),
+		dim:           len(in.Configs.ParameterConfigs.Configs),
+		rng:           suggestion.NewRand(d.Int64("Seed", time.Now().UnixNano())),
+	}
+	d.Check(p.eta > 1, "Eta must be greater than 1")
+	d.Check(p.r_max > 0 && p.r_min > 0, "R and r must be positive")
+	d.Check(p.ResourceName != "", "ResourceName is required")
+	d.Check(p.SuggestionNum > 0, "SuggestionNum must be positive")
+	d.Check(p.MaxParallel > 0, "MaxParallel must be positive")
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	p.topRung = int(math.Log(p.r_max/p.r_min)/math.Log(p.eta)+1e-9) - p.s
+	if p.topRung < 0 {
+		p.topRung = 0
+	}
+	var err error
+	p.sampler, err = suggestion.NewSampler(p.samplerKind, p.dim, p.SuggestionNum, p.rng.Seed())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Study %v: ASHA top rung %v, random seed %v", in.StudyId, p.topRung, p.rng.Seed())
+	return p, nil
+}
+
+func (p *ASHAParameters) SaveState() ([]byte, error) {
+	return suggestion.EncodeState(&ashaState{Drawn: p.drawn, Rand: p.rng.State()})
+}
+
+func (p *ASHAParameters) LoadState(state []byte) error {
+	st := &ashaState{}
+	if err := suggestion.DecodeState(state, st); err != nil {
+		return err
+	}
+	sampler, err := suggestion.NewSampler(p.samplerKind, p.dim, p.SuggestionNum, st.Rand.Seed)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < st.Drawn; i++ {
+		sampler.Sample()
+	}
+	p.sampler, p.drawn, p.rng = sampler, st.Drawn, suggestion.NewRandFromState(st.Rand)
+	return nil
+}
+
+func (p *ASHAParameters) resource(rung int) int {
+	return int(math.Min(p.r_max, p.r_min*math.Pow(p.eta, float64(p.s+rung))))
+}
+
+func parseTrial(t *api.Trial, ot api.OptimizationType) (*rungTrial, bool) {
+	rt := &rungTrial{params: t.ParameterSet, rung: -1}
+	for _, tag := range t.Tags {
+		switch tag.Name {
+		case configIdTag:
+			rt.configId = tag.Value
+		case rungTag:
+			rt.rung, _ = strconv.Atoi(tag.Value)
+		}
+	}
+	if rt.configId == "" || rt.rung < 0 {
+		return nil, false
+	}
+	v, err := strconv.ParseFloat(t.ObjectiveValue, 64)
+	if err != nil {
+		v = math.Inf(1)
+	} else if ot == api.OptimizationType_MAXIMIZE {
+		v = -v
+	}
+	rt.value = v
+	return rt, true
+}
+
+// promotable returns the best configuration of rung k that is in the top 1/eta and has not been promoted yet.
+func (p *ASHAParameters) promotable(rungs [][]*rungTrial, k int) *rungTrial {
+	var done []*rungTrial
+	for _, rt := range rungs[k] {
+		if !rt.running {
+			done = append(done, rt)
+		}
+	}
+	sort.SliceStable(done, func(i, j int) bool { return done[i].value < done[j].value })
+	promoted := make(map[string]bool)
+	for _, rt := range rungs[k+1] {
+		promoted[rt.configId] = true
+	}
+	for _, rt := range done[:int(float64(len(done))/p.eta)] {
+		if !promoted[rt.configId] && !math.IsInf(rt.value, 1) {
+			return rt
+		}
+	}
+	return nil
+}
+
+func (p *ASHAParameters) makeTrial(configId string, rung int, params []*api.Parameter) *api.Trial {
+	r := p.resource(rung)
+	t := &api.Trial{
+		Status:       api.TrialState_PENDING,
+		EvalLogs:     make([]*api.EvaluationLog, 0),
+		ParameterSet: make([]*api.Parameter, len(params)),
+	}
+	for i, v := range params {
+		t.ParameterSet[i] = &api.Parameter{Name: v.Name, ParameterType: v.ParameterType, Value: v.Value}
+		if v.Name == p.ResourceName {
+			t.ParameterSet[i].Value = strconv.Itoa(r)
+		}
+	}
+	t.Tags = []*api.Tag{
+		{Name: configIdTag, Value: configId},
+		{Name: rungTag, Value: strconv.Itoa(rung)},
+		{Name: resourceTag, Value: strconv.Itoa(r)},
+	}
+	return t
+}
+
+// The rungs are rebuilt from the tags of the trials. Only the positions of the random generators are saved.
+func (p *ASHAParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	rungs := make([][]*rungTrial, p.topRung+1)
+	configs := make(map[string]bool)
+	for _, t := range in.CompletedTrials {
+		if rt, ok := parseTrial(t, in.Configs.OptimizationType); ok && rt.rung <= p.topRung {
+			rungs[rt.rung] = append(rungs[rt.rung], rt)
+			configs[rt.configId] = true
+		}
+	}
+	for _, t := range in.RunningTrials {
+		if rt, ok := parseTrial(t, in.Configs.OptimizationType); ok && rt.rung <= p.topRung {
+			rt.running = true
+			rungs[rt.rung] = append(rungs[rt.rung], rt)
+			configs[rt.configId] = true
+		}
+	}
+
+	var s_t []*api.Trial
+	for len(in.RunningTrials)+len(s_t) < p.MaxParallel {
+		var next *api.Trial
+		for k := p.topRung - 1; k >= 0 && next == nil; k-- {
+			if rt := p.promotable(rungs, k); rt != nil {
+				next = p.makeTrial(rt.configId, k+1, rt.params)
+				rungs[k+1] = append(rungs[k+1], &rungTrial{configId: rt.configId, rung: k + 1, params: rt.params, running: true})
+				log.Printf("Study %v: promote %v to rung %v", in.StudyId, rt.configId, k+1)
+			}
+		}
+		if next == nil && len(configs) < p.SuggestionNum {
+			cid := p.generate_randid()
+			params := suggestion.ParameterSetFromUnit(p.sampler.Sample(), in.Configs.ParameterConfigs.Configs)
+			p.drawn++
+			next = p.makeTrial(cid, 0, params)
+			rungs[0] = append(rungs[0], &rungTrial{configId: cid, rung: 0, params: params, running: true})
+			configs[cid] = true
+		}
+		if next == nil {
+			break
+		}
+		s_t = append(s_t, next)
+	}
+	if len(s_t) == 0 && len(in.RunningTrials) == 0 {
+		return &api.GenerateTrialsReply{Completed: true}, nil
+	}
+	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func studyConfig(ot api.OptimizationType) *api.StudyConfig {
+	return &api.StudyConfig{
+		OptimizationType: ot,
+		ParameterConfigs: &api.StudyConfig_ParameterConfigs{Configs: []*api.ParameterConfig{
+			{Name: "lr", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0", Max: "1"}},
+			{Name: "epochs", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "1", Max: "9"}},
+		}},
+	}
+}
+
+func newStudy(t *testing.T, conf *api.StudyConfig, extra ...*api.SuggestionParameter) *ASHAParameters {
+	sps := append([]*api.SuggestionParameter{
+		{Name: "Eta", Value: "3"},
+		{Name: "R", Value: "9"},
+		{Name: "ResourceName", Value: "epochs"},
+		{Name: "SuggestionNum", Value: "6"},
+		{Name: "MaxParallel", Value: "1"},
+		{Name: "Seed", Value: "1"},
+	}, extra...)
+	st, err := (&ASHASuggestService{}).NewStudy(&api.SetSuggestionParametersRequest{StudyId: "study", SuggestionParameters: sps, Configs: conf})
+	if err != nil {
+		t.Fatalf("NewStudy: %v", err)
+	}
+	return st.(*ASHAParameters)
+}
+
+// trial is a trial of configuration cid at rung with the objective value obj.
+func trial(p *ASHAParameters, cid string, rung int, obj string) *api.Trial {
+	t := p.makeTrial(cid, rung, []*api.Parameter{{Name: "lr", Value: "0.5"}, {Name: "epochs", Value: "1"}})
+	t.ObjectiveValue = obj
+	return t
+}
+
+func tag(t *api.Trial, name string) string {
+	for _, tag := range t.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+func TestResource(t *testing.T) {
+	conf := studyConfig(api.OptimizationType_MINIMIZE)
+	for _, c := range []struct {
+		rate    string
+		topRung int
+		want    []int
+	}{
+		{rate: "0", topRung: 2, want: []int{1, 3, 9}},
+		{rate: "1", topRung: 1, want: []int{3, 9}},
+		{rate: "3", topRung: 0, want: []int{9}},
+	} {
+		p := newStudy(t, conf, &api.SuggestionParameter{Name: "EarlyStoppingRate", Value: c.rate})
+		var got []int
+		for k := 0; k <= p.topRung; k++ {
+			got = append(got, p.resource(k))
+		}
+		if p.topRung != c.topRung || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("EarlyStoppingRate %v: expected the top rung %v and resources %v, got %v %v", c.rate, c.topRung, c.want, p.topRung, got)
+		}
+	}
+}
+
+func TestPromotion(t *testing.T) {
+	p := newStudy(t, studyConfig(api.OptimizationType_MINIMIZE))
+	for _, c := range []struct {
+		name      string
+		ot        api.OptimizationType
+		completed []*api.Trial
+		// config and rung of the next trial, none when the study is completed
+		config string
+		rung   int
+		none   bool
+	}{
+		{
+			name:      "rung not filled",
+			completed: []*api.Trial{trial(p, "a", 0, "0.3"), trial(p, "b", 0, "0.1")},
+			rung:      0,
+		},
+		{
+			name:      "best of three",
+			completed: []*api.Trial{trial(p, "a", 0, "0.3"), trial(p, "b", 0, "0.1"), trial(p, "c", 0, "0.2")},
+			config:    "b",
+			rung:      1,
+		},
+		{
+			name:      "best of three maximized",
+			ot:        api.OptimizationType_MAXIMIZE,
+			completed: []*api.Trial{trial(p, "a", 0, "0.3"), trial(p, "b", 0, "0.1"), trial(p, "c", 0, "0.2")},
+			config:    "a",
+			rung:      1,
+		},
+		{
+			name:      "best already promoted",
+			completed: []*api.Trial{trial(p, "a", 0, "0.3"), trial(p, "b", 0, "0.1"), trial(p, "c", 0, "0.2"), trial(p, "d", 0, "0.4"), trial(p, "b", 1, "")},
+			rung:      0,
+		},
+		{
+			name: "second best of six",
+			completed: []*api.Trial{trial(p, "a", 0, "0.3"), trial(p, "b", 0, "0.1"), trial(p, "c", 0, "0.2"),
+				trial(p, "d", 0, "0.4"), trial(p, "e", 0, "0.5"), trial(p, "f", 0, "0.6"), trial(p, "b", 1, "0.05")},
+			config: "c",
+			rung:   1,
+		},
+		{
+			name:      "failed trials are not promoted",
+			completed: []*api.Trial{trial(p, "a", 0, ""), trial(p, "b", 0, "nan?"), trial(p, "c", 0, "")},
+			rung:      0,
+		},
+		{
+			name: "higher rung first",
+			completed: []*api.Trial{trial(p, "a", 0, "0.3"), trial(p, "b", 0, "0.1"), trial(p, "c", 0, "0.2"),
+				trial(p, "d", 0, "0.4"), trial(p, "e", 0, "0.5"), trial(p, "f", 0, "0.6"),
+				trial(p, "b", 1, "0.05"), trial(p, "c", 1, "0.07"), trial(p, "a", 1, "0.01")},
+			config: "a",
+			rung:   2,
+		},
+		{
+			name: "all configurations sampled",
+			completed: []*api.Trial{trial(p, "a", 0, "0.3"), trial(p, "b", 0, "0.1"), trial(p, "c", 0, "0.2"),
+				trial(p, "d", 0, "0.4"), trial(p, "e", 0, "0.5"), trial(p, "f", 0, "0.6"),
+				trial(p, "b", 1, "0.05"), trial(p, "c", 1, "0.07")},
+			none: true,
+		},
+	} {
+		conf := studyConfig(c.ot)
+		p := newStudy(t, conf)
+		r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: c.completed})
+		if err != nil {
+			t.Errorf("%v: %v", c.name, err)
+			continue
+		}
+		if c.none {
+			if !r.Completed || len(r.Trials) != 0 {
+				t.Errorf("%v: expected the study to be completed, got %v", c.name, r)
+			}
+			continue
+		}
+		if len(r.Trials) != 1 {
+			t.Errorf("%v: expected one trial, got %v", c.name, r.Trials)
+			continue
+		}
+		next := r.Trials[0]
+		rung := fmt.Sprint(c.rung)
+		epochs := fmt.Sprint(p.resource(c.rung))
+		if c.config != "" && tag(next, configIdTag) != c.config || tag(next, rungTag) != rung ||
+			tag(next, resourceTag) != epochs || next.ParameterSet[1].Value != epochs {
+			t.Errorf("%v: expected %v at rung %v with %v epochs, got %v", c.name, c.config, rung, epochs, next)
+		}
+	}
+}
+
+// run completes the suggested trials at once, with the value of lr as objective, until n trials are completed.
+func run(t *testing.T, p *ASHAParameters, conf *api.StudyConfig, completed []*api.Trial, n int) []*api.Trial {
+	for len(completed) < n {
+		r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: completed})
+		if err != nil {
+			t.Fatalf("GenerateTrials: %v", err)
+		}
+		if r.Completed {
+			break
+		}
+		for _, tr := range r.Trials {
+			tr.ObjectiveValue = tr.ParameterSet[0].Value
+			completed = append(completed, tr)
+		}
+	}
+	return completed
+}
+
+func TestSeedAndState(t *testing.T) {
+	conf := studyConfig(api.OptimizationType_MINIMIZE)
+	for _, sampler := range []string{"random", "sobol", "lhs"} {
+		sp := &api.SuggestionParameter{Name: "Sampler", Value: sampler}
+		all := run(t, newStudy(t, conf, sp), conf, nil, 100)
+		// SuggestionNum configurations, and a configuration is promoted after it completes at the rung below
+		rungs := make(map[string]int)
+		for _, tr := range all {
+			cid, rung := tag(tr, configIdTag), tag(tr, rungTag)
+			if fmt.Sprint(rungs[cid]) != rung {
+				t.Errorf("%v: unexpected trial of %v at rung %v", sampler, cid, rung)
+			}
+			rungs[cid]++
+		}
+		if len(rungs) != 6 {
+			t.Errorf("%v: expected 6 configurations, got %v", sampler, rungs)
+		}
+		if !reflect.DeepEqual(all, run(t, newStudy(t, conf, sp), conf, nil, 100)) {
+			t.Errorf("%v: expected the same trials with the same seed", sampler)
+		}
+		if reflect.DeepEqual(all, run(t, newStudy(t, conf, sp, &api.SuggestionParameter{Name: "Seed", Value: "2"}), conf, nil, 100)) {
+			t.Errorf("%v: expected other trials with another seed", sampler)
+		}
+
+		p := newStudy(t, conf, sp)
+		completed := run(t, p, conf, nil, 4)
+		state, err := p.SaveState()
+		if err != nil {
+			t.Fatalf("%v: SaveState: %v", sampler, err)
+		}
+		q := newStudy(t, conf, sp, &api.SuggestionParameter{Name: "Seed", Value: "2"})
+		if err := q.LoadState(state); err != nil {
+			t.Fatalf("%v: LoadState: %v", sampler, err)
+		}
+		n := len(completed)
+		if got := run(t, q, conf, completed[:n:n], 100); !reflect.DeepEqual(got, all) {
+			t.Errorf("%v: expected the same trials after LoadState, got %v", sampler, got)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/suggestion"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	generationTag = "CMAES_Generation"
+	indexTag      = "CMAES_Index"
+)
+
+// candidate is one member of a generation, kept in the normalized [0, 1] space.
+type candidate struct {
+	x         []float64
+	completed bool
+	value     float64
+}
+
+type CMAESParameters struct {
+	SuggestionNum int
+	MaxParallel   int
+	Lambda        int
+	Sigma0        float64
+
+	dim        int
+	mu         int
+	weights    []float64
+	mueff      float64
+	cc         float64
+	cs         float64
+	c1         float64
+	cmu        float64
+	damps      float64
+	chiN       float64
+	mean       []float64
+	sigma      float64
+	cov        [][]float64
+	b          [][]float64
+	d          []float64
+	pc         []float64
+	ps         []float64
+	generation int
+	population []*candidate
+	rng        *suggestion.Rand
+}
+
+type CMAESSuggestService struct {
+}
+
+func NewCMAESSuggestService() *suggestion.Service {
+	return suggestion.NewService(&CMAESSuggestService{})
+}
+
+func (c *CMAESSuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (suggestion.Study, error) {
+	d := suggestion.NewParameterDecoder(in.SuggestionParameters)
+	p := &CMAESParameters{
+		SuggestionNum: d.Int("SuggestionNum", 0),
+		MaxParallel:   d.Int("MaxParallel", 0),
+		Lambda:        d.Int("Lambda", 0),
+		Sigma0:        d.Float("Sigma", 0.3),
+		rng:           suggestion.NewRand(d.Int64("Seed", time.Now().UnixNano())),
+	}
+	d.Check(p.SuggestionNum > 0, "SuggestionNum must be positive")
+	d.Check(p.Sigma0 > 0, "Sigma must be positive")
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	for _, pc := range in.Configs.ParameterConfigs.Configs {
+		if pc.ParameterType == api.ParameterType_CATEGORICAL {
+			return nil, fmt.Errorf("CMA-ES does not support CATEGORICAL parameter %v", pc.Name)
+		}
+		if pc.ParameterType == api.ParameterType_DISCRETE && len(pc.Feasible.List) == 0 {
+			return nil, fmt.Errorf("DISCRETE parameter %v has no feasible list", pc.Name)
+		}
+	}
+	p.dim = len(in.Configs.ParameterConfigs.Configs)
+	if p.dim == 0 {
+		return nil, fmt.Errorf("No parameter to optimize")
+	}
+	p.initState()
+	p.samplePopulation()
+	log.Printf("Study %v: CMA-ES dim %v lambda %v sigma %v, random seed %v", in.StudyId, p.dim, p.Lambda, p.sigma, p.rng.Seed())
+	return p, nil
+}
+
+// initState sets the strategy constants following Hansen's "The CMA Evolution Strategy: A Tutorial".
+func (p *CMAESParameters) initState() {
+	n := float64(p.dim)
+	if p.Lambda < 2 {
+		p.Lambda = 4 + int(3*math.Log(n))
+	}
+	p.mu = p.Lambda / 2
+	p.weights = make([]float64, p.mu)
+	var wsum, w2sum float64
+	for i := range p.weights {
+		p.weights[i] = math.Log(float64(p.mu)+0.5) - math.Log(float64(i+1))
+		wsum += p.weights[i]
+	}
+	for i := range p.weights {
+		p.weights[i] /= wsum
+		w2sum += p.weights[i] * p.weights[i]
+	}
+	p.mueff = 1 / w2sum
+	p.cc = (4 + p.mueff/n) / (n + 4 + 2*p.mueff/n)
+	p.cs = (p.mueff + 2) / (n + p.mueff + 5)
+	p.c1 = 2 / ((n+1.3)*(n+1.3) + p.mueff)
+	p.cmu = math.Min(1-p.c1, 2*(p.mueff-2+1/p.mueff)/((n+2)*(n+2)+p.mueff))
+	p.damps = 1 + 2*math.Max(0, math.Sqrt((p.mueff-1)/(n+1))-1) + p.cs
+	p.chiN = math.Sqrt(n) * (1 - 1/(4*n) + 1/(21*n*n))
+
+	p.mean = make([]float64, p.dim)
+	p.pc = make([]float64, p.dim)
+	p.ps = make([]float64, p.dim)
+	p.d = make([]float64, p.dim)
+	p.cov = identity(p.dim)
+	p.b = identity(p.dim)
+	for i := range p.mean {
+		p.mean[i] = 0.5
+		p.d[i] = 1
+	}
+	p.sigma = p.Sigma0
+}
+
+func (p *CMAESParameters) samplePopulation() {
+	p.population = make([]*candidate, p.Lambda)
+	for k := range p.population {
+		z := make([]float64, p.dim)
+		for i := range z {
+			z[i] = p.d[i] * p.rng.NormFloat64()
+		}
+		x := make([]float64, p.dim)
+		for i := range x {
+			var bz float64
+			for j := range z {
+				bz += p.b[i][j] * z[j]
+			}
+			x[i] = math.Min(1, math.Max(0, p.mean[i]+p.sigma*bz))
+		}
+		p.population[k] = &candidate{x: x}
+	}
+}
+
+// update moves the distribution towards the best candidates of the finished generation.
+func (p *CMAESParameters) update() {
+	pop := make([]*candidate, len(p.population))
+	copy(pop, p.population)
+	sort.SliceStable(pop, func(i, j int) bool { return pop[i].value < pop[j].value })
+
+	old := p.mean
+	p.mean = make([]float64, p.dim)
+	for k := 0; k < p.mu; k++ {
+		for i := range p.mean {
+			p.mean[i] += p.weights[k] * pop[k].x[i]
+		}
+	}
+	yw := make([]float64, p.dim)
+	for i := range yw {
+		yw[i] = (p.mean[i] - old[i]) / p.sigma
+	}
+
+	// C^-1/2 * yw = B * D^-1 * B^T * yw
+	bty := make([]float64, p.dim)
+	for i := range bty {
+		for j := range yw {
+			bty[i] += p.b[j][i] * yw[j]
+		}
+		bty[i] /= p.d[i]
+	}
+	var psnorm float64
+	for i := range p.ps {
+		var v float64
+		for j := range bty {
+			v += p.b[i][j] * bty[j]
+		}
+		p.ps[i] = (1-p.cs)*p.ps[i] + math.Sqrt(p.cs*(2-p.cs)*p.mueff)*v
+		psnorm += p.ps[i] * p.ps[i]
+	}
+	psnorm = math.Sqrt(psnorm)
+
+	var hsig float64
+	if psnorm/math.Sqrt(1-math.Pow(1-p.cs, 2*float64(p.generation+1)))/p.chiN < 1.4+2/float64(p.dim+1) {
+		hsig = 1
+	}
+	for i := range p.pc {
+		p.pc[i] = (1-p.cc)*p.pc[i] + hsig*math.Sqrt(p.cc*(2-p.cc)*p.mueff)*yw[i]
+	}
+
+	for i := 0; i < p.dim; i++ {
+		for j := 0; j < p.dim; j++ {
+			rankmu := 0.0
+			for k := 0; k < p.mu; k++ {
+				rankmu += p.weights[k] * (pop[k].x[i] - old[i]) * (pop[k].x[j] - old[j]) / (p.sigma * p.sigma)
+			}
+			p.cov[i][j] = (1-p.c1-p.cmu)*p.cov[i][j] +
+				p.c1*(p.pc[i]*p.pc[j]+(1-hsig)*p.cc*(2-p.cc)*p.cov[i][j]) +
+				p.cmu*rankmu
+		}
+	}
+	p.sigma *= math.Exp((p.cs / p.damps) * (psnorm/p.chiN - 1))
+
+	eig, vec := jacobiEigen(p.cov)
+	for i := range eig {
+		if eig[i] < 1e-20 {
+			eig[i] = 1e-20
+		}
+		p.d[i] = math.Sqrt(eig[i])
+	}
+	p.b = vec
+	p.generation++
+}
+
+func toParameterSet(x []float64, pcs []*api.ParameterConfig) []*api.Parameter {
+	ps := make([]*api.Parameter, len(pcs))
+	for i, pc := range pcs {
+		ps[i] = &api.Parameter{Name: pc.Name, ParameterType: pc.ParameterType}
+		switch pc.ParameterType {
+		case api.ParameterType_INT:
+			imin, _ := strconv.Atoi(pc.Feasible.Min)
+			imax, _ := strconv.Atoi(pc.Feasible.Max)
+			ps[i].Value = strconv.Itoa(imin + int(math.Floor(x[i]*float64(imax-imin)+0.5)))
+		case api.ParameterType_DOUBLE:
+			dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
+			dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
+			ps[i].Value = strconv.FormatFloat(dmin+x[i]*(dmax-dmin), 'f', 4, 64)
+		case api.ParameterType_DISCRETE:
+			l := make([]float64, len(pc.Feasible.List))
+			for j, v := range pc.Feasible.List {
+				l[j], _ = strconv.ParseFloat(v, 64)
+			}
+			sort.Float64s(l)
+			ps[i].Value = strconv.FormatFloat(l[int(math.Floor(x[i]*float64(len(l)-1)+0.5))], 'f', -1, 64)
+		}
+	}
+	return ps
+}
+
+func objectiveValue(t *api.Trial, ot api.OptimizationType) float64 {
+	v, err := strconv.ParseFloat(t.ObjectiveValue, 64)
+	if err != nil {
+		log.Printf("Trial %v has no valid objective value %q", t.TrialId, t.ObjectiveValue)
+		return math.Inf(1)
+	}
+	if ot == api.OptimizationType_MAXIMIZE {
+		return -v
+	}
+	return v
+}
+
+type candidateState struct {
+	X         []float64
+	Completed bool
+	Value     float64
+}
+
+// cmaesState is the part of CMAESParameters that evolves during the study.
+type cmaesState struct {
+	Mean       []float64
+	Sigma      float64
+	Cov        [][]float64
+	B          [][]float64
+	D          []float64
+	Pc         []float64
+	Ps         []float64
+	Generation int
+	Population []candidateState
+	Rand       suggestion.RandState
+}
+
+func (p *CMAESParameters) SaveState() ([]byte, error) {
+	st := &cmaesState{Mean: p.mean, Sigma: p.sigma, Cov: p.cov, B: p.b, D: p.d, Pc: p.pc, Ps: p.ps, Generation: p.generation, Rand: p.rng.State()}
+	for _, cd := range p.population {
+		st.Population = append(st.Population, candidateState{X: cd.x, Completed: cd.completed, Value: cd.value})
+	}
+	return suggestion.EncodeState(st)
+}
+
+func (p *CMAESParameters) LoadState(state []byte) error {
+	st := &cmaesState{}
+	if err := suggestion.DecodeState(state, st); err != nil {
+		return err
+	}
+	if len(st.Mean) != p.dim {
+		return fmt.Errorf("Saved state has %v parameters, expected %v", len(st.Mean), p.dim)
+	}
+	p.mean, p.sigma, p.cov, p.b, p.d, p.pc, p.ps, p.generation = st.Mean, st.Sigma, st.Cov, st.B, st.D, st.Pc, st.Ps, st.Generation
+	p.rng = suggestion.NewRandFromState(st.Rand)
+	p.population = nil
+	for _, cd := range st.Population {
+		p.population = append(p.population, &candidate{x: cd.X, completed: cd.Completed, value: cd.Value})
+	}
+	return nil
+}
+
+func (p *CMAESParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	if len(in.CompletedTrials) >= p.SuggestionNum {
+		return &api.GenerateTrialsReply{Completed: true}, nil
+	}
+	for _, t := range in.CompletedTrials {
+		i, ok := p.candidateIndex(t)
+		if !ok || p.population[i].completed {
+			continue
+		}
+		p.population[i].completed = true
+		p.population[i].value = objectiveValue(t, in.Configs.OptimizationType)
+	}
+	done := true
+	for _, cand := range p.population {
+		if !cand.completed {
+			done = false
+			break
+		}
+	}
+	if done {
+		p.update()
+		p.samplePopulation()
+		log.Printf("Study %v: CMA-ES generation %v sigma %v", in.StudyId, p.generation, p.sigma)
+	}
+
+	reqnum := len(p.population)
+	if p.MaxParallel > 0 {
+		reqnum = p.MaxParallel - len(in.RunningTrials)
+	}
+	if remain := p.SuggestionNum - len(in.CompletedTrials) - len(in.RunningTrials); remain < reqnum {
+		reqnum = remain
+	}
+	// a candidate is suggested again until a trial of it completes, so that a trial which is never run,
+	// or which is lost, does not stall the generation.
+	running := make(map[int]bool)
+	for _, t := range in.RunningTrials {
+		if i, ok := p.candidateIndex(t); ok {
+			running[i] = true
+		}
+	}
+	var s_t []*api.Trial
+	for i, cand := range p.population {
+		if len(s_t) >= reqnum {
+			break
+		}
+		if cand.completed || running[i] {
+			continue
+		}
+		s_t = append(s_t, &api.Trial{
+			ParameterSet: toParameterSet(cand.x, in.Configs.ParameterConfigs.Configs),
+			Status:       api.TrialState_PENDING,
+			EvalLogs:     make([]*api.EvaluationLog, 0),
+			Tags: []*api.Tag{
+				{Name: generationTag, Value: strconv.Itoa(p.generation)},
+				{Name: indexTag, Value: strconv.Itoa(i)},
+			},
+		})
+	}
+	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
+}
+
+// candidateIndex returns the index in the population of the trial of a candidate of the current generation.
+func (p *CMAESParameters) candidateIndex(t *api.Trial) (int, bool) {
+	var gen, idx string
+	for _, tag := range t.Tags {
+		switch tag.Name {
+		case generationTag:
+			gen = tag.Value
+		case indexTag:
+			idx = tag.Value
+		}
+	}
+	if gen != strconv.Itoa(p.generation) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(idx)
+	if err != nil || i < 0 || i >= len(p.population) {
+		return 0, false
+	}
+	return i, true
+}
+
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+// jacobiEigen returns the eigenvalues and the eigenvectors (as columns) of the symmetric matrix a.
+func jacobiEigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		for j := range m[i] {
+			// enforce symmetry against rounding drift
+			m[i][j] = (a[i][j] + a[j][i]) / 2
+		}
+	}
+	v := identity(n)
+	for sweep := 0; sweep < 100; sweep++ {
+		var off float64
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += m[i][j] * m[i][j]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(m[p][q]) < 1e-300 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				cs := 1 / math.Sqrt(t*t+1)
+				sn := t * cs
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = cs*mkp - sn*mkq
+					m[k][q] = sn*mkp + cs*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = cs*mpk - sn*mqk
+					m[q][k] = sn*mpk + cs*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = cs*vkp - sn*vkq
+					v[k][q] = sn*vkp + cs*vkq
+				}
+			}
+		}
+	}
+	eig := make([]float64, n)
+	for i := range eig {
+		eig[i] = m[i][i]
+	}
+	return eig, v
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func studyConfig() *api.StudyConfig {
+	return &api.StudyConfig{
+		OptimizationType: api.OptimizationType_MINIMIZE,
+		ParameterConfigs: &api.StudyConfig_ParameterConfigs{Configs: []*api.ParameterConfig{
+			{Name: "x", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0", Max: "1"}},
+			{Name: "y", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0", Max: "1"}},
+		}},
+	}
+}
+
+func newStudy(t *testing.T, conf *api.StudyConfig, params map[string]string) *CMAESParameters {
+	var sps []*api.SuggestionParameter
+	for n, v := range params {
+		sps = append(sps, &api.SuggestionParameter{Name: n, Value: v})
+	}
+	st, err := (&CMAESSuggestService{}).NewStudy(&api.SetSuggestionParametersRequest{StudyId: "study", SuggestionParameters: sps, Configs: conf})
+	if err != nil {
+		t.Fatalf("NewStudy %v: %v", params, err)
+	}
+	return st.(*CMAESParameters)
+}
+
+func values(tr *api.Trial) []float64 {
+	var ret []float64
+	for _, p := range tr.ParameterSet {
+		v, _ := strconv.ParseFloat(p.Value, 64)
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// sphere is minimized at (0.2, 0.7).
+func sphere(tr *api.Trial) float64 {
+	x := values(tr)
+	return (x[0]-0.2)*(x[0]-0.2) + (x[1]-0.7)*(x[1]-0.7)
+}
+
+// run completes the suggested trials at once until the study is completed or n trials are completed,
+// and returns the completed trials in order.
+func run(t *testing.T, p *CMAESParameters, conf *api.StudyConfig, completed []*api.Trial, n int) []*api.Trial {
+	for i := 0; len(completed) < n; i++ {
+		r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: completed})
+		if err != nil {
+			t.Fatalf("GenerateTrials: %v", err)
+		}
+		if r.Completed {
+			return completed
+		}
+		for j, tr := range r.Trials {
+			tr.TrialId = fmt.Sprintf("%v-%v", i, j)
+			tr.Status = api.TrialState_COMPLETED
+			tr.ObjectiveValue = strconv.FormatFloat(sphere(tr), 'f', -1, 64)
+			completed = append(completed, tr)
+		}
+	}
+	return completed
+}
+
+func TestNewStudyErrors(t *testing.T) {
+	categorical := studyConfig()
+	categorical.ParameterConfigs.Configs[0] = &api.ParameterConfig{Name: "opt", ParameterType: api.ParameterType_CATEGORICAL, Feasible: &api.FeasibleSpace{List: []string{"adam", "sgd"}}}
+	discrete := studyConfig()
+	discrete.ParameterConfigs.Configs[0] = &api.ParameterConfig{Name: "n", ParameterType: api.ParameterType_DISCRETE, Feasible: &api.FeasibleSpace{}}
+	for _, c := range []struct {
+		name   string
+		conf   *api.StudyConfig
+		params map[string]string
+	}{
+		{name: "no SuggestionNum", conf: studyConfig(), params: map[string]string{}},
+		{name: "negative Sigma", conf: studyConfig(), params: map[string]string{"SuggestionNum": "10", "Sigma": "-1"}},
+		{name: "invalid Seed", conf: studyConfig(), params: map[string]string{"SuggestionNum": "10", "Seed": "x"}},
+		{name: "categorical", conf: categorical, params: map[string]string{"SuggestionNum": "10"}},
+		{name: "discrete without list", conf: discrete, params: map[string]string{"SuggestionNum": "10"}},
+		{name: "no parameter", conf: &api.StudyConfig{ParameterConfigs: &api.StudyConfig_ParameterConfigs{}}, params: map[string]string{"SuggestionNum": "10"}},
+	} {
+		var sps []*api.SuggestionParameter
+		for n, v := range c.params {
+			sps = append(sps, &api.SuggestionParameter{Name: n, Value: v})
+		}
+		if _, err := (&CMAESSuggestService{}).NewStudy(&api.SetSuggestionParametersRequest{SuggestionParameters: sps, Configs: c.conf}); err == nil {
+			t.Errorf("%v: expected an error", c.name)
+		}
+	}
+}
+
+func TestGenerations(t *testing.T) {
+	conf := studyConfig()
+	p := newStudy(t, conf, map[string]string{"SuggestionNum": "120", "Lambda": "6", "Seed": "1"})
+	trials := run(t, p, conf, nil, 1000)
+	// the distribution is updated when the trials of the next generation are requested, so not after the last one
+	if len(trials) != 120 || p.generation != 19 {
+		t.Fatalf("Expected 120 trials in 20 generations, got %v in %v", len(trials), p.generation+1)
+	}
+	for _, tr := range trials {
+		for _, v := range values(tr) {
+			if v < 0 || v > 1 {
+				t.Errorf("Trial %v is out of the feasible space", values(tr))
+			}
+		}
+	}
+	if d := math.Hypot(p.mean[0]-0.2, p.mean[1]-0.7); d > 0.05 {
+		t.Errorf("Expected the mean to move to the minimum, got %v", p.mean)
+	}
+	if p.sigma >= p.Sigma0 {
+		t.Errorf("Expected the step size to shrink from %v, got %v", p.Sigma0, p.sigma)
+	}
+	// the best trials of the last generation are better than the ones of the first
+	best := func(ts []*api.Trial) float64 {
+		b := math.Inf(1)
+		for _, tr := range ts {
+			b = math.Min(b, sphere(tr))
+		}
+		return b
+	}
+	if first, last := best(trials[:6]), best(trials[114:]); last >= first {
+		t.Errorf("Expected the last generation to improve on %v, got %v", first, last)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	conf := studyConfig()
+	for _, c := range []struct {
+		seed1, seed2 string
+		same         bool
+	}{
+		{seed1: "1", seed2: "1", same: true},
+		{seed1: "1", seed2: "2", same: false},
+	} {
+		t1 := run(t, newStudy(t, conf, map[string]string{"SuggestionNum": "24", "Seed": c.seed1}), conf, nil, 1000)
+		t2 := run(t, newStudy(t, conf, map[string]string{"SuggestionNum": "24", "Seed": c.seed2}), conf, nil, 1000)
+		if reflect.DeepEqual(t1, t2) != c.same {
+			t.Errorf("Seeds %v and %v: expected the same trials %v", c.seed1, c.seed2, c.same)
+		}
+	}
+}
+
+func TestSaveLoadState(t *testing.T) {
+	conf := studyConfig()
+	params := map[string]string{"SuggestionNum": "40", "Lambda": "4", "Seed": "3"}
+	p := newStudy(t, conf, params)
+	// the study is in the middle of its third generation
+	completed := run(t, p, conf, nil, 10)
+	state, err := p.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+	q := newStudy(t, conf, params)
+	if err := q.LoadState(state); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if !reflect.DeepEqual(p.mean, q.mean) || p.sigma != q.sigma || p.generation != q.generation || p.rng.State() != q.rng.State() {
+		t.Errorf("Expected the restored state %v %v %v, got %v %v %v", p.mean, p.sigma, p.generation, q.mean, q.sigma, q.generation)
+	}
+	// the restored study suggests the same trials, also in the next generations
+	n := len(completed)
+	pt := run(t, p, conf, completed[:n:n], 1000)
+	qt := run(t, q, conf, completed[:n:n], 1000)
+	if !reflect.DeepEqual(pt, qt) {
+		t.Errorf("Expected the same trials after LoadState")
+	}
+
+	other := studyConfig()
+	other.ParameterConfigs.Configs = other.ParameterConfigs.Configs[:1]
+	if err := newStudy(t, other, params).LoadState(state); err == nil {
+		t.Errorf("Expected an error for a state of another dimension")
+	}
+}
+
+func TestLostCandidates(t *testing.T) {
+	conf := studyConfig()
+	p := newStudy(t, conf, map[string]string{"SuggestionNum": "20", "Lambda": "4", "Seed": "1"})
+	generate := func(completed, running []*api.Trial) []*api.Trial {
+		r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: completed, RunningTrials: running})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Trials
+	}
+	first := generate(nil, nil)
+	if len(first) != 4 {
+		t.Fatalf("Expected a generation of 4 trials, got %v", len(first))
+	}
+	// the trials were never run, so the same candidates are suggested again
+	if again := generate(nil, nil); !reflect.DeepEqual(again, first) {
+		t.Errorf("Expected the candidates again, got %v", again)
+	}
+	// the running and completed candidates are not
+	first[0].ObjectiveValue = "0.5"
+	again := generate(first[:1], first[1:3])
+	if len(again) != 1 || !reflect.DeepEqual(again[0].Tags, first[3].Tags) {
+		t.Errorf("Expected the last candidate, got %v", again)
+	}
+	// a failed trial completes its candidate with the worst value
+	first[1].Status = api.TrialState_ERROR
+	first[2].ObjectiveValue, first[3].ObjectiveValue = "0.1", "0.2"
+	generate(first, nil)
+	if p.generation != 1 {
+		t.Errorf("Expected the next generation, got %v", p.generation)
+	}
+}
@@ -0,0 +1,139 @@
+package suggestion
+
+import (
+	"context"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"log"
+	"sync"
+)
+
+// Algorithm creates the state of a study from its suggestion parameters.
+// It is the only part a new suggestion service has to implement; Service does the rest.
+type Algorithm interface {
+	NewStudy(in *api.SetSuggestionParametersRequest) (Study, error)
+}
+
+// Study is the state of one study. Service never calls it concurrently.
+// When GenerateTrials returns Completed, the study is removed from the Service.
+type Study interface {
+	GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error)
+}
+
+// StateSaver is implemented by studies whose state cannot be rebuilt from the suggestion parameters and the trials alone.
+// The saved state is returned in GenerateTrialsReply.State; the manager stores it and gives it
+// back in GenerateTrialsRequest.State so that a restarted service can continue the study.
+type StateSaver interface {
+	SaveState() ([]byte, error)
+	// LoadState is called on a study just created by NewStudy, so it only needs to restore what changes during the study.
+	LoadState(state []byte) error
+}
+
+type studyEntry struct {
+	mu    sync.Mutex
+	study Study
+}
+
+// Service implements api.SuggestionServer on top of an Algorithm.
+// Different studies are served concurrently, the calls for the same study are serialized.
+type Service struct {
+	alg     Algorithm
+	mu      sync.Mutex
+	studies map[string]*studyEntry
+}
+
+func NewService(alg Algorithm) *Service {
+	return &Service{alg: alg, studies: make(map[string]*studyEntry)}
+}
+
+func (s *Service) SetSuggestionParameters(ctx context.Context, in *api.SetSuggestionParametersRequest) (*api.SetSuggestionParametersReply, error) {
+	st, err := s.alg.NewStudy(in)
+	if err != nil {
+		log.Printf("Failed to Suggestion Parameter set. %v", err)
+		return &api.SetSuggestionParametersReply{}, err
+	}
+	s.mu.Lock()
+	s.studies[in.StudyId] = &studyEntry{study: st}
+	s.mu.Unlock()
+	return &api.SetSuggestionParametersReply{}, nil
+}
+
+// lock returns the locked entry of the study of in.
+// A study the service does not know, e.g. after a restart, is set up again from the request.
+func (s *Service) lock(ctx context.Context, in *api.GenerateTrialsRequest) (*studyEntry, error) {
+	s.mu.Lock()
+	e, ok := s.studies[in.StudyId]
+	if !ok {
+		e = &studyEntry{}
+		s.studies[in.StudyId] = e
+	}
+	s.mu.Unlock()
+	e.mu.Lock()
+	if e.study != nil {
+		return e, nil
+	}
+	st, err := s.restore(in)
+	if err != nil {
+		e.mu.Unlock()
+		s.remove(in.StudyId, e)
+		return nil, err
+	}
+	e.study = st
+	return e, nil
+}
+
+func (s *Service) restore(in *api.GenerateTrialsRequest) (Study, error) {
+	if in.Configs == nil {
+		return nil, fmt.Errorf("Study %v is not initialized", in.StudyId)
+	}
+	log.Printf("Study %v is not initialized. Restore it.", in.StudyId)
+	st, err := s.alg.NewStudy(&api.SetSuggestionParametersRequest{
+		StudyId:              in.StudyId,
+		SuggestionParameters: in.Configs.SuggestionParameters,
+		Configs:              in.Configs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ss, ok := st.(StateSaver); ok && len(in.State) > 0 {
+		if err = ss.LoadState(in.State); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+func (s *Service) remove(studyId string, e *studyEntry) {
+	s.mu.Lock()
+	if s.studies[studyId] == e {
+		delete(s.studies, studyId)
+	}
+	s.mu.Unlock()
+}
+
+func (s *Service) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	e, err := s.lock(ctx, in)
+	if err != nil {
+		return &api.GenerateTrialsReply{Completed: false}, err
+	}
+	defer e.mu.Unlock()
+	r, err := e.study.GenerateTrials(ctx, in)
+	if err != nil {
+		return r, err
+	}
+	if r.Completed {
+		s.remove(in.StudyId, e)
+		return r, nil
+	}
+	if ss, ok := e.study.(StateSaver); ok {
+		r.State, err = ss.SaveState()
+	}
+	return r, err
+}
+
+func (s *Service) StopSuggestion(ctx context.Context, in *api.StopSuggestionRequest) (*api.StopSuggestionReply, error) {
+	s.mu.Lock()
+	delete(s.studies, in.StudyId)
+	s.mu.Unlock()
+	return &api.StopSuggestionReply{}, nil
+}
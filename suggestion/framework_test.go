@@ -0,0 +1,213 @@
+package suggestion
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlkube/katib/api"
+)
+
+// testAlgorithm creates studies that count their GenerateTrials calls and complete after Limit calls.
+type testAlgorithm struct {
+	// enter, when set, is called at the start of each GenerateTrials
+	enter func(in *api.GenerateTrialsRequest)
+}
+
+type testStudy struct {
+	alg   *testAlgorithm
+	limit int
+	calls int
+}
+
+func (a *testAlgorithm) NewStudy(in *api.SetSuggestionParametersRequest) (Study, error) {
+	d := NewParameterDecoder(in.SuggestionParameters)
+	st := &testStudy{alg: a, limit: d.Int("Limit", 0)}
+	d.Check(st.limit > 0, "Limit must be positive")
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (st *testStudy) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	if st.alg.enter != nil {
+		st.alg.enter(in)
+	}
+	st.calls++
+	return &api.GenerateTrialsReply{Completed: st.calls >= st.limit}, nil
+}
+
+func (st *testStudy) SaveState() ([]byte, error) {
+	return EncodeState(st.calls)
+}
+
+func (st *testStudy) LoadState(state []byte) error {
+	return DecodeState(state, &st.calls)
+}
+
+func testConfig(limit string) *api.StudyConfig {
+	return &api.StudyConfig{SuggestionParameters: []*api.SuggestionParameter{{Name: "Limit", Value: limit}}}
+}
+
+// calls is the number of calls recorded in the state of r.
+func calls(t *testing.T, r *api.GenerateTrialsReply) int {
+	var n int
+	if err := DecodeState(r.State, &n); err != nil {
+		t.Fatalf("DecodeState %v: %v", r.State, err)
+	}
+	return n
+}
+
+func TestServiceState(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(&testAlgorithm{})
+	if _, err := s.SetSuggestionParameters(ctx, &api.SetSuggestionParametersRequest{StudyId: "a", SuggestionParameters: testConfig("10").SuggestionParameters}); err != nil {
+		t.Fatalf("SetSuggestionParameters: %v", err)
+	}
+	var r *api.GenerateTrialsReply
+	for i := 0; i < 3; i++ {
+		var err error
+		if r, err = s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "a"}); err != nil {
+			t.Fatalf("GenerateTrials: %v", err)
+		}
+	}
+	if calls(t, r) != 3 {
+		t.Errorf("Expected the state after 3 calls, got %v", calls(t, r))
+	}
+
+	// a restarted service sets the study up again from the configuration and the state of the request
+	for _, c := range []struct {
+		name  string
+		conf  *api.StudyConfig
+		state []byte
+		calls int
+		err   bool
+	}{
+		{name: "no configuration", state: r.State, err: true},
+		{name: "invalid suggestion parameters", conf: testConfig("0"), state: r.State, err: true},
+		{name: "invalid state", conf: testConfig("10"), state: []byte("x"), err: true},
+		{name: "no state", conf: testConfig("10"), calls: 1},
+		{name: "saved state", conf: testConfig("10"), state: r.State, calls: 4},
+	} {
+		s := NewService(&testAlgorithm{})
+		got, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "a", Configs: c.conf, State: c.state})
+		if c.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", c.name)
+			}
+			// the study is not kept, so that the next call sets it up again
+			if len(s.studies) != 0 {
+				t.Errorf("%v: expected no study, got %v", c.name, s.studies)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", c.name, err)
+			continue
+		}
+		if calls(t, got) != c.calls {
+			t.Errorf("%v: expected the state after %v calls, got %v", c.name, c.calls, calls(t, got))
+		}
+	}
+}
+
+func TestServiceLocking(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	active := make(map[string]int)
+	blocked := make(chan bool)
+	release := make(chan bool)
+	alg := &testAlgorithm{enter: func(in *api.GenerateTrialsRequest) {
+		mu.Lock()
+		active[in.StudyId]++
+		n := active[in.StudyId]
+		mu.Unlock()
+		if n > 1 {
+			t.Errorf("Study %v: %v concurrent calls", in.StudyId, n)
+		}
+		if in.StudyId == "blocked" {
+			blocked <- true
+			<-release
+		} else {
+			time.Sleep(time.Millisecond)
+		}
+		mu.Lock()
+		active[in.StudyId]--
+		mu.Unlock()
+	}}
+	s := NewService(alg)
+
+	// a study waiting in GenerateTrials does not hold up the other studies
+	done := make(chan error)
+	go func() {
+		_, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "blocked", Configs: testConfig("10")})
+		done <- err
+	}()
+	<-blocked
+	other := make(chan error)
+	go func() {
+		_, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "other", Configs: testConfig("10")})
+		other <- err
+	}()
+	select {
+	case err := <-other:
+		if err != nil {
+			t.Errorf("GenerateTrials: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected a study to be served while another one is busy")
+	}
+	release <- true
+	if err := <-done; err != nil {
+		t.Errorf("GenerateTrials: %v", err)
+	}
+
+	// the calls for the same study are serialized, including the first one that sets it up
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "same", Configs: testConfig("100")}); err != nil {
+				t.Errorf("GenerateTrials: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	r, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "same"})
+	if err != nil {
+		t.Fatalf("GenerateTrials: %v", err)
+	}
+	if calls(t, r) != 21 {
+		t.Errorf("Expected 21 calls on one study, got %v", calls(t, r))
+	}
+}
+
+func TestServiceRemove(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(&testAlgorithm{})
+	for i, completed := range []bool{false, true} {
+		r, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "a", Configs: testConfig("2")})
+		if err != nil || r.Completed != completed {
+			t.Errorf("Call %v: expected Completed %v, got %v %v", i, completed, r, err)
+		}
+	}
+	// a completed study is removed, and a study is unknown again after StopSuggestion
+	if _, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "a"}); err == nil {
+		t.Errorf("Expected an error for a completed study")
+	}
+	if _, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "b", Configs: testConfig("2")}); err != nil {
+		t.Fatalf("GenerateTrials: %v", err)
+	}
+	if _, err := s.StopSuggestion(ctx, &api.StopSuggestionRequest{StudyId: "b"}); err != nil {
+		t.Fatalf("StopSuggestion: %v", err)
+	}
+	if _, err := s.GenerateTrials(ctx, &api.GenerateTrialsRequest{StudyId: "b"}); err == nil {
+		t.Errorf("Expected an error for a stopped study")
+	}
+	if len(s.studies) != 0 {
+		t.Errorf("Expected no study, got %v", s.studies)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/suggestion"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GeneticParameters struct {
+	SuggestionNum  int
+	MaxParallel    int
+	PopulationSize int
+	TournamentSize int
+	CrossoverRate  float64
+	MutationRate   float64
+	rng            *suggestion.Rand
+}
+
+type GeneticSuggestService struct {
+}
+
+type individual struct {
+	params []*api.Parameter
+	// fitness is larger for better individuals regardless of the optimization type
+	fitness float64
+}
+
+func NewGeneticSuggestService() *suggestion.Service {
+	return suggestion.NewService(&GeneticSuggestService{})
+}
+
+func (g *GeneticSuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (suggestion.Study, error) {
+	d := suggestion.NewParameterDecoder(in.SuggestionParameters)
+	p := &GeneticParameters{
+		SuggestionNum:  d.Int("SuggestionNum", 0),
+		MaxParallel:    d.Int("MaxParallel", 0),
+		PopulationSize: d.Int("PopulationSize", 20),
+		TournamentSize: d.Int("TournamentSize", 3),
+		CrossoverRate:  d.Float("CrossoverRate", 0.9),
+		MutationRate:   d.Float("MutationRate", 0.1),
+		rng:            suggestion.NewRand(d.Int64("Seed", time.Now().UnixNano())),
+	}
+	d.Check(p.SuggestionNum > 0, "SuggestionNum must be positive")
+	d.Check(p.PopulationSize >= 2, "PopulationSize must be at least 2")
+	d.Check(p.TournamentSize >= 1, "TournamentSize must be positive")
+	d.Check(p.CrossoverRate >= 0 && p.CrossoverRate <= 1, "CrossoverRate must be in [0, 1]")
+	d.Check(p.MutationRate >= 0 && p.MutationRate <= 1, "MutationRate must be in [0, 1]")
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	log.Printf("Study %v: genetic random seed %v", in.StudyId, p.rng.Seed())
+	return p, nil
+}
+
+// The population is rebuilt from the completed trials. Only the position of the random generator is saved.
+func (p *GeneticParameters) SaveState() ([]byte, error) {
+	return suggestion.EncodeState(p.rng.State())
+}
+
+func (p *GeneticParameters) LoadState(state []byte) error {
+	var st suggestion.RandState
+	if err := suggestion.DecodeState(state, &st); err != nil {
+		return err
+	}
+	p.rng = suggestion.NewRandFromState(st)
+	return nil
+}
+
+// population returns the best PopulationSize completed trials.
+func (p *GeneticParameters) population(completed []*api.Trial, ot api.OptimizationType) []*individual {
+	var pop []*individual
+	for _, t := range completed {
+		v, err := strconv.ParseFloat(t.ObjectiveValue, 64)
+		if err != nil {
+			continue
+		}
+		if ot == api.OptimizationType_MINIMIZE {
+			v = -v
+		}
+		pop = append(pop, &individual{params: t.ParameterSet, fitness: v})
+	}
+	sort.SliceStable(pop, func(i, j int) bool { return pop[i].fitness > pop[j].fitness })
+	if len(pop) > p.PopulationSize {
+		pop = pop[:p.PopulationSize]
+	}
+	return pop
+}
+
+func (p *GeneticParameters) tournament(pop []*individual) *individual {
+	var best *individual
+	for i := 0; i < p.TournamentSize; i++ {
+		c := pop[p.rng.Intn(len(pop))]
+		if best == nil || c.fitness > best.fitness {
+			best = c
+		}
+	}
+	return best
+}
+
+func value(ps []*api.Parameter, name string) string {
+	for _, v := range ps {
+		if v.Name == name {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// crossover picks each gene from one of the parents (uniform crossover).
+func (p *GeneticParameters) crossover(a *individual, b *individual, pcs []*api.ParameterConfig) []*api.Parameter {
+	child := make([]*api.Parameter, len(pcs))
+	cross := p.rng.Float64() < p.CrossoverRate
+	for i, pc := range pcs {
+		v := value(a.params, pc.Name)
+		if cross && p.rng.Intn(2) == 1 {
+			v = value(b.params, pc.Name)
+		}
+		child[i] = &api.Parameter{Name: pc.Name, ParameterType: pc.ParameterType, Value: v}
+	}
+	return child
+}
+
+// mutate changes each gene with probability MutationRate within the feasible space of its ParameterConfig.
+// INT, DOUBLE and DISCRETE values take a gaussian step of 10% of the range, CATEGORICAL values are replaced by another one.
+func (p *GeneticParameters) mutate(child []*api.Parameter, pcs []*api.ParameterConfig, force bool) {
+	for i, pc := range pcs {
+		if !force && p.rng.Float64() >= p.MutationRate {
+			continue
+		}
+		switch pc.ParameterType {
+		case api.ParameterType_INT:
+			imin, _ := strconv.Atoi(pc.Feasible.Min)
+			imax, _ := strconv.Atoi(pc.Feasible.Max)
+			iv, err := strconv.Atoi(child[i].Value)
+			if err != nil || imax == imin {
+				child[i].Value = strconv.Itoa(imin)
+				break
+			}
+			step := int(math.Floor(p.rng.NormFloat64()*0.1*float64(imax-imin) + 0.5))
+			if step == 0 {
+				step = 2*p.rng.Intn(2) - 1
+			}
+			child[i].Value = strconv.Itoa(int(math.Min(float64(imax), math.Max(float64(imin), float64(iv+step)))))
+		case api.ParameterType_DOUBLE:
+			dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
+			dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
+			dv, err := strconv.ParseFloat(child[i].Value, 64)
+			if err != nil {
+				dv = (dmin + dmax) / 2
+			}
+			dv += p.rng.NormFloat64() * 0.1 * (dmax - dmin)
+			child[i].Value = strconv.FormatFloat(math.Min(dmax, math.Max(dmin, dv)), 'f', 4, 64)
+		case api.ParameterType_DISCRETE:
+			n := len(pc.Feasible.List)
+			j := 0
+			for k, l := range pc.Feasible.List {
+				if l == child[i].Value {
+					j = k
+				}
+			}
+			step := int(math.Floor(p.rng.NormFloat64()*0.1*float64(n) + 0.5))
+			if step == 0 {
+				step = 2*p.rng.Intn(2) - 1
+			}
+			j = int(math.Min(float64(n-1), math.Max(0, float64(j+step))))
+			child[i].Value = pc.Feasible.List[j]
+		case api.ParameterType_CATEGORICAL:
+			var others []string
+			for _, l := range pc.Feasible.List {
+				if l != child[i].Value {
+					others = append(others, l)
+				}
+			}
+			if len(others) > 0 {
+				child[i].Value = others[p.rng.Intn(len(others))]
+			}
+		}
+	}
+}
+
+func key(ps []*api.Parameter) string {
+	s := make([]string, len(ps))
+	for i, v := range ps {
+		s[i] = v.Name + "=" + v.Value
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (p *GeneticParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	if len(in.CompletedTrials) >= p.SuggestionNum {
+		return &api.GenerateTrialsReply{Completed: true}, nil
+	}
+	reqnum := p.SuggestionNum - len(in.CompletedTrials) - len(in.RunningTrials)
+	if p.MaxParallel > 0 && p.MaxParallel-len(in.RunningTrials) < reqnum {
+		reqnum = p.MaxParallel - len(in.RunningTrials)
+	}
+	if reqnum <= 0 {
+		return &api.GenerateTrialsReply{Completed: false}, nil
+	}
+	pcs := in.Configs.ParameterConfigs.Configs
+	seen := make(map[string]bool)
+	for _, t := range in.CompletedTrials {
+		seen[key(t.ParameterSet)] = true
+	}
+	for _, t := range in.RunningTrials {
+		seen[key(t.ParameterSet)] = true
+	}
+	pop := p.population(in.CompletedTrials, in.Configs.OptimizationType)
+	s_t := make([]*api.Trial, reqnum)
+	for i := range s_t {
+		initial := len(in.CompletedTrials)+len(in.RunningTrials)+i < p.PopulationSize || len(pop) < 2
+		var child []*api.Parameter
+		for retry := 0; retry < 10; retry++ {
+			if initial {
+				// the initial population is sampled at random
+				x := make([]float64, len(pcs))
+				for d := range x {
+					x[d] = p.rng.Float64()
+				}
+				child = suggestion.ParameterSetFromUnit(x, pcs)
+			} else {
+				child = p.crossover(p.tournament(pop), p.tournament(pop), pcs)
+				// a child identical to a known trial is mutated once more
+				p.mutate(child, pcs, retry > 0)
+			}
+			if !seen[key(child)] {
+				break
+			}
+		}
+		seen[key(child)] = true
+		s_t[i] = &api.Trial{
+			ParameterSet: child,
+			Status:       api.TrialState_PENDING,
+			EvalLogs:     make([]*api.EvaluationLog, 0),
+		}
+	}
+	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func parameterConfigs() []*api.ParameterConfig {
+	return []*api.ParameterConfig{
+		{Name: "lr", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0", Max: "1"}},
+		{Name: "layers", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "2", Max: "5"}},
+		{Name: "batch", ParameterType: api.ParameterType_DISCRETE, Feasible: &api.FeasibleSpace{List: []string{"16", "32", "64"}}},
+		{Name: "optimizer", ParameterType: api.ParameterType_CATEGORICAL, Feasible: &api.FeasibleSpace{List: []string{"adam", "sgd", "rmsprop"}}},
+	}
+}
+
+func studyConfig(ot api.OptimizationType) *api.StudyConfig {
+	return &api.StudyConfig{OptimizationType: ot, ParameterConfigs: &api.StudyConfig_ParameterConfigs{Configs: parameterConfigs()}}
+}
+
+func newStudy(t *testing.T, params map[string]string) *GeneticParameters {
+	sps := []*api.SuggestionParameter{{Name: "SuggestionNum", Value: "30"}, {Name: "PopulationSize", Value: "6"}, {Name: "Seed", Value: "1"}}
+	for n, v := range params {
+		sps = append(sps, &api.SuggestionParameter{Name: n, Value: v})
+	}
+	st, err := (&GeneticSuggestService{}).NewStudy(&api.SetSuggestionParametersRequest{StudyId: "study", SuggestionParameters: sps, Configs: studyConfig(api.OptimizationType_MINIMIZE)})
+	if err != nil {
+		t.Fatalf("NewStudy %v: %v", params, err)
+	}
+	return st.(*GeneticParameters)
+}
+
+func parameters(values ...string) []*api.Parameter {
+	ps := make([]*api.Parameter, len(values))
+	for i, pc := range parameterConfigs() {
+		ps[i] = &api.Parameter{Name: pc.Name, ParameterType: pc.ParameterType, Value: values[i]}
+	}
+	return ps
+}
+
+func values(ps []*api.Parameter) []string {
+	var ret []string
+	for _, v := range ps {
+		ret = append(ret, v.Value)
+	}
+	return ret
+}
+
+func TestPopulation(t *testing.T) {
+	p := newStudy(t, map[string]string{"PopulationSize": "2"})
+	var completed []*api.Trial
+	for _, obj := range []string{"0.3", "", "0.1", "n/a", "0.2"} {
+		completed = append(completed, &api.Trial{ParameterSet: parameters(obj, "2", "16", "adam"), ObjectiveValue: obj})
+	}
+	for _, c := range []struct {
+		ot   api.OptimizationType
+		want []float64
+	}{
+		{ot: api.OptimizationType_MINIMIZE, want: []float64{-0.1, -0.2}},
+		{ot: api.OptimizationType_MAXIMIZE, want: []float64{0.3, 0.2}},
+	} {
+		var got []float64
+		for _, ind := range p.population(completed, c.ot) {
+			got = append(got, ind.fitness)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: expected the fitnesses %v, got %v", c.ot, c.want, got)
+		}
+	}
+}
+
+func TestTournament(t *testing.T) {
+	pop := []*individual{{fitness: 3}, {fitness: 1}, {fitness: 2}}
+	// a tournament of one picks any individual, a large one picks the best
+	for _, c := range []struct {
+		size string
+		want map[float64]bool
+	}{
+		{size: "1", want: map[float64]bool{1: true, 2: true, 3: true}},
+		{size: "50", want: map[float64]bool{3: true}},
+	} {
+		p := newStudy(t, map[string]string{"TournamentSize": c.size})
+		got := make(map[float64]bool)
+		for i := 0; i < 100; i++ {
+			got[p.tournament(pop).fitness] = true
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("TournamentSize %v: expected the winners %v, got %v", c.size, c.want, got)
+		}
+	}
+}
+
+func TestCrossover(t *testing.T) {
+	a := &individual{params: parameters("0.1", "2", "16", "adam")}
+	b := &individual{params: parameters("0.9", "5", "64", "sgd")}
+	for _, c := range []struct {
+		rate string
+		// fromB is whether some genes come from b
+		fromB bool
+	}{
+		{rate: "0", fromB: false},
+		{rate: "1", fromB: true},
+	} {
+		p := newStudy(t, map[string]string{"CrossoverRate": c.rate})
+		fromB := false
+		for i := 0; i < 20; i++ {
+			child := p.crossover(a, b, parameterConfigs())
+			for j, v := range child {
+				if v.Name != a.params[j].Name || v.Value != a.params[j].Value && v.Value != b.params[j].Value {
+					t.Errorf("CrossoverRate %v: gene %v comes from no parent", c.rate, v)
+				}
+				if v.Value == b.params[j].Value {
+					fromB = true
+				}
+			}
+		}
+		if fromB != c.fromB {
+			t.Errorf("CrossoverRate %v: expected genes of the second parent %v, got %v", c.rate, c.fromB, fromB)
+		}
+	}
+}
+
+func TestMutate(t *testing.T) {
+	parent := parameters("0.5", "3", "32", "adam")
+	for _, c := range []struct {
+		name  string
+		rate  string
+		force bool
+		// changed is whether every gene changes, or none
+		changed bool
+	}{
+		{name: "no mutation", rate: "0"},
+		{name: "always", rate: "1", changed: true},
+		{name: "forced", rate: "0", force: true, changed: true},
+	} {
+		p := newStudy(t, map[string]string{"MutationRate": c.rate})
+		for i := 0; i < 20; i++ {
+			child := parameters(values(parent)...)
+			p.mutate(child, parameterConfigs(), c.force)
+			lr, _ := strconv.ParseFloat(child[0].Value, 64)
+			layers, _ := strconv.Atoi(child[1].Value)
+			if lr < 0 || lr > 1 || layers < 2 || layers > 5 {
+				t.Errorf("%v: %v is out of the feasible space", c.name, values(child))
+			}
+			// the DOUBLE gene may keep its value when the step is clipped, the others always move
+			for j := 1; j < len(child); j++ {
+				if (child[j].Value != parent[j].Value) != c.changed {
+					t.Errorf("%v: expected gene %v changed %v, got %v", c.name, parent[j], c.changed, child[j])
+				}
+			}
+		}
+	}
+}
+
+// run completes the suggested trials at once, with lr as objective, until the study is completed or n trials are completed.
+func run(t *testing.T, p *GeneticParameters, completed []*api.Trial, n int) []*api.Trial {
+	conf := studyConfig(api.OptimizationType_MINIMIZE)
+	for len(completed) < n {
+		r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: completed})
+		if err != nil {
+			t.Fatalf("GenerateTrials: %v", err)
+		}
+		if r.Completed {
+			break
+		}
+		for _, tr := range r.Trials {
+			tr.TrialId = fmt.Sprintf("t%v", len(completed))
+			tr.ObjectiveValue = tr.ParameterSet[0].Value
+			completed = append(completed, tr)
+		}
+	}
+	return completed
+}
+
+func TestSeedAndState(t *testing.T) {
+	params := map[string]string{"MaxParallel": "2"}
+	all := run(t, newStudy(t, params), nil, 1000)
+	if len(all) != 30 {
+		t.Errorf("Expected SuggestionNum trials, got %v", len(all))
+	}
+	seen := make(map[string]bool)
+	for _, tr := range all {
+		if seen[key(tr.ParameterSet)] {
+			t.Errorf("Trial %v is suggested twice", values(tr.ParameterSet))
+		}
+		seen[key(tr.ParameterSet)] = true
+	}
+	if !reflect.DeepEqual(all, run(t, newStudy(t, params), nil, 1000)) {
+		t.Errorf("Expected the same trials with the same seed")
+	}
+	params["Seed"] = "2"
+	if reflect.DeepEqual(all, run(t, newStudy(t, params), nil, 1000)) {
+		t.Errorf("Expected other trials with another seed")
+	}
+
+	params["Seed"] = "1"
+	p := newStudy(t, params)
+	completed := run(t, p, nil, 10)
+	state, err := p.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+	params["Seed"] = "2"
+	q := newStudy(t, params)
+	if err := q.LoadState(state); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if got := run(t, q, completed[:10:10], 1000); !reflect.DeepEqual(got, all) {
+		t.Errorf("Expected the same trials after LoadState, got %v", got)
+	}
+}
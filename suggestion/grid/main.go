@@ -4,50 +4,42 @@ import (
 	"context"
 	"fmt"
 	"github.com/mlkube/katib/api"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
+	"github.com/mlkube/katib/suggestion"
 	"log"
-	"net"
 	"strconv"
 )
 
-const (
-	port = "0.0.0.0:6789"
-)
-
 type GridSuggestParameters struct {
 	defaultGridNum int
 	gridConfig     map[string]int
 	MaxParallel    int
+	grids          [][]*api.Parameter
+	gridPointer    int
 }
 
 type GridSuggestService struct {
-	parameters  map[string]*GridSuggestParameters
-	grids       map[string][][]*api.Parameter
-	gridPointer map[string]int
 }
 
-func NewGridSuggestService() *GridSuggestService {
-	return &GridSuggestService{parameters: make(map[string]*GridSuggestParameters), grids: make(map[string][][]*api.Parameter), gridPointer: make(map[string]int)}
+func NewGridSuggestService() *suggestion.Service {
+	return suggestion.NewService(&GridSuggestService{})
 }
 
-func (s *GridSuggestService) SetSuggestionParameters(ctx context.Context, in *api.SetSuggestionParametersRequest) (*api.SetSuggestionParametersReply, error) {
+func (s *GridSuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (suggestion.Study, error) {
+	d := suggestion.NewParameterDecoder(in.SuggestionParameters)
 	p := &GridSuggestParameters{gridConfig: make(map[string]int)}
-	for _, sp := range in.SuggestionParameters {
-		switch sp.Name {
-		case "DefaultGrid":
-			p.defaultGridNum, _ = strconv.Atoi(sp.Value)
-		case "MaxParrallel":
-			p.MaxParallel, _ = strconv.Atoi(sp.Value)
-		default:
-			p.gridConfig[sp.Name], _ = strconv.Atoi(sp.Value)
-		}
+	p.defaultGridNum = d.Int("DefaultGrid", 0)
+	// MaxParrallel is the spelling of older study configs.
+	p.MaxParallel = d.Int("MaxParallel", d.Int("MaxParrallel", 0))
+	for _, name := range d.Unused() {
+		p.gridConfig[name] = d.Int(name, 0)
 	}
-	s.parameters[in.StudyId] = p
-	return &api.SetSuggestionParametersReply{}, nil
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
-func (s *GridSuggestService) allocInt(min int, max int, reqnum int) []string {
+func (p *GridSuggestParameters) allocInt(min int, max int, reqnum int) []string {
 	ret := make([]string, reqnum)
 	if reqnum == 1 {
 		ret[0] = strconv.Itoa(min)
@@ -59,7 +51,7 @@ func (s *GridSuggestService) allocInt(min int, max int, reqnum int) []string {
 	return ret
 }
 
-func (s *GridSuggestService) allocFloat(min float64, max float64, reqnum int) []string {
+func (p *GridSuggestParameters) allocFloat(min float64, max float64, reqnum int) []string {
 	ret := make([]string, reqnum)
 	if reqnum == 1 {
 		ret[0] = strconv.FormatFloat(min, 'f', 4, 64)
@@ -71,7 +63,7 @@ func (s *GridSuggestService) allocFloat(min float64, max float64, reqnum int) []
 	return ret
 }
 
-func (s *GridSuggestService) allocCat(list []string, reqnum int) []string {
+func (p *GridSuggestParameters) allocCat(list []string, reqnum int) []string {
 	ret := make([]string, reqnum)
 	if reqnum == 1 {
 		ret[0] = list[0]
@@ -84,7 +76,7 @@ func (s *GridSuggestService) allocCat(list []string, reqnum int) []string {
 	return ret
 }
 
-func (s *GridSuggestService) setP(gci int, p [][]*api.Parameter, pg [][]string, pcs []*api.ParameterConfig) {
+func (g *GridSuggestParameters) setP(gci int, p [][]*api.Parameter, pg [][]string, pcs []*api.ParameterConfig) {
 	if gci == len(pg)-1 {
 		for i := range pg[gci] {
 			p[i] = append(p[i], &api.Parameter{
@@ -105,19 +97,19 @@ func (s *GridSuggestService) setP(gci int, p [][]*api.Parameter, pg [][]string,
 					Value:         pg[gci][i],
 				})
 			}
-			s.setP(gci+1, p[d*i:d*(i+1)], pg, pcs)
+			g.setP(gci+1, p[d*i:d*(i+1)], pg, pcs)
 		}
 	}
 }
 
-func (s *GridSuggestService) genGrids(studyId string, pcs []*api.ParameterConfig) [][]*api.Parameter {
+func (p *GridSuggestParameters) genGrids(studyId string, pcs []*api.ParameterConfig) [][]*api.Parameter {
 	var pg [][]string
 	var holenum int = 1
 	gcl := make([]int, len(pcs))
 	for i, pc := range pcs {
-		gc, ok := s.parameters[studyId].gridConfig[pc.Name]
+		gc, ok := p.gridConfig[pc.Name]
 		if !ok {
-			gc = s.parameters[studyId].defaultGridNum
+			gc = p.defaultGridNum
 		}
 		holenum *= gc
 		gcl[i] = gc
@@ -125,71 +117,60 @@ func (s *GridSuggestService) genGrids(studyId string, pcs []*api.ParameterConfig
 		case api.ParameterType_INT:
 			imin, _ := strconv.Atoi(pc.Feasible.Min)
 			imax, _ := strconv.Atoi(pc.Feasible.Max)
-			pg = append(pg, s.allocInt(imin, imax, gc))
+			pg = append(pg, p.allocInt(imin, imax, gc))
 		case api.ParameterType_DOUBLE:
 			dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
 			dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
-			pg = append(pg, s.allocFloat(dmin, dmax, gc))
+			pg = append(pg, p.allocFloat(dmin, dmax, gc))
 		case api.ParameterType_CATEGORICAL:
-			pg = append(pg, s.allocCat(pc.Feasible.List, gc))
+			pg = append(pg, p.allocCat(pc.Feasible.List, gc))
 		}
 	}
 	ret := make([][]*api.Parameter, holenum)
-	s.setP(0, ret, pg, pcs)
+	p.setP(0, ret, pg, pcs)
 	log.Printf("Study %v : %v parameters generated", studyId, holenum)
 	return ret
 }
 
-func (s *GridSuggestService) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
-	if _, ok := s.grids[in.StudyId]; !ok {
-		s.grids[in.StudyId] = s.genGrids(in.StudyId, in.Configs.ParameterConfigs.Configs)
-		s.gridPointer[in.StudyId] = 0
+// The grids are rebuilt from the suggestion parameters, so only the grid pointer is saved.
+func (p *GridSuggestParameters) SaveState() ([]byte, error) {
+	return suggestion.EncodeState(p.gridPointer)
+}
+
+func (p *GridSuggestParameters) LoadState(state []byte) error {
+	return suggestion.DecodeState(state, &p.gridPointer)
+}
+
+func (p *GridSuggestParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	if p.grids == nil {
+		p.grids = p.genGrids(in.StudyId, in.Configs.ParameterConfigs.Configs)
 	}
-	if s.gridPointer[in.StudyId] >= len(s.grids[in.StudyId]) {
+	if p.gridPointer >= len(p.grids) {
 		if len(in.RunningTrials) == 0 {
-			s.StopSuggestion(ctx, &api.StopSuggestionRequest{StudyId: in.StudyId})
 			return &api.GenerateTrialsReply{Completed: true}, nil
 		} else {
 			return &api.GenerateTrialsReply{Completed: false}, nil
 		}
 	}
 	var reqnum int = 0
-	if s.parameters[in.StudyId].MaxParallel <= 0 {
-		reqnum = len(s.grids[in.StudyId])
-	} else if len(s.grids[in.StudyId])-s.gridPointer[in.StudyId] < s.parameters[in.StudyId].MaxParallel-len(in.RunningTrials) {
-		reqnum = len(s.grids[in.StudyId]) - s.gridPointer[in.StudyId]
-	} else if len(in.RunningTrials) < s.parameters[in.StudyId].MaxParallel {
-		reqnum = s.parameters[in.StudyId].MaxParallel - len(in.RunningTrials)
+	if p.MaxParallel <= 0 {
+		reqnum = len(p.grids)
+	} else if len(p.grids)-p.gridPointer < p.MaxParallel-len(in.RunningTrials) {
+		reqnum = len(p.grids) - p.gridPointer
+	} else if len(in.RunningTrials) < p.MaxParallel {
+		reqnum = p.MaxParallel - len(in.RunningTrials)
 	}
 	s_t := make([]*api.Trial, reqnum)
 	for i := 0; i < int(reqnum); i++ {
 		s_t[i] = &api.Trial{}
 		s_t[i].Status = api.TrialState_PENDING
 		s_t[i].EvalLogs = make([]*api.EvaluationLog, 0)
-		s_t[i].ParameterSet = s.grids[in.StudyId][s.gridPointer[in.StudyId]+i]
+		s_t[i].ParameterSet = p.grids[p.gridPointer+i]
 	}
-	s.gridPointer[in.StudyId] += reqnum
+	p.gridPointer += reqnum
 	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
 }
 
-func (s *GridSuggestService) StopSuggestion(ctx context.Context, in *api.StopSuggestionRequest) (*api.StopSuggestionReply, error) {
-	delete(s.gridPointer, in.StudyId)
-	delete(s.grids, in.StudyId)
-	delete(s.parameters, in.StudyId)
-	return &api.StopSuggestionReply{}, nil
-}
-
 func main() {
-	listener, err := net.Listen("tcp", port)
-	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
-	}
-	size := 1<<31 - 1
-	s := grpc.NewServer(grpc.MaxRecvMsgSize(size), grpc.MaxSendMsgSize(size))
-	api.RegisterSuggestionServer(s, NewGridSuggestService())
-	reflection.Register(s)
-	log.Printf("Grid Search Suggestion Service\n")
-	if err = s.Serve(listener); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
-	}
+	suggestion.Serve("Grid Search", NewGridSuggestService())
 }
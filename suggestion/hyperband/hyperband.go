@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"crypto/rand"
 	"fmt"
 	"github.com/mlkube/katib/api"
 	"github.com/mlkube/katib/suggestion"
@@ -10,214 +9,338 @@ import (
 	"math"
 	"sort"
 	"strconv"
+	"time"
 )
 
+const (
+	bracketIdTag = "HyperBand_BracketID"
+	sTag         = "HyperBand_s"
+	rTag         = "HyperBand_r"
+	shiTag       = "HyperBand_shi"
+)
+
+// Bracket holds the trials of one rung of a successive halving bracket.
 type Bracket []*api.Trial
 
-func (b Bracket) Len() int {
-	return len(b)
+// sort orders the trials from the best to the worst. Trials without a valid objective value come last.
+func (b Bracket) sort(ot api.OptimizationType) {
+	sort.SliceStable(b, func(i, j int) bool {
+		vi, oki := loss(b[i], ot)
+		vj, okj := loss(b[j], ot)
+		if oki != okj {
+			return oki
+		}
+		return vi < vj
+	})
 }
 
-func (b Bracket) Swap(i, j int) {
-	b[i], b[j] = b[j], b[i]
+// loss returns the objective value of t in the minimizing direction.
+func loss(t *api.Trial, ot api.OptimizationType) (float64, bool) {
+	v, err := strconv.ParseFloat(t.ObjectiveValue, 64)
+	if err != nil || math.IsNaN(v) {
+		return 0, false
+	}
+	if ot == api.OptimizationType_MAXIMIZE {
+		v = -v
+	}
+	return v, true
 }
 
-func (b Bracket) Less(i, j int) bool {
-	vi, _ := strconv.ParseFloat(b[i].ObjectiveValue, 64)
-	vj, _ := strconv.ParseFloat(b[j].ObjectiveValue, 64)
-	return vi > vj
+func tagValue(t *api.Trial, name string) string {
+	for _, tag := range t.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
 }
 
 type HyperBandParameters struct {
-	eta           float64
-	sMax          int
-	b_l           float64
-	r_l           float64
-	r             float64
-	n             int
-	shloopitr     int
-	currentS      int
-	MasterBracket Bracket
-	ResourceName  string
+	eta          float64
+	sMax         int
+	r_l          float64
+	MaxParallel  int
+	ResourceName string
+
+	// BOHB model settings
+	minPoints      int
+	gamma          float64
+	randomFraction float64
+	numCandidates  int
+	bohb           bool
+
+	rng *suggestion.Rand
 }
 type HyperBandSuggestService struct {
-	suggestion.RandomSuggestService
-	parameters map[string]*HyperBandParameters
+	// bohb replaces the random sampling of new brackets by a TPE model
+	// once enough trials are completed at a budget.
+	bohb bool
+}
+
+func NewHyperBandSuggestService() *suggestion.Service {
+	return suggestion.NewService(&HyperBandSuggestService{})
 }
 
-func NewHyperBandSuggestService() *HyperBandSuggestService {
-	return &HyperBandSuggestService{parameters: make(map[string]*HyperBandParameters)}
+func NewBOHBSuggestService() *suggestion.Service {
+	return suggestion.NewService(&HyperBandSuggestService{bohb: true})
 }
 
-func (h *HyperBandSuggestService) generate_randid() string {
-	// UUID isn't quite handy in the Go world
-	id_ := make([]byte, 8)
-	_, err := rand.Read(id_)
-	if err != nil {
-		log.Fatalf("Error reading random: %v", err)
+// generate_randid draws the ID from the study generator, so that the same seed gives the same trials.
+func (p *HyperBandParameters) generate_randid() string {
+	return fmt.Sprintf("%016x", p.rng.Uint64())
+}
+
+// makeModel builds a TPE sampler from the completed trials of the largest budget
+// that has at least minPoints results. It returns nil when no budget qualifies.
+func (p *HyperBandParameters) makeModel(sconf *api.StudyConfig, completed []*api.Trial) *suggestion.TPESampler {
+	obs := make(map[int][]suggestion.Observation)
+	for _, c := range completed {
+		v, ok := loss(c, sconf.OptimizationType)
+		if !ok {
+			continue
+		}
+		for _, t := range c.Tags {
+			if t.Name == rTag {
+				r, _ := strconv.Atoi(t.Value)
+				obs[r] = append(obs[r], suggestion.Observation{X: suggestion.UnitFromParameterSet(c.ParameterSet, sconf.ParameterConfigs.Configs), Loss: v})
+			}
+		}
+	}
+	budget := -1
+	for r, o := range obs {
+		if len(o) >= p.minPoints && r > budget {
+			budget = r
+		}
+	}
+	if budget < 0 {
+		return nil
 	}
-	return fmt.Sprintf("%016x", id_)
+	log.Printf("BOHB model on budget %v with %v observations", budget, len(obs[budget]))
+	m := suggestion.NewTPESampler(obs[budget], p.gamma, p.rng.Rand)
+	if m != nil {
+		m.NumCandidates = p.numCandidates
+	}
+	return m
 }
 
-func (h *HyperBandSuggestService) makeMasterBracket(sconf *api.StudyConfig, n int) Bracket {
-	log.Printf("Make MasterBracket %v Trials", n)
-	s_t := make([]*api.Trial, n)
+// sampleConfigs returns n new parameter sets for the bottom rung of a bracket.
+func (p *HyperBandParameters) sampleConfigs(sconf *api.StudyConfig, n int, completed []*api.Trial) [][]*api.Parameter {
+	var model *suggestion.TPESampler
+	if p.bohb {
+		model = p.makeModel(sconf, completed)
+	}
+	ret := make([][]*api.Parameter, n)
 	for i := 0; i < n; i++ {
-		s_t[i] = &api.Trial{}
-		s_t[i].ParameterSet = make([]*api.Parameter, len(sconf.ParameterConfigs.Configs))
+		if model != nil && p.rng.Float64() >= p.randomFraction {
+			ret[i] = suggestion.ParameterSetFromUnit(model.Sample(), sconf.ParameterConfigs.Configs)
+			continue
+		}
+		ret[i] = make([]*api.Parameter, len(sconf.ParameterConfigs.Configs))
 		for j, pc := range sconf.ParameterConfigs.Configs {
-			s_t[i].ParameterSet[j] = &api.Parameter{Name: pc.Name}
+			ret[i][j] = &api.Parameter{Name: pc.Name}
 			switch pc.ParameterType {
 			case api.ParameterType_INT:
 				imin, _ := strconv.Atoi(pc.Feasible.Min)
 				imax, _ := strconv.Atoi(pc.Feasible.Max)
-				s_t[i].ParameterSet[j].Value = strconv.Itoa(h.IntRandom(imin, imax))
+				ret[i][j].Value = strconv.Itoa(p.rng.IntRandom(imin, imax))
 			case api.ParameterType_DOUBLE:
 				dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
 				dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
-				s_t[i].ParameterSet[j].Value = strconv.FormatFloat(h.DoubelRandom(dmin, dmax), 'f', 4, 64)
+				ret[i][j].Value = strconv.FormatFloat(p.rng.DoubelRandom(dmin, dmax), 'f', 4, 64)
 			case api.ParameterType_CATEGORICAL:
-				s_t[i].ParameterSet[j].Value = pc.Feasible.List[h.IntRandom(0, len(pc.Feasible.List)-1)]
+				ret[i][j].Value = pc.Feasible.List[p.rng.IntRandom(0, len(pc.Feasible.List)-1)]
 			}
 		}
-		s_t[i].Tags = append(s_t[i].Tags, &api.Tag{Name: "HyperBand_BracketID", Value: h.generate_randid()})
 	}
-	return Bracket(s_t)
+	return ret
 }
 
-func (h *HyperBandSuggestService) SetSuggestionParameters(ctx context.Context, in *api.SetSuggestionParametersRequest) (*api.SetSuggestionParametersReply, error) {
-	p := &HyperBandParameters{}
-	for _, sp := range in.SuggestionParameters {
-		switch sp.Name {
-		case "Eta":
-			p.eta, _ = strconv.ParseFloat(sp.Value, 64)
-		case "R":
-			p.r_l, _ = strconv.ParseFloat(sp.Value, 64)
-		case "ResourceName":
-			p.ResourceName = sp.Value
-		default:
-			log.Printf("Unknown Suggestion Parameter %v", sp.Name)
-		}
+func (h *HyperBandSuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (suggestion.Study, error) {
+	p := &HyperBandParameters{
+		minPoints:      len(in.Configs.ParameterConfigs.Configs) + 1,
+		gamma:          0.15,
+		randomFraction: 1.0 / 3,
+		numCandidates:  64,
+		bohb:           h.bohb,
 	}
-	if p.eta == 0 || p.r_l == 0 || p.ResourceName == "" {
-		log.Printf("Failed to Suggestion Parameter set.")
-		return &api.SetSuggestionParametersReply{}, fmt.Errorf("Suggestion Parameter set Error")
+	d := suggestion.NewParameterDecoder(in.SuggestionParameters)
+	p.rng = suggestion.NewRand(d.Int64("Seed", time.Now().UnixNano()))
+	p.eta = d.Float("Eta", 0)
+	p.r_l = d.Float("R", 0)
+	p.ResourceName = d.String("ResourceName", "")
+	p.MaxParallel = d.Int("MaxParallel", 0)
+	p.minPoints = d.Int("MinPoints", p.minPoints)
+	p.gamma = d.Float("Gamma", p.gamma)
+	p.randomFraction = d.Float("RandomFraction", p.randomFraction)
+	p.numCandidates = d.Int("NumCandidates", p.numCandidates)
+	d.Check(p.eta > 1, "Eta must be greater than 1")
+	d.Check(p.r_l >= 1, "R must be at least 1")
+	d.Check(p.ResourceName != "", "ResourceName is required")
+	d.Check(p.numCandidates > 0, "NumCandidates must be positive")
+	d.Check(p.gamma > 0 && p.gamma < 1, "Gamma must be between 0 and 1")
+	d.Check(p.randomFraction >= 0 && p.randomFraction <= 1, "RandomFraction must be between 0 and 1")
+	if err := d.Err(); err != nil {
+		return nil, err
 	}
-	p.sMax = int(math.Log(p.r_l) / math.Log(p.eta))
-	p.b_l = float64((p.sMax + 1.0)) * p.r_l
-	p.n = int((p.b_l/p.r_l)*(math.Pow(p.eta, float64(p.sMax))/float64(p.sMax+1))) + 1
-	p.currentS = p.sMax + 1
-	p.shloopitr = p.currentS + 1
-	p.r = p.r_l * math.Pow(p.eta, float64(-p.sMax))
-	p.MasterBracket = h.makeMasterBracket(in.Configs, p.n)
-	h.parameters[in.StudyId] = p
-	log.Printf("Smax = %v", p.sMax)
-	return &api.SetSuggestionParametersReply{}, nil
+	p.sMax = int(math.Log(p.r_l)/math.Log(p.eta) + 1e-9)
+	log.Printf("Study %v: Smax = %v, random seed %v", in.StudyId, p.sMax, p.rng.Seed())
+	return p, nil
 }
 
-func (h *HyperBandSuggestService) getHyperParameter(studyId string, sconf *api.StudyConfig, n int) Bracket {
-	s_t := make([]*api.Trial, n)
-	for i := 0; i < n; i++ {
-		s_t[i] = &api.Trial{}
-		s_t[i].ParameterSet = make([]*api.Parameter, len(sconf.ParameterConfigs.Configs))
-		s_t[i].Status = api.TrialState_PENDING
-		s_t[i].EvalLogs = make([]*api.EvaluationLog, 0)
-		var j int
-		if sconf.OptimizationType == api.OptimizationType_MAXIMIZE {
-			j = i
-		} else if sconf.OptimizationType == api.OptimizationType_MINIMIZE {
-			j = len(h.parameters[studyId].MasterBracket) - 1 - i
-		}
-		for k, v := range h.parameters[studyId].MasterBracket[j].ParameterSet {
-			s_t[i].ParameterSet[k] = v
-		}
-		for _, t := range h.parameters[studyId].MasterBracket[j].Tags {
-			s_t[i].Tags = append(s_t[i].Tags, t)
-		}
-	}
+// The brackets are rebuilt from the trials, only the position of the random generator is saved.
+func (p *HyperBandParameters) SaveState() ([]byte, error) {
+	return suggestion.EncodeState(p.rng.State())
+}
 
-	return Bracket(s_t)
+func (p *HyperBandParameters) LoadState(state []byte) error {
+	var st suggestion.RandState
+	if err := suggestion.DecodeState(state, &st); err != nil {
+		return err
+	}
+	p.rng = suggestion.NewRandFromState(st)
+	return nil
 }
-func (h *HyperBandSuggestService) hbLoopParamUpdate(studyId string) {
-	log.Printf("HB loop s = %v", h.parameters[studyId].currentS)
-	h.parameters[studyId].shloopitr = 0
-	h.parameters[studyId].n = int((h.parameters[studyId].b_l/h.parameters[studyId].r_l)*(math.Pow(h.parameters[studyId].eta, float64(h.parameters[studyId].currentS))/float64(h.parameters[studyId].currentS+1))) + 1
-	h.parameters[studyId].r = h.parameters[studyId].r_l * math.Pow(h.parameters[studyId].eta, float64(-h.parameters[studyId].currentS))
+
+// rungSize returns the number of configurations and the resource of rung i of bracket s.
+func (p *HyperBandParameters) rungSize(s int, i int) (int, int) {
+	n := int(float64(p.sMax+1)*math.Pow(p.eta, float64(s))/float64(s+1)) + 1
+	r := p.r_l * math.Pow(p.eta, float64(-s))
+	return int(float64(n) * math.Pow(p.eta, float64(-i))), int(r * math.Pow(p.eta, float64(i)))
 }
 
-func (h *HyperBandSuggestService) shLoopParamUpdate(studyId string) (int, int) {
-	log.Printf("SH loop i = %v", h.parameters[studyId].shloopitr)
-	pn_i := int(float64(h.parameters[studyId].n) * math.Pow(h.parameters[studyId].eta, float64(-h.parameters[studyId].shloopitr+1)) / h.parameters[studyId].eta)
-	r_i := int(h.parameters[studyId].r * math.Pow(h.parameters[studyId].eta, float64(h.parameters[studyId].shloopitr)))
-	return pn_i, r_i
+func (p *HyperBandParameters) makeTrial(params []*api.Parameter, configId string, s int, i int, r_i int) *api.Trial {
+	t := &api.Trial{
+		Status:       api.TrialState_PENDING,
+		EvalLogs:     make([]*api.EvaluationLog, 0),
+		ParameterSet: make([]*api.Parameter, len(params)),
+	}
+	for k, v := range params {
+		t.ParameterSet[k] = &api.Parameter{Name: v.Name, ParameterType: v.ParameterType, Value: v.Value}
+		if v.Name == p.ResourceName {
+			t.ParameterSet[k].Value = strconv.Itoa(r_i)
+		}
+	}
+	t.Tags = []*api.Tag{
+		{Name: bracketIdTag, Value: configId},
+		{Name: sTag, Value: strconv.Itoa(s)},
+		{Name: rTag, Value: strconv.Itoa(r_i)},
+		{Name: shiTag, Value: strconv.Itoa(i)},
+	}
+	log.Printf("Gen Trial %v", t.Tags)
+	return t
 }
 
-func (h *HyperBandSuggestService) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
-	if h.parameters[in.StudyId].currentS <= 0 {
-		h.StopSuggestion(ctx, &api.StopSuggestionRequest{StudyId: in.StudyId})
-		return &api.GenerateTrialsReply{Completed: true}, nil
+// rungs sorts the tagged trials into rungs[s][i], the trials of rung i of bracket s,
+// and counts the running ones.
+func (p *HyperBandParameters) rungs(in *api.GenerateTrialsRequest) ([][]Bracket, [][]int) {
+	rungs := make([][]Bracket, p.sMax+1)
+	running := make([][]int, p.sMax+1)
+	for s := range rungs {
+		rungs[s] = make([]Bracket, s+1)
+		running[s] = make([]int, s+1)
 	}
-	if len(in.RunningTrials) > 0 {
-		return &api.GenerateTrialsReply{Completed: false}, nil
-	}
-	if len(in.CompletedTrials) > 0 {
-		var schec int
-		var bid string
-		for _, c := range in.CompletedTrials {
-			schec = 0
-			for _, t := range c.Tags {
-				if t.Name == "HyperBand_shi" && t.Value == strconv.Itoa(h.parameters[in.StudyId].shloopitr) {
-					schec++
-				}
-				if t.Name == "HyperBand_s" && t.Value == strconv.Itoa(h.parameters[in.StudyId].currentS) {
-					schec++
+	add := func(t *api.Trial, isRunning bool) {
+		s, err1 := strconv.Atoi(tagValue(t, sTag))
+		i, err2 := strconv.Atoi(tagValue(t, shiTag))
+		if err1 != nil || err2 != nil || s < 0 || s > p.sMax || i < 0 || i > s {
+			return
+		}
+		rungs[s][i] = append(rungs[s][i], t)
+		if isRunning {
+			running[s][i]++
+		}
+	}
+	for _, t := range in.CompletedTrials {
+		add(t, false)
+	}
+	for _, t := range in.RunningTrials {
+		add(t, true)
+	}
+	return rungs, running
+}
+
+// advance returns up to limit new trials of bracket s (no limit when negative) and whether the bracket is finished.
+// Rung i+1 is filled with the best configurations of rung i once every trial of rung i is completed.
+func (p *HyperBandParameters) advance(s int, rungs []Bracket, running []int, limit int, in *api.GenerateTrialsRequest) ([]*api.Trial, bool) {
+	for i := 0; i <= s; i++ {
+		n_i, r_i := p.rungSize(s, i)
+		var candidates Bracket
+		if i > 0 {
+			for _, t := range rungs[i-1] {
+				if _, ok := loss(t, in.Configs.OptimizationType); ok {
+					candidates = append(candidates, t)
 				}
-				if t.Name == "HyperBand_BracketID" {
-					bid = t.Value
+			}
+			candidates.sort(in.Configs.OptimizationType)
+			if len(candidates) < n_i {
+				n_i = len(candidates)
+			}
+		}
+		if n_i == 0 {
+			return nil, true
+		}
+		want := n_i - len(rungs[i])
+		if limit >= 0 && want > limit {
+			want = limit
+		}
+		if want > 0 {
+			var s_t []*api.Trial
+			if i == 0 {
+				log.Printf("HB bracket s = %v: %v new configurations", s, want)
+				for _, params := range p.sampleConfigs(in.Configs, want, in.CompletedTrials) {
+					s_t = append(s_t, p.makeTrial(params, p.generate_randid(), s, 0, r_i))
 				}
+				return s_t, false
 			}
-			if schec == 2 {
-				for _, b := range h.parameters[in.StudyId].MasterBracket {
-					for _, t := range b.Tags {
-						if t.Name == "HyperBand_BracketID" && t.Value == bid {
-							b.ObjectiveValue = c.ObjectiveValue
-						}
-					}
+			issued := make(map[string]bool)
+			for _, t := range rungs[i] {
+				issued[tagValue(t, bracketIdTag)] = true
+			}
+			for _, t := range candidates[:n_i] {
+				if len(s_t) >= want {
+					break
+				}
+				if cid := tagValue(t, bracketIdTag); !issued[cid] {
+					s_t = append(s_t, p.makeTrial(t.ParameterSet, cid, s, i, r_i))
 				}
 			}
+			return s_t, false
 		}
-		sort.Sort(h.parameters[in.StudyId].MasterBracket)
-	}
-	var evalT []*api.Trial
-	var r_i int
-	if h.parameters[in.StudyId].shloopitr > h.parameters[in.StudyId].currentS {
-		h.parameters[in.StudyId].currentS--
-		h.hbLoopParamUpdate(in.StudyId)
-		_, r_i = h.shLoopParamUpdate(in.StudyId)
-		h.parameters[in.StudyId].MasterBracket = h.makeMasterBracket(in.Configs, h.parameters[in.StudyId].n)
-		evalT = h.getHyperParameter(in.StudyId, in.Configs, h.parameters[in.StudyId].n)
-		h.parameters[in.StudyId].shloopitr++
-	} else {
-		var pn_i int
-		pn_i, r_i = h.shLoopParamUpdate(in.StudyId)
-		evalT = h.getHyperParameter(in.StudyId, in.Configs, pn_i)
-		h.parameters[in.StudyId].shloopitr++
-	}
-	for i := range evalT {
-		for j := range evalT[i].ParameterSet {
-			if evalT[i].ParameterSet[j].Name == h.parameters[in.StudyId].ResourceName {
-				evalT[i].ParameterSet[j].Value = strconv.Itoa(r_i)
-			}
+		if len(rungs[i]) < n_i || running[i] > 0 {
+			return nil, false
 		}
-		evalT[i].Tags = append(evalT[i].Tags, &api.Tag{Name: "HyperBand_s", Value: strconv.Itoa(h.parameters[in.StudyId].currentS)})
-		evalT[i].Tags = append(evalT[i].Tags, &api.Tag{Name: "HyperBand_r", Value: strconv.Itoa(r_i)})
-		evalT[i].Tags = append(evalT[i].Tags, &api.Tag{Name: "HyperBand_shi", Value: strconv.Itoa(h.parameters[in.StudyId].shloopitr)})
-		log.Printf("Gen Trial %v", evalT[i].Tags)
 	}
-	return &api.GenerateTrialsReply{Trials: evalT, Completed: false}, nil
+	return nil, true
 }
 
-func (h *HyperBandSuggestService) StopSuggestion(ctx context.Context, in *api.StopSuggestionRequest) (*api.StopSuggestionReply, error) {
-	delete(h.parameters, in.StudyId)
-	return &api.StopSuggestionReply{}, nil
+// GenerateTrials rebuilds the position of every bracket from the tags of the trials.
+// Brackets are run from s = sMax down to 0. Without MaxParallel one bracket runs at a time;
+// with MaxParallel, the free slots a bracket can not use while it waits for a rung go to the next brackets.
+func (p *HyperBandParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	rungs, running := p.rungs(in)
+	limit := -1
+	if p.MaxParallel > 0 {
+		limit = p.MaxParallel - len(in.RunningTrials)
+		if limit < 0 {
+			limit = 0
+		}
+	}
+	finished := true
+	var s_t []*api.Trial
+	for s := p.sMax; s >= 0; s-- {
+		t, done := p.advance(s, rungs[s], running[s], limit, in)
+		if done {
+			continue
+		}
+		finished = false
+		s_t = append(s_t, t...)
+		if limit < 0 {
+			break
+		}
+		limit -= len(t)
+	}
+	if finished && len(in.RunningTrials) == 0 {
+		return &api.GenerateTrialsReply{Completed: true}, nil
+	}
+	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
 }
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/suggestion"
+)
+
+func studyConfig() *api.StudyConfig {
+	return &api.StudyConfig{
+		OptimizationType: api.OptimizationType_MINIMIZE,
+		ParameterConfigs: &api.StudyConfig_ParameterConfigs{Configs: []*api.ParameterConfig{
+			{Name: "lr", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0", Max: "1"}},
+			{Name: "epochs", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "1", Max: "9"}},
+		}},
+	}
+}
+
+func newStudy(t *testing.T, h *HyperBandSuggestService, params map[string]string) *HyperBandParameters {
+	sps := []*api.SuggestionParameter{{Name: "Eta", Value: "3"}, {Name: "R", Value: "9"}, {Name: "ResourceName", Value: "epochs"}}
+	for n, v := range params {
+		sps = append(sps, &api.SuggestionParameter{Name: n, Value: v})
+	}
+	st, err := h.NewStudy(&api.SetSuggestionParametersRequest{StudyId: "study", SuggestionParameters: sps, Configs: studyConfig()})
+	if err != nil {
+		t.Fatalf("NewStudy %v: %v", params, err)
+	}
+	return st.(*HyperBandParameters)
+}
+
+func lr(tr *api.Trial) float64 {
+	v, _ := strconv.ParseFloat(tr.ParameterSet[0].Value, 64)
+	return v
+}
+
+// objective is minimized at lr 0.3 and improves with the number of epochs.
+func objective(tr *api.Trial) string {
+	epochs, _ := strconv.Atoi(tr.ParameterSet[1].Value)
+	return strconv.FormatFloat(math.Abs(lr(tr)-0.3)+1/float64(epochs), 'f', -1, 64)
+}
+
+// run completes the suggested trials at once until the study is completed or n trials are completed.
+func run(t *testing.T, p *HyperBandParameters, completed []*api.Trial, n int) []*api.Trial {
+	conf := studyConfig()
+	for len(completed) < n {
+		r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: completed})
+		if err != nil {
+			t.Fatalf("GenerateTrials: %v", err)
+		}
+		if r.Completed {
+			break
+		}
+		for _, tr := range r.Trials {
+			tr.Status = api.TrialState_COMPLETED
+			tr.ObjectiveValue = objective(tr)
+			completed = append(completed, tr)
+		}
+	}
+	return completed
+}
+
+// budgetTrial is a completed trial at budget r.
+func budgetTrial(lr float64, r int, obj string) *api.Trial {
+	return &api.Trial{
+		ParameterSet: []*api.Parameter{
+			{Name: "lr", Value: strconv.FormatFloat(lr, 'f', -1, 64)},
+			{Name: "epochs", Value: strconv.Itoa(r)},
+		},
+		Tags:           []*api.Tag{{Name: rTag, Value: strconv.Itoa(r)}},
+		ObjectiveValue: obj,
+	}
+}
+
+func TestBOHBModel(t *testing.T) {
+	budget1 := []*api.Trial{budgetTrial(0.1, 1, "0.1"), budgetTrial(0.5, 1, "0.5"), budgetTrial(0.6, 1, "0.6")}
+	budget3 := []*api.Trial{budgetTrial(0.8, 3, "0.2"), budgetTrial(0.4, 3, "0.4"), budgetTrial(0.2, 3, "")}
+	for _, c := range []struct {
+		name      string
+		completed []*api.Trial
+		// lr of the best trial of the budget of the model, 0 for no model
+		best float64
+	}{
+		{name: "no trial"},
+		{name: "too few trials", completed: budget1[:2]},
+		{name: "smallest budget", completed: append(budget1, budget3[:2]...), best: 0.1},
+		{name: "failed trials are not counted", completed: append(budget1, budget3...), best: 0.1},
+		{name: "largest budget", completed: append(append(budget1, budget3[:2]...), budgetTrial(0.7, 3, "0.7")), best: 0.8},
+	} {
+		p := newStudy(t, &HyperBandSuggestService{bohb: true}, map[string]string{"MinPoints": "3", "Gamma": "0.3", "Seed": "1"})
+		m := p.makeModel(studyConfig(), c.completed)
+		if c.best == 0 {
+			if m != nil {
+				t.Errorf("%v: expected no model", c.name)
+			}
+			continue
+		}
+		if m == nil {
+			t.Errorf("%v: expected a model", c.name)
+			continue
+		}
+		// the good density has a single point, so the samples are around it
+		for i := 0; i < 10; i++ {
+			x := suggestion.ParameterSetFromUnit(m.Sample(), studyConfig().ParameterConfigs.Configs)
+			if v, _ := strconv.ParseFloat(x[0].Value, 64); math.Abs(v-c.best) > 0.05 {
+				t.Errorf("%v: expected a sample around %v, got %v", c.name, c.best, x)
+			}
+		}
+	}
+}
+
+func TestBOHBSampling(t *testing.T) {
+	completed := []*api.Trial{budgetTrial(0.3, 1, "0.1")}
+	for i := 0; i < 9; i++ {
+		completed = append(completed, budgetTrial(0.5+float64(i)/20, 1, "1"))
+	}
+	for _, c := range []struct {
+		name     string
+		bohb     bool
+		fraction string
+		near     bool
+	}{
+		{name: "model", bohb: true, fraction: "0", near: true},
+		{name: "random fraction", bohb: true, fraction: "1"},
+		{name: "hyperband", fraction: "0"},
+	} {
+		p := newStudy(t, &HyperBandSuggestService{bohb: c.bohb}, map[string]string{"RandomFraction": c.fraction, "Gamma": "0.1", "Seed": "1"})
+		near := 0
+		for _, ps := range p.sampleConfigs(studyConfig(), 20, completed) {
+			if v, _ := strconv.ParseFloat(ps[0].Value, 64); math.Abs(v-0.3) < 0.05 {
+				near++
+			}
+		}
+		if c.near != (near == 20) {
+			t.Errorf("%v: expected all the samples around the best trial %v, got %v of 20", c.name, c.near, near)
+		}
+	}
+}
+
+func TestBOHBSeed(t *testing.T) {
+	h := &HyperBandSuggestService{bohb: true}
+	params := map[string]string{"MinPoints": "3", "Seed": "1"}
+	all := run(t, newStudy(t, h, params), nil, 1000)
+	// the brackets of 10, 5 and 4 configurations run one after another
+	if len(all) != 24 {
+		t.Errorf("Expected 24 trials, got %v", len(all))
+	}
+	if !reflect.DeepEqual(all, run(t, newStudy(t, h, params), nil, 1000)) {
+		t.Errorf("Expected the same trials with the same seed")
+	}
+	params["Seed"] = "2"
+	if reflect.DeepEqual(all, run(t, newStudy(t, h, params), nil, 1000)) {
+		t.Errorf("Expected other trials with another seed")
+	}
+	for _, tr := range all {
+		if tr.ParameterSet[1].Value != tagValue(tr, rTag) {
+			t.Errorf("Expected the budget in the resource parameter of %v", tr)
+		}
+	}
+}
+
+func TestRungSize(t *testing.T) {
+	p := newStudy(t, &HyperBandSuggestService{}, nil)
+	if p.sMax != 2 {
+		t.Errorf("Expected sMax 2, got %v", p.sMax)
+	}
+	for _, c := range []struct {
+		s, i, n, r int
+	}{
+		{s: 2, i: 0, n: 10, r: 1},
+		{s: 2, i: 1, n: 3, r: 3},
+		{s: 2, i: 2, n: 1, r: 9},
+		{s: 1, i: 0, n: 5, r: 3},
+		{s: 1, i: 1, n: 1, r: 9},
+		{s: 0, i: 0, n: 4, r: 9},
+	} {
+		if n, r := p.rungSize(c.s, c.i); n != c.n || r != c.r {
+			t.Errorf("Rung %v of bracket %v: expected %v configurations with %v epochs, got %v %v", c.i, c.s, c.n, c.r, n, r)
+		}
+	}
+}
+
+// rungTrials are the trials of rung i of bracket s. Configuration k has the objective value objs[k], and no trial when it is "-".
+func rungTrials(p *HyperBandParameters, s int, i int, objs ...string) []*api.Trial {
+	_, r := p.rungSize(s, i)
+	var ts []*api.Trial
+	for k, obj := range objs {
+		if obj == "-" {
+			continue
+		}
+		tr := p.makeTrial([]*api.Parameter{{Name: "lr", Value: "0.5"}, {Name: "epochs", Value: "1"}}, fmt.Sprintf("s%vc%v", s, k), s, i, r)
+		tr.ObjectiveValue = obj
+		ts = append(ts, tr)
+	}
+	return ts
+}
+
+func TestBracketRebuild(t *testing.T) {
+	p := newStudy(t, &HyperBandSuggestService{}, nil)
+	rung0 := rungTrials(p, 2, 0, "0.5", "0.1", "0.9", "0.3", "0.7", "0.2", "0.8", "0.4", "0.6", "1")
+	bracket2 := append(append(rungTrials(p, 2, 0, "0.5", "0.1", "0.9", "0.3", "0.7", "0.2", "0.8", "0.4", "0.6", "1"),
+		rungTrials(p, 2, 1, "-", "0.3", "-", "0.2", "-", "0.1")...), rungTrials(p, 2, 2, "-", "-", "-", "-", "-", "0.05")...)
+	for _, c := range []struct {
+		name        string
+		maxParallel string
+		completed   []*api.Trial
+		running     []*api.Trial
+		// want has bracket/rung/epochs/configuration of each next trial, with "new" for new configurations
+		want []string
+	}{
+		{
+			name: "first bracket",
+			want: []string{"2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new"},
+		},
+		{
+			name:      "trials with other tags are ignored",
+			completed: append(rungTrials(p, 5, 0, "0.1"), &api.Trial{ObjectiveValue: "0.1"}),
+			want:      []string{"2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new", "2/0/1/new"},
+		},
+		{
+			name:      "rung waiting for a running trial",
+			completed: rung0[:9],
+			running:   rung0[9:],
+		},
+		{
+			name:      "best third promoted",
+			completed: rung0,
+			want:      []string{"2/1/3/s2c1", "2/1/3/s2c5", "2/1/3/s2c3"},
+		},
+		{
+			name:      "failed trials are not promoted",
+			completed: rungTrials(p, 2, 0, "", "0.1", "x", "", "", "", "", "", "", ""),
+			want:      []string{"2/1/3/s2c1"},
+		},
+		{
+			name:      "rung partly promoted",
+			completed: append(rungTrials(p, 2, 0, "0.5", "0.1", "0.9", "0.3", "0.7", "0.2", "0.8", "0.4", "0.6", "1"), rungTrials(p, 2, 1, "-", "0.05")...),
+			want:      []string{"2/1/3/s2c5", "2/1/3/s2c3"},
+		},
+		{
+			name:      "next bracket",
+			completed: bracket2,
+			want:      []string{"1/0/3/new", "1/0/3/new", "1/0/3/new", "1/0/3/new", "1/0/3/new"},
+		},
+		{
+			name:        "free slots go to the next bracket",
+			maxParallel: "9",
+			completed:   rung0[:8],
+			running:     rung0[8:],
+			want:        []string{"1/0/3/new", "1/0/3/new", "1/0/3/new", "1/0/3/new", "1/0/3/new", "0/0/9/new", "0/0/9/new"},
+		},
+	} {
+		p := newStudy(t, &HyperBandSuggestService{}, map[string]string{"MaxParallel": c.maxParallel})
+		r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: studyConfig(), CompletedTrials: c.completed, RunningTrials: c.running})
+		if err != nil {
+			t.Errorf("%v: %v", c.name, err)
+			continue
+		}
+		if r.Completed {
+			t.Errorf("%v: unexpected completion", c.name)
+		}
+		known := make(map[string]bool)
+		for _, tr := range append(c.completed, c.running...) {
+			known[tagValue(tr, bracketIdTag)] = true
+		}
+		var got []string
+		for _, tr := range r.Trials {
+			cid := tagValue(tr, bracketIdTag)
+			if !known[cid] {
+				cid = "new"
+				known[tagValue(tr, bracketIdTag)] = true
+			}
+			if tr.ParameterSet[1].Value != tagValue(tr, rTag) {
+				t.Errorf("%v: expected the epochs of the rung in %v", c.name, tr)
+			}
+			got = append(got, fmt.Sprintf("%v/%v/%v/%v", tagValue(tr, sTag), tagValue(tr, shiTag), tagValue(tr, rTag), cid))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestHyperBandCompleted(t *testing.T) {
+	for _, bohb := range []bool{false, true} {
+		h := &HyperBandSuggestService{bohb: bohb}
+		all := run(t, newStudy(t, h, map[string]string{"Seed": "1"}), nil, 1000)
+		if len(all) != 24 {
+			t.Errorf("bohb %v: expected 24 trials, got %v", bohb, len(all))
+		}
+		// a restarted service rebuilds the brackets from the trials
+		for _, c := range []struct {
+			name      string
+			completed []*api.Trial
+			running   []*api.Trial
+			done      bool
+		}{
+			{name: "all trials completed", completed: all, done: true},
+			{name: "last trial running", completed: all[:23], running: all[23:]},
+		} {
+			r, err := newStudy(t, h, nil).GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: studyConfig(), CompletedTrials: c.completed, RunningTrials: c.running})
+			if err != nil || r.Completed != c.done || len(r.Trials) != 0 {
+				t.Errorf("bohb %v, %v: expected Completed %v and no trial, got %v %v", bohb, c.name, c.done, r, err)
+			}
+		}
+
+		p := newStudy(t, h, map[string]string{"Seed": "1"})
+		completed := run(t, p, nil, 12)
+		state, err := p.SaveState()
+		if err != nil {
+			t.Fatalf("bohb %v: SaveState: %v", bohb, err)
+		}
+		q := newStudy(t, h, map[string]string{"Seed": "2"})
+		if err := q.LoadState(state); err != nil {
+			t.Fatalf("bohb %v: LoadState: %v", bohb, err)
+		}
+		n := len(completed)
+		if got := run(t, q, completed[:n:n], 1000); !reflect.DeepEqual(got, all) {
+			t.Errorf("bohb %v: expected the same trials after LoadState, got %v", bohb, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package suggestion
+
+import (
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ParameterDecoder reads typed suggestion parameters and collects the errors.
+//
+//	d := NewParameterDecoder(in.SuggestionParameters)
+//	p.SuggestionNum = d.Int("SuggestionNum", 0)
+//	d.Check(p.SuggestionNum > 0, "SuggestionNum must be positive")
+//	if err := d.Err(); err != nil {
+//		return nil, err
+//	}
+type ParameterDecoder struct {
+	names  []string
+	values map[string]string
+	used   map[string]bool
+	errs   []string
+}
+
+func NewParameterDecoder(sps []*api.SuggestionParameter) *ParameterDecoder {
+	d := &ParameterDecoder{values: make(map[string]string), used: make(map[string]bool)}
+	for _, sp := range sps {
+		if _, ok := d.values[sp.Name]; !ok {
+			d.names = append(d.names, sp.Name)
+		}
+		d.values[sp.Name] = sp.Value
+	}
+	return d
+}
+
+func (d *ParameterDecoder) Has(name string) bool {
+	_, ok := d.values[name]
+	return ok
+}
+
+func (d *ParameterDecoder) String(name string, def string) string {
+	v, ok := d.values[name]
+	if !ok {
+		return def
+	}
+	d.used[name] = true
+	return v
+}
+
+func (d *ParameterDecoder) Int(name string, def int) int {
+	v := d.String(name, "")
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		d.errs = append(d.errs, fmt.Sprintf("%v: %v is not an integer", name, v))
+		return def
+	}
+	return i
+}
+
+func (d *ParameterDecoder) Int64(name string, def int64) int64 {
+	v := d.String(name, "")
+	if v == "" {
+		return def
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		d.errs = append(d.errs, fmt.Sprintf("%v: %v is not an integer", name, v))
+		return def
+	}
+	return i
+}
+
+func (d *ParameterDecoder) Float(name string, def float64) float64 {
+	v := d.String(name, "")
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		d.errs = append(d.errs, fmt.Sprintf("%v: %v is not a number", name, v))
+		return def
+	}
+	return f
+}
+
+// Floats reads a comma separated list of numbers.
+func (d *ParameterDecoder) Floats(name string, def []float64) []float64 {
+	v := d.String(name, "")
+	if v == "" {
+		return def
+	}
+	var ret []float64
+	for _, s := range strings.Split(v, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			d.errs = append(d.errs, fmt.Sprintf("%v: %v is not a number", name, s))
+			return def
+		}
+		ret = append(ret, f)
+	}
+	return ret
+}
+
+// Check records an error when ok is false.
+func (d *ParameterDecoder) Check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		d.errs = append(d.errs, fmt.Sprintf(format, args...))
+	}
+}
+
+// Unused returns the names of the parameters that have not been read, in the order of the request.
+func (d *ParameterDecoder) Unused() []string {
+	var ret []string
+	for _, n := range d.names {
+		if !d.used[n] {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
+// Err logs the parameters that have not been read and returns the collected errors, if any.
+func (d *ParameterDecoder) Err() error {
+	for _, n := range d.Unused() {
+		log.Printf("Unknown Suggestion Parameter %v", n)
+	}
+	if len(d.errs) > 0 {
+		return fmt.Errorf("Suggestion Parameter set Error: %v", strings.Join(d.errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"github.com/mlkube/katib/suggestion"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	memberTag     = "PBT_Member"
+	generationTag = "PBT_Generation"
+	lineageTag    = "PBT_Lineage"
+)
+
+type member struct {
+	id         int
+	generation int
+	params     []*api.Parameter
+	// trialId and score of the last completed trial of the member
+	trialId string
+	lineage string
+	score   float64
+	running bool
+	// failed is the failed trial of the current generation, which is run again
+	failed *api.Trial
+}
+
+type PBTParameters struct {
+	PopulationSize       int
+	Generations          int
+	MaxParallel          int
+	ResourceName         string
+	PerturbationInterval string
+	Quantile             float64
+	PerturbFactors       []float64
+	ResampleProbability  float64
+	members              []*member
+	rng                  *suggestion.Rand
+}
+
+type PBTSuggestService struct {
+}
+
+func NewPBTSuggestService() *suggestion.Service {
+	return suggestion.NewService(&PBTSuggestService{})
+}
+
+func (s *PBTSuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (suggestion.Study, error) {
+	d := suggestion.NewParameterDecoder(in.SuggestionParameters)
+	p := &PBTParameters{
+		PopulationSize:       d.Int("PopulationSize", 8),
+		Generations:          d.Int("Generations", 10),
+		MaxParallel:          d.Int("MaxParallel", 0),
+		ResourceName:         d.String("ResourceName", ""),
+		PerturbationInterval: d.String("PerturbationInterval", ""),
+		Quantile:             d.Float("Quantile", 0.25),
+		PerturbFactors:       d.Floats("PerturbFactors", []float64{0.8, 1.2}),
+		ResampleProbability:  d.Float("ResampleProbability", 0.25),
+		rng:                  suggestion.NewRand(d.Int64("Seed", time.Now().UnixNano())),
+	}
+	d.Check(p.PopulationSize >= 2, "PopulationSize must be at least 2")
+	d.Check(p.Generations >= 1, "Generations must be positive")
+	d.Check(p.Quantile > 0 && p.Quantile <= 0.5, "Quantile must be in (0, 0.5]")
+	d.Check(len(p.PerturbFactors) > 0, "PerturbFactors must not be empty")
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	if p.MaxParallel <= 0 || p.MaxParallel > p.PopulationSize {
+		p.MaxParallel = p.PopulationSize
+	}
+	sampler, _ := suggestion.NewSampler("random", len(in.Configs.ParameterConfigs.Configs), p.PopulationSize, p.rng.Int63())
+	p.members = make([]*member, p.PopulationSize)
+	for i := range p.members {
+		p.members[i] = &member{id: i, params: suggestion.ParameterSetFromUnit(sampler.Sample(), in.Configs.ParameterConfigs.Configs)}
+	}
+	log.Printf("Study %v: PBT population %v, random seed %v", in.StudyId, p.PopulationSize, p.rng.Seed())
+	return p, nil
+}
+
+func (p *PBTParameters) perturb(params []*api.Parameter, pcs []*api.ParameterConfig) []*api.Parameter {
+	ret := make([]*api.Parameter, len(params))
+	for i, v := range params {
+		ret[i] = &api.Parameter{Name: v.Name, ParameterType: v.ParameterType, Value: v.Value}
+		var pc *api.ParameterConfig
+		for _, c := range pcs {
+			if c.Name == v.Name {
+				pc = c
+			}
+		}
+		if pc == nil || v.Name == p.ResourceName {
+			continue
+		}
+		if p.rng.Float64() < p.ResampleProbability {
+			ret[i].Value = suggestion.ParameterSetFromUnit([]float64{p.rng.Float64()}, []*api.ParameterConfig{pc})[0].Value
+			continue
+		}
+		f := p.PerturbFactors[p.rng.Intn(len(p.PerturbFactors))]
+		switch pc.ParameterType {
+		case api.ParameterType_INT:
+			imin, _ := strconv.Atoi(pc.Feasible.Min)
+			imax, _ := strconv.Atoi(pc.Feasible.Max)
+			iv, _ := strconv.Atoi(v.Value)
+			nv := int(math.Floor(float64(iv)*f + 0.5))
+			if nv == iv {
+				// make sure small integers still move
+				if f > 1 {
+					nv++
+				} else {
+					nv--
+				}
+			}
+			ret[i].Value = strconv.Itoa(int(math.Min(float64(imax), math.Max(float64(imin), float64(nv)))))
+		case api.ParameterType_DOUBLE:
+			dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
+			dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
+			dv, _ := strconv.ParseFloat(v.Value, 64)
+			ret[i].Value = strconv.FormatFloat(math.Min(dmax, math.Max(dmin, dv*f)), 'f', 4, 64)
+		case api.ParameterType_DISCRETE, api.ParameterType_CATEGORICAL:
+			for j, l := range pc.Feasible.List {
+				if l == v.Value {
+					// move to a neighbouring value in the list
+					if f > 1 && j+1 < len(pc.Feasible.List) {
+						ret[i].Value = pc.Feasible.List[j+1]
+					} else if f <= 1 && j > 0 {
+						ret[i].Value = pc.Feasible.List[j-1]
+					}
+					break
+				}
+			}
+		}
+	}
+	return ret
+}
+
+func (p *PBTParameters) makeTrial(m *member, restore string, lineage string) *api.Trial {
+	t := &api.Trial{
+		Status:       api.TrialState_PENDING,
+		EvalLogs:     make([]*api.EvaluationLog, 0),
+		ParameterSet: make([]*api.Parameter, len(m.params)),
+	}
+	for i, v := range m.params {
+		t.ParameterSet[i] = &api.Parameter{Name: v.Name, ParameterType: v.ParameterType, Value: v.Value}
+		if v.Name == p.ResourceName && p.PerturbationInterval != "" {
+			t.ParameterSet[i].Value = p.PerturbationInterval
+		}
+	}
+	t.Tags = []*api.Tag{
+		{Name: memberTag, Value: strconv.Itoa(m.id)},
+		{Name: generationTag, Value: strconv.Itoa(m.generation)},
+		{Name: lineageTag, Value: lineage},
+	}
+	if restore != "" {
+		t.Tags = append(t.Tags, &api.Tag{Name: api.RestoreTrialTag, Value: restore})
+	}
+	return t
+}
+
+type memberState struct {
+	Generation int
+	Params     []*api.Parameter
+	TrialId    string
+	Lineage    string
+	Score      float64
+}
+
+type pbtState struct {
+	Members []memberState
+	Rand    suggestion.RandState
+}
+
+func (p *PBTParameters) SaveState() ([]byte, error) {
+	st := &pbtState{Members: make([]memberState, len(p.members)), Rand: p.rng.State()}
+	for i, m := range p.members {
+		st.Members[i] = memberState{Generation: m.generation, Params: m.params, TrialId: m.trialId, Lineage: m.lineage, Score: m.score}
+	}
+	return suggestion.EncodeState(st)
+}
+
+func (p *PBTParameters) LoadState(state []byte) error {
+	st := &pbtState{}
+	if err := suggestion.DecodeState(state, st); err != nil {
+		return err
+	}
+	if len(st.Members) != len(p.members) {
+		return fmt.Errorf("Saved state has %v members, expected %v", len(st.Members), len(p.members))
+	}
+	for i, m := range st.Members {
+		p.members[i] = &member{id: i, generation: m.Generation, params: m.Params, trialId: m.TrialId, lineage: m.Lineage, score: m.Score}
+	}
+	p.rng = suggestion.NewRandFromState(st.Rand)
+	return nil
+}
+
+func (p *PBTParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	for _, m := range p.members {
+		m.running = false
+		m.failed = nil
+	}
+	for _, t := range in.RunningTrials {
+		if id, _, ok := memberOf(t); ok && id < len(p.members) {
+			p.members[id].running = true
+		}
+	}
+	// pick up the results of the generation each member is waiting for
+	for _, t := range in.CompletedTrials {
+		id, gen, ok := memberOf(t)
+		if !ok || id >= len(p.members) {
+			continue
+		}
+		m := p.members[id]
+		if gen != m.generation || m.running {
+			continue
+		}
+		v, err := strconv.ParseFloat(t.ObjectiveValue, 64)
+		if t.Status == api.TrialState_ERROR || err != nil || math.IsNaN(v) {
+			// a failed trial has no checkpoint to restore from, so the member runs it again
+			m.failed = t
+			continue
+		}
+		if in.Configs.OptimizationType == api.OptimizationType_MINIMIZE {
+			v = -v
+		}
+		m.score = v
+		m.trialId = t.TrialId
+		m.lineage = tagValue(t, lineageTag)
+		m.generation++
+		m.failed = nil
+	}
+
+	ranked := make([]*member, 0, len(p.members))
+	for _, m := range p.members {
+		if m.trialId != "" {
+			ranked = append(ranked, m)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	nq := int(math.Ceil(p.Quantile * float64(len(ranked))))
+
+	// members that are behind go first so that every member advances when MaxParallel < PopulationSize
+	order := make([]*member, len(p.members))
+	copy(order, p.members)
+	sort.SliceStable(order, func(i, j int) bool { return order[i].generation < order[j].generation })
+	running := len(in.RunningTrials)
+	finished := true
+	var s_t []*api.Trial
+	for _, m := range order {
+		if m.generation < p.Generations || m.running {
+			finished = false
+		}
+		if m.running || m.generation >= p.Generations || running >= p.MaxParallel {
+			continue
+		}
+		if m.generation > 0 && m.trialId == "" {
+			continue
+		}
+		if m.failed != nil {
+			log.Printf("Study %v: member %v runs failed trial %v again", in.StudyId, m.id, m.failed.TrialId)
+			m.params = m.failed.ParameterSet
+			s_t = append(s_t, p.makeTrial(m, tagValue(m.failed, api.RestoreTrialTag), tagValue(m.failed, lineageTag)))
+			m.running = true
+			running++
+			continue
+		}
+		restore := m.trialId
+		lineage := m.lineage
+		for _, b := range ranked[len(ranked)-nq:] {
+			if b != m || len(ranked) < 2 {
+				continue
+			}
+			// exploit a member of the top quantile and explore around its hyperparameters
+			top := ranked[p.rng.Intn(nq)]
+			log.Printf("Study %v: member %v exploits member %v (trial %v)", in.StudyId, m.id, top.id, top.trialId)
+			m.params = p.perturb(top.params, in.Configs.ParameterConfigs.Configs)
+			restore = top.trialId
+			lineage = top.lineage
+		}
+		if restore != "" {
+			if lineage != "" {
+				lineage += ","
+			}
+			lineage += restore
+		}
+		s_t = append(s_t, p.makeTrial(m, restore, lineage))
+		m.running = true
+		running++
+	}
+	if finished {
+		return &api.GenerateTrialsReply{Completed: true}, nil
+	}
+	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
+}
+
+func tagValue(t *api.Trial, name string) string {
+	for _, tag := range t.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+func memberOf(t *api.Trial) (int, int, bool) {
+	id, gen := -1, -1
+	for _, tag := range t.Tags {
+		switch tag.Name {
+		case memberTag:
+			id, _ = strconv.Atoi(tag.Value)
+		case generationTag:
+			gen, _ = strconv.Atoi(tag.Value)
+		}
+	}
+	return id, gen, id >= 0 && gen >= 0
+}
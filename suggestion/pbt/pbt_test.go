@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func studyConfig() *api.StudyConfig {
+	return &api.StudyConfig{
+		OptimizationType: api.OptimizationType_MAXIMIZE,
+		ParameterConfigs: &api.StudyConfig_ParameterConfigs{Configs: []*api.ParameterConfig{
+			{Name: "lr", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0", Max: "1"}},
+			{Name: "layers", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "2", Max: "5"}},
+			{Name: "batch", ParameterType: api.ParameterType_DISCRETE, Feasible: &api.FeasibleSpace{List: []string{"16", "32", "64"}}},
+			{Name: "epochs", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "1", Max: "100"}},
+		}},
+	}
+}
+
+func newStudy(t *testing.T, params map[string]string) *PBTParameters {
+	sps := []*api.SuggestionParameter{
+		{Name: "PopulationSize", Value: "4"},
+		{Name: "Generations", Value: "3"},
+		{Name: "ResourceName", Value: "epochs"},
+		{Name: "PerturbationInterval", Value: "5"},
+		{Name: "Seed", Value: "1"},
+	}
+	for n, v := range params {
+		sps = append(sps, &api.SuggestionParameter{Name: n, Value: v})
+	}
+	st, err := (&PBTSuggestService{}).NewStudy(&api.SetSuggestionParametersRequest{StudyId: "study", SuggestionParameters: sps, Configs: studyConfig()})
+	if err != nil {
+		t.Fatalf("NewStudy %v: %v", params, err)
+	}
+	return st.(*PBTParameters)
+}
+
+func generate(t *testing.T, p *PBTParameters, completed []*api.Trial) *api.GenerateTrialsReply {
+	r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: studyConfig(), CompletedTrials: completed})
+	if err != nil {
+		t.Fatalf("GenerateTrials: %v", err)
+	}
+	return r
+}
+
+// complete gives the trials an ID and the value of lr as objective.
+func complete(trials []*api.Trial, completed []*api.Trial) []*api.Trial {
+	for _, tr := range trials {
+		tr.TrialId = fmt.Sprintf("t%v", len(completed))
+		tr.Status = api.TrialState_COMPLETED
+		tr.ObjectiveValue = tr.ParameterSet[0].Value
+		completed = append(completed, tr)
+	}
+	return completed
+}
+
+// run completes the suggested trials at once until the study is completed or n trials are completed.
+func run(t *testing.T, p *PBTParameters, completed []*api.Trial, n int) []*api.Trial {
+	for len(completed) < n {
+		r := generate(t, p, completed)
+		if r.Completed {
+			break
+		}
+		completed = complete(r.Trials, completed)
+	}
+	return completed
+}
+
+func TestPerturb(t *testing.T) {
+	params := []*api.Parameter{
+		{Name: "lr", Value: "0.5"},
+		{Name: "layers", Value: "2"},
+		{Name: "batch", Value: "32"},
+		{Name: "epochs", Value: "5"},
+	}
+	for _, c := range []struct {
+		factors string
+		want    []string
+	}{
+		{factors: "1.2", want: []string{"0.6000", "3", "64", "5"}},
+		{factors: "0.8", want: []string{"0.4000", "2", "16", "5"}},
+		{factors: "3", want: []string{"1.0000", "5", "64", "5"}},
+	} {
+		p := newStudy(t, map[string]string{"PerturbFactors": c.factors, "ResampleProbability": "0"})
+		var got []string
+		for _, v := range p.perturb(params, studyConfig().ParameterConfigs.Configs) {
+			got = append(got, v.Value)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("PerturbFactors %v: expected %v, got %v", c.factors, c.want, got)
+		}
+	}
+	// resampled parameters stay in the feasible space, and the resource is never changed
+	p := newStudy(t, map[string]string{"ResampleProbability": "1"})
+	for i := 0; i < 20; i++ {
+		ps := p.perturb(params, studyConfig().ParameterConfigs.Configs)
+		lr, _ := strconv.ParseFloat(ps[0].Value, 64)
+		layers, _ := strconv.Atoi(ps[1].Value)
+		if lr < 0 || lr > 1 || layers < 2 || layers > 5 || ps[3].Value != "5" {
+			t.Errorf("Unexpected resampled parameters %v", ps)
+		}
+	}
+}
+
+func TestExploit(t *testing.T) {
+	p := newStudy(t, nil)
+	first := generate(t, p, nil).Trials
+	if len(first) != 4 {
+		t.Fatalf("Expected a trial for each member, got %v", first)
+	}
+	completed := complete(first, nil)
+	for i, tr := range completed {
+		if tagValue(tr, generationTag) != "0" || tagValue(tr, api.RestoreTrialTag) != "" {
+			t.Errorf("Unexpected first trial %v", tr)
+		}
+		tr.ObjectiveValue = []string{"0.4", "0.9", "0.1", "0.5"}[i]
+	}
+	next := generate(t, p, completed).Trials
+	if len(next) != 4 {
+		t.Fatalf("Expected a trial for each member, got %v", next)
+	}
+	for _, tr := range next {
+		id, gen, _ := memberOf(tr)
+		// the worst member, 2, copies the best one, 1, and the others continue from their own checkpoint
+		restore := completed[id]
+		if id == 2 {
+			restore = completed[1]
+		}
+		if gen != 1 || tagValue(tr, api.RestoreTrialTag) != restore.TrialId || tagValue(tr, lineageTag) != restore.TrialId {
+			t.Errorf("Member %v: expected generation 1 restored from %v, got %v", id, restore.TrialId, tr)
+		}
+		if id != 2 && !reflect.DeepEqual(tr.ParameterSet, restore.ParameterSet) {
+			t.Errorf("Member %v: expected the parameters %v, got %v", id, restore.ParameterSet, tr.ParameterSet)
+		}
+		if id == 2 && reflect.DeepEqual(tr.ParameterSet, completed[2].ParameterSet) {
+			t.Errorf("Member 2: expected new parameters, got %v", tr.ParameterSet)
+		}
+	}
+}
+
+func TestFailedTrials(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		status api.TrialState
+		obj    string
+	}{
+		{name: "error", status: api.TrialState_ERROR, obj: "0.99"},
+		{name: "no objective", status: api.TrialState_COMPLETED},
+		{name: "unparsable objective", status: api.TrialState_COMPLETED, obj: "n/a"},
+	} {
+		p := newStudy(t, nil)
+		completed := run(t, p, nil, 4)
+		second := generate(t, p, completed).Trials
+		completed = complete(second, completed)
+		// the best member fails in generation 1
+		failed := second[0]
+		for _, tr := range second {
+			if tr.ParameterSet[0].Value > failed.ParameterSet[0].Value {
+				failed = tr
+			}
+		}
+		failed.Status, failed.ObjectiveValue = c.status, c.obj
+		id, _, _ := memberOf(failed)
+
+		next := generate(t, p, completed).Trials
+		if len(next) != 4 {
+			t.Fatalf("%v: expected a trial for each member, got %v", c.name, next)
+		}
+		for _, tr := range next {
+			if tagValue(tr, api.RestoreTrialTag) == failed.TrialId {
+				t.Errorf("%v: trial %v restores from the failed trial", c.name, tr)
+			}
+			if m, gen, _ := memberOf(tr); m == id {
+				if gen != 1 || !reflect.DeepEqual(tr.Tags, failed.Tags) || !reflect.DeepEqual(tr.ParameterSet, failed.ParameterSet) {
+					t.Errorf("%v: expected the failed trial %v again, got %v", c.name, failed, tr)
+				}
+			} else if gen != 2 {
+				t.Errorf("%v: expected generation 2 for member %v, got %v", c.name, m, gen)
+			}
+		}
+	}
+}
+
+func TestSeedAndState(t *testing.T) {
+	all := run(t, newStudy(t, nil), nil, 1000)
+	if len(all) != 12 {
+		t.Errorf("Expected 4 members for 3 generations, got %v trials", len(all))
+	}
+	if !reflect.DeepEqual(all, run(t, newStudy(t, nil), nil, 1000)) {
+		t.Errorf("Expected the same trials with the same seed")
+	}
+	if reflect.DeepEqual(all, run(t, newStudy(t, map[string]string{"Seed": "2"}), nil, 1000)) {
+		t.Errorf("Expected other trials with another seed")
+	}
+
+	p := newStudy(t, nil)
+	completed := run(t, p, nil, 8)
+	state, err := p.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+	q := newStudy(t, map[string]string{"Seed": "2"})
+	if err := q.LoadState(state); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if got := run(t, q, completed[:8:8], 1000); !reflect.DeepEqual(got, all) {
+		t.Errorf("Expected the same trials after LoadState, got %v", got)
+	}
+	if err := newStudy(t, map[string]string{"PopulationSize": "5"}).LoadState(state); err == nil {
+		t.Errorf("Expected an error for a state of another population size")
+	}
+}
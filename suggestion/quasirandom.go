@@ -0,0 +1,333 @@
+package suggestion
+
+import (
+	"context"
+	"fmt"
+	"github.com/mlkube/katib/api"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// Sampler generates points in the unit hypercube [0, 1)^dim.
+// The same kind, dimension and seed always produce the same sequence.
+type Sampler interface {
+	Sample() []float64
+}
+
+// NewSampler returns a sampler of the given kind (sobol, halton, lhs or random).
+// n is the number of points the caller intends to draw; it is only used by lhs.
+func NewSampler(kind string, dim int, n int, seed int64) (Sampler, error) {
+	rng := rand.New(rand.NewSource(seed))
+	switch kind {
+	case "sobol":
+		return newSobolSampler(dim, rng)
+	case "halton":
+		return newHaltonSampler(dim, rng)
+	case "lhs":
+		return newLatinHypercubeSampler(dim, n, rng), nil
+	case "random":
+		return &uniformSampler{dim: dim, rng: rng}, nil
+	}
+	return nil, fmt.Errorf("Unknown sampler %v", kind)
+}
+
+// ParameterSetFromUnit maps a point of the unit hypercube onto the feasible space of pcs.
+func ParameterSetFromUnit(x []float64, pcs []*api.ParameterConfig) []*api.Parameter {
+	ps := make([]*api.Parameter, len(pcs))
+	for i, pc := range pcs {
+		ps[i] = &api.Parameter{Name: pc.Name, ParameterType: pc.ParameterType}
+		switch pc.ParameterType {
+		case api.ParameterType_INT:
+			imin, _ := strconv.Atoi(pc.Feasible.Min)
+			imax, _ := strconv.Atoi(pc.Feasible.Max)
+			ps[i].Value = strconv.Itoa(imin + unitIndex(x[i], imax-imin+1))
+		case api.ParameterType_DOUBLE:
+			dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
+			dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
+			ps[i].Value = strconv.FormatFloat(dmin+x[i]*(dmax-dmin), 'f', 4, 64)
+		case api.ParameterType_DISCRETE, api.ParameterType_CATEGORICAL:
+			ps[i].Value = pc.Feasible.List[unitIndex(x[i], len(pc.Feasible.List))]
+		}
+	}
+	return ps
+}
+
+func unitIndex(x float64, n int) int {
+	i := int(math.Floor(x * float64(n)))
+	if i >= n {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+type uniformSampler struct {
+	dim int
+	rng *rand.Rand
+}
+
+func (u *uniformSampler) Sample() []float64 {
+	x := make([]float64, u.dim)
+	for i := range x {
+		x[i] = u.rng.Float64()
+	}
+	return x
+}
+
+// sobolDirections holds degree, coefficients and initial direction numbers of the
+// primitive polynomials for dimensions 2.. from Joe and Kuo's new-joe-kuo-6.21201.
+var sobolDirections = []struct {
+	s uint
+	a uint
+	m []uint32
+}{
+	{1, 0, []uint32{1}},
+	{2, 1, []uint32{1, 3}},
+	{3, 1, []uint32{1, 3, 1}},
+	{3, 2, []uint32{1, 1, 1}},
+	{4, 1, []uint32{1, 1, 3, 3}},
+	{4, 4, []uint32{1, 3, 5, 13}},
+	{5, 2, []uint32{1, 1, 5, 5, 17}},
+	{5, 4, []uint32{1, 1, 5, 5, 5}},
+	{5, 7, []uint32{1, 1, 7, 11, 19}},
+	{5, 11, []uint32{1, 1, 5, 1, 1}},
+	{5, 13, []uint32{1, 1, 1, 3, 11}},
+	{5, 14, []uint32{1, 3, 5, 5, 31}},
+	{6, 1, []uint32{1, 3, 3, 9, 7, 49}},
+	{6, 13, []uint32{1, 1, 1, 15, 21, 21}},
+	{6, 16, []uint32{1, 3, 1, 13, 27, 49}},
+	{6, 19, []uint32{1, 1, 1, 15, 7, 5}},
+	{6, 22, []uint32{1, 3, 1, 15, 13, 25}},
+	{6, 25, []uint32{1, 1, 5, 5, 19, 61}},
+	{7, 1, []uint32{1, 3, 7, 11, 23, 15, 103}},
+	{7, 4, []uint32{1, 3, 7, 13, 13, 15, 69}},
+}
+
+const sobolBits = 32
+
+type sobolSampler struct {
+	v     [][]uint32
+	shift []uint32
+	index uint32
+}
+
+func newSobolSampler(dim int, rng *rand.Rand) (*sobolSampler, error) {
+	if dim > len(sobolDirections)+1 {
+		return nil, fmt.Errorf("sobol supports up to %v parameters", len(sobolDirections)+1)
+	}
+	s := &sobolSampler{v: make([][]uint32, dim), shift: make([]uint32, dim)}
+	for d := range s.v {
+		v := make([]uint32, sobolBits)
+		if d == 0 {
+			for i := range v {
+				v[i] = 1 << uint(sobolBits-1-i)
+			}
+		} else {
+			dd := sobolDirections[d-1]
+			for i := 0; i < sobolBits; i++ {
+				if uint(i) < dd.s {
+					v[i] = dd.m[i] << uint(sobolBits-1-i)
+					continue
+				}
+				v[i] = v[i-int(dd.s)] ^ (v[i-int(dd.s)] >> dd.s)
+				for k := uint(1); k < dd.s; k++ {
+					if (dd.a>>(dd.s-1-k))&1 == 1 {
+						v[i] ^= v[i-int(k)]
+					}
+				}
+			}
+		}
+		s.v[d] = v
+		// a random digital shift keeps the net property of the sequence
+		s.shift[d] = rng.Uint32()
+	}
+	return s, nil
+}
+
+func (s *sobolSampler) Sample() []float64 {
+	// point 0 is included, so that the first 2^k points are a (t,m,s)-net
+	gray := s.index ^ (s.index >> 1)
+	s.index++
+	x := make([]float64, len(s.v))
+	for d := range s.v {
+		p := s.shift[d]
+		for i := 0; i < sobolBits; i++ {
+			if (gray>>uint(i))&1 == 1 {
+				p ^= s.v[d][i]
+			}
+		}
+		x[d] = float64(p) / (1 << sobolBits)
+	}
+	return x
+}
+
+type haltonSampler struct {
+	bases []int
+	shift []float64
+	index int
+}
+
+func newHaltonSampler(dim int, rng *rand.Rand) (*haltonSampler, error) {
+	h := &haltonSampler{bases: make([]int, 0, dim), shift: make([]float64, dim)}
+	for c := 2; len(h.bases) < dim; c++ {
+		prime := true
+		for _, b := range h.bases {
+			if c%b == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			h.bases = append(h.bases, c)
+		}
+	}
+	// Cranley-Patterson rotation
+	for i := range h.shift {
+		h.shift[i] = rng.Float64()
+	}
+	return h, nil
+}
+
+func (h *haltonSampler) Sample() []float64 {
+	h.index++
+	x := make([]float64, len(h.bases))
+	for d, b := range h.bases {
+		f, r := 1.0, 0.0
+		for i := h.index; i > 0; i /= b {
+			f /= float64(b)
+			r += f * float64(i%b)
+		}
+		x[d] = math.Mod(r+h.shift[d], 1)
+	}
+	return x
+}
+
+type latinHypercubeSampler struct {
+	dim   int
+	n     int
+	perm  [][]int
+	rng   *rand.Rand
+	index int
+}
+
+func newLatinHypercubeSampler(dim int, n int, rng *rand.Rand) *latinHypercubeSampler {
+	if n < 1 {
+		n = 1
+	}
+	l := &latinHypercubeSampler{dim: dim, n: n, rng: rng}
+	l.permute()
+	return l
+}
+
+func (l *latinHypercubeSampler) permute() {
+	l.perm = make([][]int, l.dim)
+	for d := range l.perm {
+		l.perm[d] = l.rng.Perm(l.n)
+	}
+	l.index = 0
+}
+
+// Sample starts a new design once n points have been drawn.
+func (l *latinHypercubeSampler) Sample() []float64 {
+	if l.index >= l.n {
+		l.permute()
+	}
+	x := make([]float64, l.dim)
+	for d := range x {
+		x[d] = (float64(l.perm[d][l.index]) + l.rng.Float64()) / float64(l.n)
+	}
+	l.index++
+	return x
+}
+
+type QuasiRandomSuggestParameters struct {
+	SuggestionNum int
+	MaxParallel   int
+	Sequence      string
+	Seed          int64
+	sampler       Sampler
+	dim           int
+	drawn         int
+}
+
+type quasiRandomState struct {
+	Seed  int64
+	Drawn int
+}
+
+type QuasiRandomSuggestService struct {
+}
+
+func NewQuasiRandomSuggestService() *Service {
+	return NewService(&QuasiRandomSuggestService{})
+}
+
+func (s *QuasiRandomSuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (Study, error) {
+	d := NewParameterDecoder(in.SuggestionParameters)
+	p := &QuasiRandomSuggestParameters{
+		SuggestionNum: d.Int("SuggestionNum", 0),
+		MaxParallel:   d.Int("MaxParallel", 0),
+		Sequence:      d.String("Sequence", "sobol"),
+		Seed:          d.Int64("Seed", time.Now().UnixNano()),
+	}
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	var err error
+	p.dim = len(in.Configs.ParameterConfigs.Configs)
+	p.sampler, err = NewSampler(p.Sequence, p.dim, p.SuggestionNum, p.Seed)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Study %v: %v sequence with seed %v", in.StudyId, p.Sequence, p.Seed)
+	return p, nil
+}
+
+// SaveState records the seed and the position in the sequence, which are enough to replay the sampler.
+func (p *QuasiRandomSuggestParameters) SaveState() ([]byte, error) {
+	return EncodeState(&quasiRandomState{Seed: p.Seed, Drawn: p.drawn})
+}
+
+func (p *QuasiRandomSuggestParameters) LoadState(state []byte) error {
+	st := &quasiRandomState{}
+	if err := DecodeState(state, st); err != nil {
+		return err
+	}
+	sampler, err := NewSampler(p.Sequence, p.dim, p.SuggestionNum, st.Seed)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < st.Drawn; i++ {
+		sampler.Sample()
+	}
+	p.Seed, p.sampler, p.drawn = st.Seed, sampler, st.Drawn
+	return nil
+}
+
+func (p *QuasiRandomSuggestParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	if len(in.CompletedTrials) >= p.SuggestionNum {
+		return &api.GenerateTrialsReply{Completed: true}, nil
+	}
+	reqnum := p.SuggestionNum - len(in.CompletedTrials) - len(in.RunningTrials)
+	if p.MaxParallel > 0 && p.MaxParallel-len(in.RunningTrials) < reqnum {
+		reqnum = p.MaxParallel - len(in.RunningTrials)
+	}
+	if reqnum <= 0 {
+		return &api.GenerateTrialsReply{Completed: false}, nil
+	}
+	s_t := make([]*api.Trial, reqnum)
+	for i := range s_t {
+		p.drawn++
+		s_t[i] = &api.Trial{
+			ParameterSet: ParameterSetFromUnit(p.sampler.Sample(), in.Configs.ParameterConfigs.Configs),
+			Status:       api.TrialState_PENDING,
+			EvalLogs:     make([]*api.EvaluationLog, 0),
+		}
+	}
+	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
+}
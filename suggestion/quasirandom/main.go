@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/mlkube/katib/suggestion"
+)
+
+func main() {
+	suggestion.Serve("Quasi-Random", suggestion.NewQuasiRandomSuggestService())
+}
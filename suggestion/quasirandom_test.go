@@ -0,0 +1,218 @@
+package suggestion
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func sample(t *testing.T, kind string, dim int, n int, seed int64) [][]float64 {
+	s, err := NewSampler(kind, dim, n, seed)
+	if err != nil {
+		t.Fatalf("NewSampler %v: %v", kind, err)
+	}
+	ret := make([][]float64, n)
+	for i := range ret {
+		ret[i] = s.Sample()
+	}
+	return ret
+}
+
+// strata counts the points of dimension d in each of n intervals of [0, 1).
+func strata(xs [][]float64, d int, n int) []int {
+	c := make([]int, n)
+	for _, x := range xs {
+		c[unitIndex(x[d], n)]++
+	}
+	return c
+}
+
+func TestSamplers(t *testing.T) {
+	const dim = 3
+	for _, c := range []struct {
+		kind string
+		// maxDev is the largest difference allowed between the number of points in an interval and the expected one
+		maxDev int
+	}{
+		{kind: "sobol", maxDev: 0},
+		{kind: "halton", maxDev: 2},
+		{kind: "lhs", maxDev: 0},
+		{kind: "random", maxDev: 64},
+	} {
+		xs := sample(t, c.kind, dim, 64, 1)
+		for _, x := range xs {
+			if len(x) != dim {
+				t.Fatalf("%v: expected %v dimensions, got %v", c.kind, dim, x)
+			}
+			for _, v := range x {
+				if v < 0 || v >= 1 {
+					t.Errorf("%v: %v is out of the unit hypercube", c.kind, x)
+				}
+			}
+		}
+		if !reflect.DeepEqual(xs, sample(t, c.kind, dim, 64, 1)) {
+			t.Errorf("%v: expected the same points with the same seed", c.kind)
+		}
+		if reflect.DeepEqual(xs, sample(t, c.kind, dim, 64, 2)) {
+			t.Errorf("%v: expected other points with another seed", c.kind)
+		}
+		for d := 0; d < dim; d++ {
+			for n, cnt := range strata(xs, d, 16) {
+				if cnt < 4-c.maxDev || cnt > 4+c.maxDev {
+					t.Errorf("%v: expected about 4 points in interval %v/16 of dimension %v, got %v", c.kind, n, d, cnt)
+				}
+			}
+		}
+	}
+}
+
+func TestSobolNet(t *testing.T) {
+	// the first 2^m points of the first two dimensions are a (0,m,2)-net: every elementary box of volume 2^-m has one point
+	xs := sample(t, "sobol", 2, 64, 7)
+	for _, k := range []int{1, 2, 4, 8, 16, 32, 64} {
+		cnt := make(map[[2]int]int)
+		for _, x := range xs {
+			cnt[[2]int{unitIndex(x[0], k), unitIndex(x[1], 64/k)}]++
+		}
+		if len(cnt) != 64 {
+			t.Errorf("Expected one point in each of the %vx%v boxes, got %v", k, 64/k, cnt)
+		}
+	}
+	// each dimension is stratified
+	xs = sample(t, "sobol", 21, 32, 7)
+	for d := 0; d < 21; d++ {
+		for n, c := range strata(xs, d, 32) {
+			if c != 1 {
+				t.Errorf("Expected one point in interval %v/32 of dimension %v, got %v", n, d, c)
+			}
+		}
+	}
+}
+
+func TestLatinHypercube(t *testing.T) {
+	// each design of n points has one point in each of the n intervals of each dimension
+	s, err := NewSampler("lhs", 4, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var xs [][]float64
+	for i := 0; i < 30; i++ {
+		xs = append(xs, s.Sample())
+	}
+	for _, design := range [][][]float64{xs[:10], xs[10:20], xs[20:]} {
+		for d := 0; d < 4; d++ {
+			for n, c := range strata(design, d, 10) {
+				if c != 1 {
+					t.Errorf("Expected one point in interval %v/10 of dimension %v, got %v", n, d, c)
+				}
+			}
+		}
+	}
+	if reflect.DeepEqual(xs[:10], xs[10:20]) {
+		t.Errorf("Expected a new design after n points")
+	}
+}
+
+func TestNewSamplerErrors(t *testing.T) {
+	for _, c := range []struct {
+		kind string
+		dim  int
+	}{
+		{kind: "grid", dim: 2},
+		{kind: "sobol", dim: 22},
+	} {
+		if _, err := NewSampler(c.kind, c.dim, 10, 1); err == nil {
+			t.Errorf("%v %v: expected an error", c.kind, c.dim)
+		}
+	}
+}
+
+func TestParameterSetFromUnit(t *testing.T) {
+	pcs := []*api.ParameterConfig{
+		{Name: "int", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "1", Max: "4"}},
+		{Name: "double", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "-1", Max: "1"}},
+		{Name: "discrete", ParameterType: api.ParameterType_DISCRETE, Feasible: &api.FeasibleSpace{List: []string{"8", "16", "32"}}},
+		{Name: "categorical", ParameterType: api.ParameterType_CATEGORICAL, Feasible: &api.FeasibleSpace{List: []string{"adam", "sgd"}}},
+	}
+	for _, c := range []struct {
+		x    float64
+		want []string
+	}{
+		{x: 0, want: []string{"1", "-1.0000", "8", "adam"}},
+		{x: 0.3, want: []string{"2", "-0.4000", "8", "adam"}},
+		{x: 0.5, want: []string{"3", "0.0000", "16", "sgd"}},
+		{x: 0.999, want: []string{"4", "0.9980", "32", "sgd"}},
+		{x: 1, want: []string{"4", "1.0000", "32", "sgd"}},
+	} {
+		ps := ParameterSetFromUnit([]float64{c.x, c.x, c.x, c.x}, pcs)
+		var got []string
+		for i, p := range ps {
+			if p.Name != pcs[i].Name || p.ParameterType != pcs[i].ParameterType {
+				t.Errorf("Expected parameter %v, got %v", pcs[i].Name, p)
+			}
+			got = append(got, p.Value)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: expected %v, got %v", c.x, c.want, got)
+		}
+	}
+}
+
+func quasiRandomConfig() *api.StudyConfig {
+	return &api.StudyConfig{ParameterConfigs: &api.StudyConfig_ParameterConfigs{Configs: []*api.ParameterConfig{
+		{Name: "lr", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0.01", Max: "0.1"}},
+		{Name: "layers", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "2", Max: "5"}},
+	}}}
+}
+
+func TestQuasiRandomStudy(t *testing.T) {
+	conf := quasiRandomConfig()
+	for _, seq := range []string{"sobol", "halton", "lhs"} {
+		sps := []*api.SuggestionParameter{{Name: "SuggestionNum", Value: "8"}, {Name: "Sequence", Value: seq}, {Name: "Seed", Value: "5"}}
+		newStudy := func() *QuasiRandomSuggestParameters {
+			st, err := (&QuasiRandomSuggestService{}).NewStudy(&api.SetSuggestionParametersRequest{StudyId: "study", SuggestionParameters: sps, Configs: conf})
+			if err != nil {
+				t.Fatalf("%v: NewStudy: %v", seq, err)
+			}
+			return st.(*QuasiRandomSuggestParameters)
+		}
+		generate := func(p *QuasiRandomSuggestParameters, completed int) []*api.Trial {
+			r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: make([]*api.Trial, completed)})
+			if err != nil {
+				t.Fatalf("%v: GenerateTrials: %v", seq, err)
+			}
+			return r.Trials
+		}
+		p := newStudy()
+		first := generate(p, 5)
+		if len(first) != 3 {
+			t.Errorf("%v: expected the 3 remaining trials, got %v", seq, len(first))
+		}
+		for _, tr := range first {
+			lr, _ := strconv.ParseFloat(tr.ParameterSet[0].Value, 64)
+			layers, _ := strconv.Atoi(tr.ParameterSet[1].Value)
+			if lr < 0.01 || lr > 0.1 || layers < 2 || layers > 5 {
+				t.Errorf("%v: trial %v is out of the feasible space", seq, tr.ParameterSet)
+			}
+		}
+		if !reflect.DeepEqual(first, generate(newStudy(), 5)) {
+			t.Errorf("%v: expected the same trials with the same seed", seq)
+		}
+
+		// a restarted service continues the sequence
+		state, err := p.SaveState()
+		if err != nil {
+			t.Fatalf("%v: SaveState: %v", seq, err)
+		}
+		q := newStudy()
+		if err := q.LoadState(state); err != nil {
+			t.Fatalf("%v: LoadState: %v", seq, err)
+		}
+		if pt, qt := generate(p, 6), generate(q, 6); !reflect.DeepEqual(pt, qt) || reflect.DeepEqual(pt, first[:2]) {
+			t.Errorf("%v: expected the next trials after LoadState, got %v and %v", seq, pt, qt)
+		}
+	}
+}
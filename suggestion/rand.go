@@ -0,0 +1,68 @@
+package suggestion
+
+import (
+	"math/rand"
+)
+
+// countingSource counts the values drawn from a rand.Source, so that a generator can be moved to the same position again.
+type countingSource struct {
+	src   rand.Source
+	drawn uint64
+}
+
+func (c *countingSource) Int63() int64 {
+	c.drawn++
+	return c.src.Int63()
+}
+
+func (c *countingSource) Seed(seed int64) {
+	c.drawn = 0
+	c.src.Seed(seed)
+}
+
+// Rand is a per-study random generator.
+// The same seed and the same sequence of calls always give the same values,
+// and RandState records its position so that a restarted service continues the sequence.
+type Rand struct {
+	*rand.Rand
+	seed int64
+	src  *countingSource
+}
+
+type RandState struct {
+	Seed  int64
+	Drawn uint64
+}
+
+func NewRand(seed int64) *Rand {
+	src := &countingSource{src: rand.NewSource(seed)}
+	return &Rand{Rand: rand.New(src), seed: seed, src: src}
+}
+
+// NewRandFromState returns a generator at the position recorded in st.
+func NewRandFromState(st RandState) *Rand {
+	r := NewRand(st.Seed)
+	for r.src.drawn < st.Drawn {
+		r.src.Int63()
+	}
+	return r
+}
+
+func (r *Rand) Seed() int64 {
+	return r.seed
+}
+
+func (r *Rand) State() RandState {
+	return RandState{Seed: r.seed, Drawn: r.src.drawn}
+}
+
+func (r *Rand) DoubelRandom(min, max float64) float64 {
+	if min == max {
+		return min
+	}
+	return r.Float64()*(max-min) + min
+}
+
+func (r *Rand) IntRandom(min, max int) int {
+	return r.Intn(max-min+1) + min
+}
@@ -0,0 +1,74 @@
+package suggestion
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRandReplay(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		draw func(r *Rand) interface{}
+	}{
+		{name: "Int63", draw: func(r *Rand) interface{} { return r.Int63() }},
+		{name: "Uint64", draw: func(r *Rand) interface{} { return r.Uint64() }},
+		{name: "Intn", draw: func(r *Rand) interface{} { return r.Intn(10) }},
+		{name: "Float64", draw: func(r *Rand) interface{} { return r.Float64() }},
+		{name: "NormFloat64", draw: func(r *Rand) interface{} { return r.NormFloat64() }},
+		{name: "Perm", draw: func(r *Rand) interface{} { return r.Perm(5) }},
+		{name: "IntRandom", draw: func(r *Rand) interface{} { return r.IntRandom(2, 5) }},
+		{name: "DoubelRandom", draw: func(r *Rand) interface{} { return r.DoubelRandom(-1, 1) }},
+	} {
+		draw := func(r *Rand, n int) []interface{} {
+			var ret []interface{}
+			for i := 0; i < n; i++ {
+				ret = append(ret, c.draw(r))
+			}
+			return ret
+		}
+		r := NewRand(3)
+		first := draw(r, 7)
+		st := r.State()
+		if st.Seed != 3 || st.Drawn < 7 {
+			t.Errorf("%v: expected seed 3 and at least 7 values drawn, got %v", c.name, st)
+		}
+		// a generator restored from the state continues where r stopped
+		q := NewRandFromState(st)
+		if q.Seed() != 3 || q.State() != st {
+			t.Errorf("%v: expected the state %v, got %v", c.name, st, q.State())
+		}
+		if next, got := draw(r, 20), draw(q, 20); !reflect.DeepEqual(next, got) {
+			t.Errorf("%v: expected %v after NewRandFromState, got %v", c.name, next, got)
+		}
+		if got := draw(NewRand(3), 7); !reflect.DeepEqual(got, first) {
+			t.Errorf("%v: expected the same values with the same seed, got %v and %v", c.name, first, got)
+		}
+		if got := draw(NewRand(4), 7); reflect.DeepEqual(got, first) {
+			t.Errorf("%v: expected other values with another seed", c.name)
+		}
+		if got := NewRandFromState(RandState{Seed: 3}); !reflect.DeepEqual(draw(got, 7), first) {
+			t.Errorf("%v: expected the first values from an empty state", c.name)
+		}
+	}
+}
+
+func TestRandRange(t *testing.T) {
+	r := NewRand(1)
+	seen := make(map[int]bool)
+	for i := 0; i < 200; i++ {
+		n := r.IntRandom(2, 5)
+		if n < 2 || n > 5 {
+			t.Errorf("IntRandom(2, 5) returned %v", n)
+		}
+		seen[n] = true
+		if v := r.DoubelRandom(-1, 1); v < -1 || v >= 1 {
+			t.Errorf("DoubelRandom(-1, 1) returned %v", v)
+		}
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected every value of [2, 5], got %v", seen)
+	}
+	if v := r.DoubelRandom(0.5, 0.5); v != 0.5 {
+		t.Errorf("DoubelRandom(0.5, 0.5) returned %v", v)
+	}
+}
@@ -0,0 +1,49 @@
+package suggestion
+
+import (
+	"github.com/mlkube/katib/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	port = "0.0.0.0:6789"
+)
+
+// Serve runs s on port 6789 together with the grpc health service.
+// On SIGINT or SIGTERM the health status turns NOT_SERVING and the server stops once the running calls are finished.
+func Serve(name string, s api.SuggestionServer) {
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	size := 1<<31 - 1
+	gs := grpc.NewServer(grpc.MaxRecvMsgSize(size), grpc.MaxSendMsgSize(size))
+	api.RegisterSuggestionServer(gs, s)
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("api.Suggestion", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(gs, hs)
+	reflection.Register(gs)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		log.Printf("Stopping %v Suggestion Service\n", name)
+		hs.Shutdown()
+		gs.GracefulStop()
+	}()
+
+	log.Printf("%v Suggestion Service\n", name)
+	if err = gs.Serve(listener); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
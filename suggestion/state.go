@@ -0,0 +1,17 @@
+package suggestion
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// EncodeState and DecodeState are helpers for StateSaver implementations.
+func EncodeState(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func DecodeState(state []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(state)).Decode(v)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"github.com/mlkube/katib/api"
 	"log"
-	"math/rand"
 	"strconv"
 	"time"
 )
@@ -17,66 +16,65 @@ type SuggestService interface {
 type RandomSuggestParameters struct {
 	SuggestionNum int
 	MaxParallel   int
+	rng           *Rand
 }
+
 type RandomSuggestService struct {
-	parameters map[string]*RandomSuggestParameters
 }
 
-func NewRandomSuggestService() *RandomSuggestService {
-	return &RandomSuggestService{parameters: make(map[string]*RandomSuggestParameters)}
+func NewRandomSuggestService() *Service {
+	return NewService(&RandomSuggestService{})
 }
 
-func (s *RandomSuggestService) DoubelRandom(min, max float64) float64 {
-	if min == max {
-		return min
+func (s *RandomSuggestService) NewStudy(in *api.SetSuggestionParametersRequest) (Study, error) {
+	d := NewParameterDecoder(in.SuggestionParameters)
+	p := &RandomSuggestParameters{
+		SuggestionNum: d.Int("SuggestionNum", 0),
+		MaxParallel:   d.Int("MaxParallel", 0),
+		rng:           NewRand(d.Int64("Seed", time.Now().UnixNano())),
+	}
+	if err := d.Err(); err != nil {
+		return nil, err
 	}
-	rand.Seed(time.Now().UnixNano())
-	return rand.Float64()*(max-min) + min
+	log.Printf("Study %v: random seed %v", in.StudyId, p.rng.Seed())
+	return p, nil
 }
 
-func (s *RandomSuggestService) IntRandom(min, max int) int {
-	rand.Seed(time.Now().UnixNano())
-	return rand.Intn(max-min+1) + min
+// SaveState records the position of the random generator, so that a restarted service draws the same values.
+func (p *RandomSuggestParameters) SaveState() ([]byte, error) {
+	return EncodeState(p.rng.State())
 }
 
-func (s *RandomSuggestService) SetSuggestionParameters(ctx context.Context, in *api.SetSuggestionParametersRequest) (*api.SetSuggestionParametersReply, error) {
-	p := &RandomSuggestParameters{}
-	for _, sp := range in.SuggestionParameters {
-		switch sp.Name {
-		case "SuggestionNum":
-			p.SuggestionNum, _ = strconv.Atoi(sp.Value)
-		case "MaxParallel":
-			p.MaxParallel, _ = strconv.Atoi(sp.Value)
-		default:
-			log.Printf("Unknown Suggestion Parameter %v", sp.Name)
-		}
+func (p *RandomSuggestParameters) LoadState(state []byte) error {
+	var st RandState
+	if err := DecodeState(state, &st); err != nil {
+		return err
 	}
-	s.parameters[in.StudyId] = p
-	return &api.SetSuggestionParametersReply{}, nil
+	p.rng = NewRandFromState(st)
+	return nil
 }
 
-func (s *RandomSuggestService) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
-	if len(in.CompletedTrials) >= s.parameters[in.StudyId].SuggestionNum {
-		s.StopSuggestion(ctx, &api.StopSuggestionRequest{StudyId: in.StudyId})
+func (p *RandomSuggestParameters) GenerateTrials(ctx context.Context, in *api.GenerateTrialsRequest) (*api.GenerateTrialsReply, error) {
+	if len(in.CompletedTrials) >= p.SuggestionNum {
 		return &api.GenerateTrialsReply{Completed: true}, nil
 	}
-	if s.parameters[in.StudyId].MaxParallel < 1 && len(in.RunningTrials) > 0 {
+	if p.MaxParallel < 1 && len(in.RunningTrials) > 0 {
 		return &api.GenerateTrialsReply{Completed: false}, nil
 	} else {
-		if len(in.RunningTrials) >= s.parameters[in.StudyId].MaxParallel {
+		if len(in.RunningTrials) >= p.MaxParallel {
 			return &api.GenerateTrialsReply{Completed: false}, nil
 		}
-		if s.parameters[in.StudyId].SuggestionNum-len(in.CompletedTrials)-len(in.RunningTrials) <= 0 {
+		if p.SuggestionNum-len(in.CompletedTrials)-len(in.RunningTrials) <= 0 {
 			return &api.GenerateTrialsReply{Completed: false}, nil
 		}
 	}
 	var reqnum int = 0
-	if s.parameters[in.StudyId].MaxParallel < 1 {
-		reqnum = s.parameters[in.StudyId].SuggestionNum
-	} else if s.parameters[in.StudyId].SuggestionNum-len(in.CompletedTrials) <= s.parameters[in.StudyId].MaxParallel {
-		reqnum = s.parameters[in.StudyId].SuggestionNum - len(in.CompletedTrials) - len(in.RunningTrials)
+	if p.MaxParallel < 1 {
+		reqnum = p.SuggestionNum
+	} else if p.SuggestionNum-len(in.CompletedTrials) <= p.MaxParallel {
+		reqnum = p.SuggestionNum - len(in.CompletedTrials) - len(in.RunningTrials)
 	} else {
-		reqnum = s.parameters[in.StudyId].MaxParallel - len(in.RunningTrials)
+		reqnum = p.MaxParallel - len(in.RunningTrials)
 	}
 	s_t := make([]*api.Trial, reqnum)
 	for i := 0; i < reqnum; i++ {
@@ -90,20 +88,15 @@ func (s *RandomSuggestService) GenerateTrials(ctx context.Context, in *api.Gener
 			case api.ParameterType_INT:
 				imin, _ := strconv.Atoi(pc.Feasible.Min)
 				imax, _ := strconv.Atoi(pc.Feasible.Max)
-				s_t[i].ParameterSet[j].Value = strconv.Itoa(s.IntRandom(imin, imax))
+				s_t[i].ParameterSet[j].Value = strconv.Itoa(p.rng.IntRandom(imin, imax))
 			case api.ParameterType_DOUBLE:
 				dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
 				dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
-				s_t[i].ParameterSet[j].Value = strconv.FormatFloat(s.DoubelRandom(dmin, dmax), 'f', 4, 64)
+				s_t[i].ParameterSet[j].Value = strconv.FormatFloat(p.rng.DoubelRandom(dmin, dmax), 'f', 4, 64)
 			case api.ParameterType_CATEGORICAL:
-				s_t[i].ParameterSet[j].Value = pc.Feasible.List[s.IntRandom(0, len(pc.Feasible.List)-1)]
+				s_t[i].ParameterSet[j].Value = pc.Feasible.List[p.rng.IntRandom(0, len(pc.Feasible.List)-1)]
 			}
 		}
 	}
 	return &api.GenerateTrialsReply{Trials: s_t, Completed: false}, nil
 }
-
-func (s *RandomSuggestService) StopSuggestion(ctx context.Context, in *api.StopSuggestionRequest) (*api.StopSuggestionReply, error) {
-	delete(s.parameters, in.StudyId)
-	return &api.StopSuggestionReply{}, nil
-}
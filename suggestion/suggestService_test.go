@@ -0,0 +1,78 @@
+package suggestion
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func TestRandomStudy(t *testing.T) {
+	conf := &api.StudyConfig{ParameterConfigs: &api.StudyConfig_ParameterConfigs{Configs: []*api.ParameterConfig{
+		{Name: "lr", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "0.01", Max: "0.1"}},
+		{Name: "layers", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "2", Max: "5"}},
+		{Name: "optimizer", ParameterType: api.ParameterType_CATEGORICAL, Feasible: &api.FeasibleSpace{List: []string{"adam", "sgd"}}},
+	}}}
+	newStudy := func(seed string) *RandomSuggestParameters {
+		sps := []*api.SuggestionParameter{{Name: "SuggestionNum", Value: "10"}, {Name: "MaxParallel", Value: "2"}, {Name: "Seed", Value: seed}}
+		st, err := (&RandomSuggestService{}).NewStudy(&api.SetSuggestionParametersRequest{StudyId: "study", SuggestionParameters: sps, Configs: conf})
+		if err != nil {
+			t.Fatalf("NewStudy: %v", err)
+		}
+		return st.(*RandomSuggestParameters)
+	}
+	// run completes the suggested trials at once until the study is completed or n trials are completed.
+	run := func(p *RandomSuggestParameters, completed []*api.Trial, n int) []*api.Trial {
+		for len(completed) < n {
+			r, err := p.GenerateTrials(context.Background(), &api.GenerateTrialsRequest{StudyId: "study", Configs: conf, CompletedTrials: completed})
+			if err != nil {
+				t.Fatalf("GenerateTrials: %v", err)
+			}
+			if r.Completed {
+				break
+			}
+			if len(r.Trials) != 2 {
+				t.Errorf("Expected MaxParallel trials, got %v", len(r.Trials))
+			}
+			completed = append(completed, r.Trials...)
+		}
+		return completed
+	}
+
+	all := run(newStudy("1"), nil, 1000)
+	if len(all) != 10 {
+		t.Errorf("Expected SuggestionNum trials, got %v", len(all))
+	}
+	for _, tr := range all {
+		lr, _ := strconv.ParseFloat(tr.ParameterSet[0].Value, 64)
+		layers, _ := strconv.Atoi(tr.ParameterSet[1].Value)
+		if lr < 0.01 || lr > 0.1 || layers < 2 || layers > 5 || tr.ParameterSet[2].Value != "adam" && tr.ParameterSet[2].Value != "sgd" {
+			t.Errorf("Trial %v is out of the feasible space", tr.ParameterSet)
+		}
+	}
+	if !reflect.DeepEqual(all, run(newStudy("1"), nil, 1000)) {
+		t.Errorf("Expected the same trials with the same seed")
+	}
+	if reflect.DeepEqual(all, run(newStudy("2"), nil, 1000)) {
+		t.Errorf("Expected other trials with another seed")
+	}
+
+	p := newStudy("1")
+	completed := run(p, nil, 4)
+	state, err := p.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+	q := newStudy("2")
+	if err := q.LoadState(state); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if got := run(q, completed[:4:4], 1000); !reflect.DeepEqual(got, all) {
+		t.Errorf("Expected the same trials after LoadState, got %v", got)
+	}
+	if err := q.LoadState([]byte("x")); err == nil {
+		t.Errorf("Expected an error for an invalid state")
+	}
+}
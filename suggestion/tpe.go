@@ -0,0 +1,154 @@
+package suggestion
+
+import (
+	"github.com/mlkube/katib/api"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// UnitFromParameterSet is the inverse of ParameterSetFromUnit.
+// Values that are missing or outside of the feasible space are mapped to the center.
+func UnitFromParameterSet(ps []*api.Parameter, pcs []*api.ParameterConfig) []float64 {
+	x := make([]float64, len(pcs))
+	for i, pc := range pcs {
+		x[i] = 0.5
+		var value string
+		found := false
+		for _, p := range ps {
+			if p.Name == pc.Name {
+				value = p.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		switch pc.ParameterType {
+		case api.ParameterType_INT:
+			imin, _ := strconv.Atoi(pc.Feasible.Min)
+			imax, _ := strconv.Atoi(pc.Feasible.Max)
+			v, err := strconv.Atoi(value)
+			if err == nil && v >= imin && v <= imax {
+				x[i] = (float64(v-imin) + 0.5) / float64(imax-imin+1)
+			}
+		case api.ParameterType_DOUBLE:
+			dmin, _ := strconv.ParseFloat(pc.Feasible.Min, 64)
+			dmax, _ := strconv.ParseFloat(pc.Feasible.Max, 64)
+			v, err := strconv.ParseFloat(value, 64)
+			if err == nil && dmax > dmin {
+				x[i] = math.Min(1, math.Max(0, (v-dmin)/(dmax-dmin)))
+			}
+		case api.ParameterType_DISCRETE, api.ParameterType_CATEGORICAL:
+			for j, l := range pc.Feasible.List {
+				if l == value {
+					x[i] = (float64(j) + 0.5) / float64(len(pc.Feasible.List))
+					break
+				}
+			}
+		}
+	}
+	return x
+}
+
+// Observation is an evaluated point of the unit hypercube. Smaller Loss is better.
+type Observation struct {
+	X    []float64
+	Loss float64
+}
+
+type kde struct {
+	points [][]float64
+	bw     []float64
+}
+
+func newKDE(points [][]float64, dim int) *kde {
+	k := &kde{points: points, bw: make([]float64, dim)}
+	n := float64(len(points))
+	for d := 0; d < dim; d++ {
+		var mean, variance float64
+		for _, p := range points {
+			mean += p[d]
+		}
+		mean /= n
+		for _, p := range points {
+			variance += (p[d] - mean) * (p[d] - mean)
+		}
+		// Scott's rule with a floor so that a collapsed dimension keeps exploring
+		k.bw[d] = math.Max(1.06*math.Sqrt(variance/n)*math.Pow(n, -1/(4+float64(dim))), 1e-2)
+	}
+	return k
+}
+
+func (k *kde) pdf(x []float64) float64 {
+	var sum float64
+	for _, p := range k.points {
+		l := 1.0
+		for d := range x {
+			z := (x[d] - p[d]) / k.bw[d]
+			l *= math.Exp(-z*z/2) / k.bw[d]
+		}
+		sum += l
+	}
+	return sum / float64(len(k.points))
+}
+
+func (k *kde) sample(rng *rand.Rand) []float64 {
+	p := k.points[rng.Intn(len(k.points))]
+	x := make([]float64, len(p))
+	for d := range x {
+		x[d] = math.Min(1-1e-9, math.Max(0, p[d]+rng.NormFloat64()*k.bw[d]))
+	}
+	return x
+}
+
+// TPESampler proposes points that maximize l(x)/g(x), where l and g are kernel
+// density estimates of the best Gamma fraction of the observations and of the rest.
+type TPESampler struct {
+	good          *kde
+	bad           *kde
+	NumCandidates int
+	rng           *rand.Rand
+}
+
+// NewTPESampler returns nil when there are too few observations to split them into good and bad sets.
+func NewTPESampler(obs []Observation, gamma float64, rng *rand.Rand) *TPESampler {
+	if len(obs) < 2 {
+		return nil
+	}
+	sorted := make([]Observation, len(obs))
+	copy(sorted, obs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Loss < sorted[j].Loss })
+	ngood := int(math.Ceil(gamma * float64(len(sorted))))
+	if ngood < 1 {
+		ngood = 1
+	}
+	if ngood >= len(sorted) {
+		ngood = len(sorted) - 1
+	}
+	var good, bad [][]float64
+	for i, o := range sorted {
+		if i < ngood {
+			good = append(good, o.X)
+		} else {
+			bad = append(bad, o.X)
+		}
+	}
+	dim := len(sorted[0].X)
+	return &TPESampler{good: newKDE(good, dim), bad: newKDE(bad, dim), NumCandidates: 64, rng: rng}
+}
+
+func (t *TPESampler) Sample() []float64 {
+	var best []float64
+	bestScore := math.Inf(-1)
+	for i := 0; i < t.NumCandidates; i++ {
+		x := t.good.sample(t.rng)
+		score := t.good.pdf(x) / math.Max(t.bad.pdf(x), 1e-32)
+		if score > bestScore {
+			best, bestScore = x, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,125 @@
+package suggestion
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/mlkube/katib/api"
+)
+
+func TestUnitFromParameterSet(t *testing.T) {
+	pcs := []*api.ParameterConfig{
+		{Name: "int", ParameterType: api.ParameterType_INT, Feasible: &api.FeasibleSpace{Min: "1", Max: "4"}},
+		{Name: "double", ParameterType: api.ParameterType_DOUBLE, Feasible: &api.FeasibleSpace{Min: "-1", Max: "1"}},
+		{Name: "discrete", ParameterType: api.ParameterType_DISCRETE, Feasible: &api.FeasibleSpace{List: []string{"8", "16", "32"}}},
+		{Name: "categorical", ParameterType: api.ParameterType_CATEGORICAL, Feasible: &api.FeasibleSpace{List: []string{"adam", "sgd"}}},
+	}
+	for _, c := range []struct {
+		name string
+		ps   []*api.Parameter
+		want []float64
+	}{
+		{
+			name: "values",
+			ps:   []*api.Parameter{{Name: "categorical", Value: "sgd"}, {Name: "int", Value: "2"}, {Name: "double", Value: "0.5"}, {Name: "discrete", Value: "8"}},
+			want: []float64{0.375, 0.75, 1.0 / 6, 0.75},
+		},
+		{
+			name: "out of the feasible space",
+			ps:   []*api.Parameter{{Name: "int", Value: "5"}, {Name: "double", Value: "3"}, {Name: "discrete", Value: "64"}, {Name: "categorical", Value: "x"}},
+			want: []float64{0.5, 1, 0.5, 0.5},
+		},
+		{
+			name: "missing",
+			want: []float64{0.5, 0.5, 0.5, 0.5},
+		},
+	} {
+		if got := UnitFromParameterSet(c.ps, pcs); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+	// ParameterSetFromUnit gives the same parameters back
+	ps := ParameterSetFromUnit([]float64{0.1, 0.3, 0.7, 0.9}, pcs)
+	if got := ParameterSetFromUnit(UnitFromParameterSet(ps, pcs), pcs); !reflect.DeepEqual(got, ps) {
+		t.Errorf("Expected %v, got %v", ps, got)
+	}
+}
+
+// observations has the losses of the points (x, x) for x in xs.
+func observations(loss func(x float64) float64, xs ...float64) []Observation {
+	var obs []Observation
+	for _, x := range xs {
+		obs = append(obs, Observation{X: []float64{x, x}, Loss: loss(x)})
+	}
+	return obs
+}
+
+func TestTPESplit(t *testing.T) {
+	xs := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 0.95}
+	for _, c := range []struct {
+		name  string
+		n     int
+		gamma float64
+		good  int
+	}{
+		{name: "too few observations", n: 1, gamma: 0.5},
+		{name: "default gamma", n: 10, gamma: 0.15, good: 2},
+		{name: "at least one good", n: 10, gamma: 0.01, good: 1},
+		{name: "at least one bad", n: 3, gamma: 0.99, good: 2},
+		{name: "half", n: 2, gamma: 0.5, good: 1},
+	} {
+		// the loss decreases with x, so the good points are the last ones
+		s := NewTPESampler(observations(func(x float64) float64 { return -x }, xs[:c.n]...), c.gamma, rand.New(rand.NewSource(1)))
+		if c.good == 0 {
+			if s != nil {
+				t.Errorf("%v: expected no sampler", c.name)
+			}
+			continue
+		}
+		if s == nil || len(s.good.points) != c.good || len(s.bad.points) != c.n-c.good {
+			t.Errorf("%v: expected %v good and %v bad points, got %v", c.name, c.good, c.n-c.good, s)
+			continue
+		}
+		for _, p := range s.good.points {
+			for _, b := range s.bad.points {
+				if p[0] <= b[0] {
+					t.Errorf("%v: good point %v is worse than bad point %v", c.name, p, b)
+				}
+			}
+		}
+	}
+}
+
+func TestTPESample(t *testing.T) {
+	// the best points are around 0.2
+	obs := observations(func(x float64) float64 { return math.Abs(x - 0.2) }, 0.05, 0.15, 0.2, 0.25, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 0.95, 0.99)
+	sample := func(seed int64) [][]float64 {
+		s := NewTPESampler(obs, 0.25, rand.New(rand.NewSource(seed)))
+		var xs [][]float64
+		for i := 0; i < 50; i++ {
+			xs = append(xs, s.Sample())
+		}
+		return xs
+	}
+	xs := sample(1)
+	var mean float64
+	for _, x := range xs {
+		for _, v := range x {
+			if v < 0 || v >= 1 {
+				t.Errorf("Sample %v is out of the unit hypercube", x)
+			}
+		}
+		mean += x[0] / float64(len(xs))
+	}
+	if math.Abs(mean-0.2) > 0.1 {
+		t.Errorf("Expected the samples around the good points, got the mean %v", mean)
+	}
+	if !reflect.DeepEqual(xs, sample(1)) {
+		t.Errorf("Expected the same samples with the same generator")
+	}
+	if reflect.DeepEqual(xs, sample(2)) {
+		t.Errorf("Expected other samples with another seed")
+	}
+}